@@ -0,0 +1,170 @@
+package adapter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+// AccessTokenController is ...
+type AccessTokenController interface {
+	Create(ctx Context)
+	List(ctx Context)
+	Revoke(ctx Context)
+}
+
+type accessTokenController struct {
+	AccessTokenService usecase.AccessTokenService
+	AccessService      usecase.AccessService
+}
+
+// NewAccessTokenController is ...
+func NewAccessTokenController(s usecase.AccessTokenService, access usecase.AccessService) AccessTokenController {
+	return &accessTokenController{
+		AccessTokenService: s,
+		AccessService:      access,
+	}
+}
+
+// Create creates a personal access token for the user and returns its value
+func (c *accessTokenController) Create(ctx Context) {
+
+	user := ctx.GetParam("name")
+	if !authorizeUser(ctx, c.AccessService, user) {
+		return
+	}
+
+	req, err := parseAccessTokenRequest(ctx)
+	if err != nil {
+		writeRequestError(ctx, err, "Invalid token request")
+		return
+	}
+
+	result, err := c.AccessTokenService.Create(req)
+	if err != nil {
+		writeErrorResponse(ctx, 500, "Failed to create token")
+		return
+	}
+
+	res := convertAccessTokenResponse(result.Token)
+	res.Token = result.Value
+
+	writeJSONResponse(ctx, 201, res)
+}
+
+// List returns the tokens of the user without their values
+func (c *accessTokenController) List(ctx Context) {
+
+	user := ctx.GetParam("name")
+	if !authorizeUser(ctx, c.AccessService, user) {
+		return
+	}
+
+	tokens, err := c.AccessTokenService.Tokens(user)
+	if err != nil {
+		writeErrorResponse(ctx, 500, "Failed to list tokens")
+		return
+	}
+
+	res := &AccessTokenListResponse{Tokens: []*AccessTokenResponse{}}
+	for _, t := range tokens {
+		res.Tokens = append(res.Tokens, convertAccessTokenResponse(t))
+	}
+
+	writeJSONResponse(ctx, 200, res)
+}
+
+// Revoke deletes a token of the user
+func (c *accessTokenController) Revoke(ctx Context) {
+
+	user := ctx.GetParam("name")
+	if !authorizeUser(ctx, c.AccessService, user) {
+		return
+	}
+
+	err := c.AccessTokenService.Revoke(user, ctx.GetParam("id"), ctx.GetUser())
+	switch err {
+	case nil:
+		ctx.SetStatus(204)
+	case usecase.ErrAccessTokenNotFound:
+		writeErrorResponse(ctx, 404, "Token not found")
+	default:
+		writeErrorResponse(ctx, 500, "Failed to revoke token")
+	}
+}
+
+func parseAccessTokenRequest(ctx Context) (*usecase.AccessTokenRequest, error) {
+
+	data, err := ctx.GetRawData()
+	if err != nil {
+		return nil, err
+	}
+
+	var req AccessTokenRequest
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	if len(req.Scopes) == 0 {
+		return nil, errors.New("scopes are required")
+	}
+
+	var scopes []entity.Scope
+	for _, s := range req.Scopes {
+		scope := entity.Scope(s)
+		if !scope.Valid() {
+			return nil, fmt.Errorf("unknown scope %q", s)
+		}
+		scopes = append(scopes, scope)
+	}
+
+	if req.ExpiresIn < 0 {
+		return nil, errors.New("expires_in must not be negative")
+	}
+
+	atr := &usecase.AccessTokenRequest{
+		Actor:  ctx.GetUser(),
+		User:   ctx.GetParam("name"),
+		Name:   req.Name,
+		Scopes: scopes,
+		Repo:   req.Repo,
+		TTL:    time.Duration(req.ExpiresIn) * time.Second,
+	}
+
+	return atr, nil
+}
+
+func convertAccessTokenResponse(token *entity.AccessToken) *AccessTokenResponse {
+
+	res := &AccessTokenResponse{
+		ID:        token.ID,
+		Name:      token.Name,
+		Repo:      token.Repo,
+		CreatedAt: time.Unix(token.CreatedAt, 0).UTC(),
+	}
+
+	for _, s := range token.Scopes {
+		res.Scopes = append(res.Scopes, string(s))
+	}
+
+	if token.ExpiresAt != 0 {
+		t := time.Unix(token.ExpiresAt, 0).UTC()
+		res.ExpiresAt = &t
+	}
+
+	if token.LastUsedAt != 0 {
+		t := time.Unix(token.LastUsedAt, 0).UTC()
+		res.LastUsedAt = &t
+	}
+
+	return res
+}
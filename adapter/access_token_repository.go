@@ -0,0 +1,250 @@
+package adapter
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+// lastUsedResolution limits how often the last use of a token is written,
+// so that authenticating does not cost a write on every request
+const lastUsedResolution = time.Minute
+
+var (
+	accessTokensBucket    = []byte("access_tokens")
+	errAccessTokenExists  = errors.New("Access token already exists")
+	errInvalidAccessToken = errors.New("Invalid access token")
+)
+
+type accessTokenRecord struct {
+	Name       string         `json:"name"`
+	Scopes     []entity.Scope `json:"scopes"`
+	Repo       string         `json:"repo,omitempty"`
+	SecretHash string         `json:"secret_hash"`
+	CreatedAt  int64          `json:"created_at"`
+	ExpiresAt  int64          `json:"expires_at,omitempty"`
+	LastUsedAt int64          `json:"last_used_at,omitempty"`
+}
+
+type accessTokenRepository struct {
+	db      *bolt.DB
+	metrics Metrics
+}
+
+// NewAccessTokenRepository is ...
+// Tokens are kept in a bucket per user, keyed by token id.
+func NewAccessTokenRepository(db *bolt.DB, metrics Metrics) usecase.AccessTokenRepository {
+
+	db.Update(func(tx *bolt.Tx) error {
+
+		_, err := tx.CreateBucketIfNotExists(accessTokensBucket)
+		if err != nil {
+			return err
+		}
+		return nil
+
+	})
+
+	return &accessTokenRepository{db: db, metrics: metricsOrNop(metrics)}
+}
+
+// Add stores the token with a hash of its secret.
+func (r *accessTokenRepository) Add(token *entity.AccessToken, secret string) error {
+
+	err := boltUpdate(r.db, r.metrics, "token.add", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(accessTokensBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		userBucket, err := bucket.CreateBucketIfNotExists([]byte(token.User))
+		if err != nil {
+			return err
+		}
+
+		if userBucket.Get([]byte(token.ID)) != nil {
+			return errAccessTokenExists
+		}
+
+		record := &accessTokenRecord{
+			Name:       token.Name,
+			Scopes:     token.Scopes,
+			Repo:       token.Repo,
+			SecretHash: hashSecret(secret),
+			CreatedAt:  token.CreatedAt,
+			ExpiresAt:  token.ExpiresAt,
+			LastUsedAt: token.LastUsedAt,
+		}
+
+		data, err := encodeRecord(record)
+		if err != nil {
+			return err
+		}
+
+		return userBucket.Put([]byte(token.ID), data)
+	})
+
+	return err
+}
+
+// Tokens returns all tokens of the user.
+func (r *accessTokenRepository) Tokens(user string) ([]*entity.AccessToken, error) {
+
+	var tokens []*entity.AccessToken
+
+	err := boltView(r.db, r.metrics, "token.list", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(accessTokensBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		userBucket := bucket.Bucket([]byte(user))
+		if userBucket == nil {
+			return nil
+		}
+
+		return userBucket.ForEach(func(k, v []byte) error {
+
+			var record accessTokenRecord
+			err := decodeRecord(v, &record)
+			if err != nil {
+				return err
+			}
+
+			tokens = append(tokens, record.token(user, string(k)))
+			return nil
+		})
+	})
+
+	return tokens, err
+}
+
+// Revoke deletes the token of the user.
+func (r *accessTokenRepository) Revoke(user string, id string) error {
+
+	err := boltUpdate(r.db, r.metrics, "token.revoke", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(accessTokensBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		userBucket := bucket.Bucket([]byte(user))
+		if userBucket == nil || userBucket.Get([]byte(id)) == nil {
+			return usecase.ErrAccessTokenNotFound
+		}
+
+		return userBucket.Delete([]byte(id))
+	})
+
+	return err
+}
+
+// Authenticate checks the secret of the token and records its use. The use
+// is only written when the recorded one is older than lastUsedResolution.
+func (r *accessTokenRepository) Authenticate(user string, id string, secret string) (*entity.AccessToken, error) {
+
+	var record *accessTokenRecord
+
+	err := boltView(r.db, r.metrics, "token.authenticate", func(tx *bolt.Tx) error {
+
+		var err error
+		record, err = authenticateAccessToken(tx, user, id, secret)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if now.Sub(time.Unix(record.LastUsedAt, 0)) < lastUsedResolution {
+		return record.token(user, id), nil
+	}
+
+	err = boltUpdate(r.db, r.metrics, "token.touch", func(tx *bolt.Tx) error {
+
+		// the token may have been revoked since it was checked
+		var err error
+		record, err = authenticateAccessToken(tx, user, id, secret)
+		if err != nil {
+			return err
+		}
+
+		record.LastUsedAt = now.Unix()
+
+		data, err := encodeRecord(record)
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket(accessTokensBucket).Bucket([]byte(user)).Put([]byte(id), data)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record.token(user, id), nil
+}
+
+// authenticateAccessToken returns the record of the token if the secret matches
+func authenticateAccessToken(tx *bolt.Tx, user string, id string, secret string) (*accessTokenRecord, error) {
+
+	bucket := tx.Bucket(accessTokensBucket)
+	if bucket == nil {
+		return nil, errors.New("Bucket not found")
+	}
+
+	userBucket := bucket.Bucket([]byte(user))
+	if userBucket == nil {
+		return nil, errInvalidAccessToken
+	}
+
+	value := userBucket.Get([]byte(id))
+	if len(value) == 0 {
+		return nil, errInvalidAccessToken
+	}
+
+	var record accessTokenRecord
+	err := decodeRecord(value, &record)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := hashSecret(secret)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(record.SecretHash)) != 1 {
+		return nil, errInvalidAccessToken
+	}
+
+	return &record, nil
+}
+
+func (record *accessTokenRecord) token(user string, id string) *entity.AccessToken {
+	return &entity.AccessToken{
+		ID:         id,
+		User:       user,
+		Name:       record.Name,
+		Scopes:     record.Scopes,
+		Repo:       record.Repo,
+		CreatedAt:  record.CreatedAt,
+		ExpiresAt:  record.ExpiresAt,
+		LastUsedAt: record.LastUsedAt,
+	}
+}
+
+// hashSecret hashes a token secret. Unlike passwords, secrets are random
+// 256 bit values, so a fast hash is enough and keeps authentication cheap.
+func hashSecret(secret string) string {
+
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,104 @@
+package adapter
+
+import (
+	"testing"
+
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+func TestAccessTokens(t *testing.T) {
+
+	d := newTestData()
+	setupRepository(d)
+	defer teardownRepository(d)
+
+	repo := NewAccessTokenRepository(d.database, nil)
+
+	token := &entity.AccessToken{
+		ID:     "0123456789abcdef",
+		User:   d.userName1,
+		Name:   "laptop",
+		Scopes: []entity.Scope{entity.ScopeRead, entity.ScopeLock},
+		Repo:   d.userName1 + "/" + d.repoName,
+	}
+
+	if err := repo.Add(token, "secret"); err != nil {
+		t.Fatalf("expected add to succeed, got: %s", err)
+	}
+
+	if err := repo.Add(token, "other secret"); err == nil {
+		t.Errorf("expected add of an existing token to fail")
+	}
+
+	if _, err := repo.Authenticate(d.userName1, token.ID, "wrong"); err == nil {
+		t.Errorf("expected wrong secret to fail")
+	}
+
+	if _, err := repo.Authenticate(d.userName2, token.ID, "secret"); err == nil {
+		t.Errorf("expected token of another user to fail")
+	}
+
+	got, err := repo.Authenticate(d.userName1, token.ID, "secret")
+	if err != nil {
+		t.Fatalf("expected authentication to succeed, got: %s", err)
+	}
+
+	if got.Name != token.Name || got.Repo != token.Repo || len(got.Scopes) != 2 || got.LastUsedAt == 0 {
+		t.Errorf("expected token with last use, got: %+v", got)
+	}
+
+	// a recent use is not written again
+	again, err := repo.Authenticate(d.userName1, token.ID, "secret")
+	if err != nil || again.LastUsedAt != got.LastUsedAt {
+		t.Errorf("expected the recorded last use, got: %+v, %v", again, err)
+	}
+
+	tokens, err := repo.Tokens(d.userName1)
+	if err != nil {
+		t.Fatalf("expected tokens, got: %s", err)
+	}
+
+	if len(tokens) != 1 || tokens[0].ID != token.ID {
+		t.Errorf("expected one token, got: %+v", tokens)
+	}
+
+	if err := repo.Revoke(d.userName1, token.ID); err != nil {
+		t.Fatalf("expected revoke to succeed, got: %s", err)
+	}
+
+	if err := repo.Revoke(d.userName1, token.ID); err != usecase.ErrAccessTokenNotFound {
+		t.Errorf("expected revoked token to be missing, got: %v", err)
+	}
+
+	if _, err := repo.Authenticate(d.userName1, token.ID, "secret"); err == nil {
+		t.Errorf("expected revoked token to fail")
+	}
+}
+
+func TestDeleteUserRevokesAccessTokens(t *testing.T) {
+
+	d := newTestData()
+	setupRepository(d)
+	defer teardownRepository(d)
+
+	repo := NewAccessTokenRepository(d.database, nil)
+
+	token := &entity.AccessToken{ID: "token", User: d.userName1, Name: "ci", Scopes: []entity.Scope{entity.ScopeRead}}
+	if err := repo.Add(token, "secret"); err != nil {
+		t.Fatalf("expected add to succeed, got: %s", err)
+	}
+
+	if err := d.userRepository.DeleteUser(d.userName1); err != nil {
+		t.Fatalf("expected delete to succeed, got: %s", err)
+	}
+
+	tokens, err := repo.Tokens(d.userName1)
+	if err != nil {
+		t.Fatalf("expected tokens, got: %s", err)
+	}
+
+	if len(tokens) != 0 {
+		t.Errorf("expected tokens of deleted user to be gone, got: %d", len(tokens))
+	}
+}
@@ -0,0 +1,111 @@
+package adapter
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+// AuditController is ...
+type AuditController interface {
+	Query(ctx Context)
+}
+
+type auditController struct {
+	AuditLog      *usecase.AuditLog
+	AccessService usecase.AccessService
+}
+
+// NewAuditController is ...
+func NewAuditController(audit *usecase.AuditLog, access usecase.AccessService) AuditController {
+	return &auditController{
+		AuditLog:      audit,
+		AccessService: access,
+	}
+}
+
+// Query returns the audit events of the actor and repo parameters between
+// the since and until parameters, times in RFC 3339 format. Events are
+// returned the most recent first, at most limit of them, from the cursor
+// parameter.
+func (c *auditController) Query(ctx Context) {
+
+	if !authorizeAdmin(ctx, c.AccessService, "You must be an admin to read the audit log") {
+		return
+	}
+
+	q, err := parseAuditQuery(ctx)
+	if err != nil {
+		writeErrorResponse(ctx, 422, "Invalid audit query: "+err.Error())
+		return
+	}
+
+	result, err := c.AuditLog.Query(q)
+	if err == usecase.ErrAuditLogDisabled {
+		writeErrorResponse(ctx, 404, err.Error())
+		return
+	}
+	if err == usecase.ErrInvalidCursor {
+		writeErrorResponse(ctx, 422, err.Error())
+		return
+	}
+	if err != nil {
+		writeErrorResponse(ctx, 500, "Failed to query audit log")
+		return
+	}
+
+	res := &AuditEventListResponse{
+		Events:     []*AuditEventResponse{},
+		NextCursor: result.NextCursor,
+	}
+	for _, e := range result.Events {
+		res.Events = append(res.Events, &AuditEventResponse{
+			Time:    time.Unix(e.Time, 0).UTC(),
+			Actor:   e.Actor,
+			Action:  e.Action,
+			Repo:    e.Repo,
+			Target:  e.Target,
+			Details: e.Details,
+		})
+	}
+
+	writeJSONResponse(ctx, 200, res)
+}
+
+func parseAuditQuery(ctx Context) (*usecase.AuditQuery, error) {
+
+	q := &usecase.AuditQuery{
+		Actor:  ctx.GetParam("actor"),
+		Repo:   ctx.GetParam("repo"),
+		Cursor: ctx.GetParam("cursor"),
+	}
+
+	for _, p := range []struct {
+		name string
+		to   *int64
+	}{{"since", &q.Since}, {"until", &q.Until}} {
+
+		value := ctx.GetParam(p.name)
+		if value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", p.name, value)
+		}
+		*p.to = t.Unix()
+	}
+
+	if limit := ctx.GetParam("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid limit %q", limit)
+		}
+		q.Limit = n
+	}
+
+	return q, nil
+}
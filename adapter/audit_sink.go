@@ -0,0 +1,382 @@
+package adapter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+var (
+	// auditEventsBucket holds the audit events keyed by sequence
+	auditEventsBucket = []byte("audit_events")
+	// auditActorsBucket and auditReposBucket hold a bucket of the sequence
+	// keys of the events of each actor and repo
+	auditActorsBucket = []byte("audit_actors")
+	auditReposBucket  = []byte("audit_repos")
+)
+
+// auditRecord is the encoding of an audit event shared by the sinks
+type auditRecord struct {
+	Time    time.Time         `json:"time"`
+	Actor   string            `json:"actor,omitempty"`
+	Action  string            `json:"action"`
+	Repo    string            `json:"repo,omitempty"`
+	Target  string            `json:"target,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+func newAuditRecord(event *entity.AuditEvent) *auditRecord {
+
+	return &auditRecord{
+		Time:    time.Unix(event.Time, 0).UTC(),
+		Actor:   event.Actor,
+		Action:  event.Action,
+		Repo:    event.Repo,
+		Target:  event.Target,
+		Details: event.Details,
+	}
+}
+
+func (r *auditRecord) event() *entity.AuditEvent {
+
+	return &entity.AuditEvent{
+		Time:    r.Time.Unix(),
+		Actor:   r.Actor,
+		Action:  r.Action,
+		Repo:    r.Repo,
+		Target:  r.Target,
+		Details: r.Details,
+	}
+}
+
+type boltAuditSink struct {
+	db      *bolt.DB
+	metrics Metrics
+}
+
+// NewBoltAuditSink is ...
+// Events are keyed by sequence, which keeps them in the order they were
+// appended, and indexed by actor and repo.
+func NewBoltAuditSink(db *bolt.DB, metrics Metrics) usecase.AuditSink {
+
+	db.Update(func(tx *bolt.Tx) error {
+
+		for _, name := range [][]byte{auditEventsBucket, auditActorsBucket, auditReposBucket} {
+			_, err := tx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return &boltAuditSink{db: db, metrics: metricsOrNop(metrics)}
+}
+
+// Append stores the event under the next sequence number
+func (s *boltAuditSink) Append(event *entity.AuditEvent) error {
+
+	return boltUpdate(s.db, s.metrics, "audit.append", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(auditEventsBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		data, err := encodeRecord(newAuditRecord(event))
+		if err != nil {
+			return err
+		}
+
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+
+		err = bucket.Put(key, data)
+		if err != nil {
+			return err
+		}
+
+		return indexAuditEvent(tx, key, event)
+	})
+}
+
+// Query walks the events of the actor or else of the repo backwards from
+// the cursor, which is the hex of the sequence key of the first event
+func (s *boltAuditSink) Query(q *usecase.AuditQuery) (*usecase.AuditQueryResult, error) {
+
+	var from []byte
+	if q.Cursor != "" {
+		var err error
+		from, err = hex.DecodeString(q.Cursor)
+		if err != nil || len(from) != 8 {
+			return nil, usecase.ErrInvalidCursor
+		}
+	}
+
+	result := &usecase.AuditQueryResult{}
+	max := q.MaxEvents()
+
+	err := boltView(s.db, s.metrics, "audit.query", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(auditEventsBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		keys := bucket
+		switch {
+		case q.Actor != "":
+			keys = indexBucket(tx, auditActorsBucket, q.Actor)
+		case q.Repo != "":
+			keys = indexBucket(tx, auditReposBucket, q.Repo)
+		}
+		if keys == nil {
+			return nil
+		}
+
+		c := keys.Cursor()
+		k, _ := c.Last()
+		if from != nil {
+			k, _ = c.Seek(from)
+			if k == nil {
+				k, _ = c.Last()
+			} else if !bytes.Equal(k, from) {
+				k, _ = c.Prev()
+			}
+		}
+
+		for ; k != nil; k, _ = c.Prev() {
+
+			var record auditRecord
+			err := decodeRecord(bucket.Get(k), &record)
+			if err != nil {
+				return err
+			}
+
+			event := record.event()
+			if !q.Matches(event) {
+				continue
+			}
+
+			if len(result.Events) == max {
+				result.NextCursor = hex.EncodeToString(k)
+				break
+			}
+			result.Events = append(result.Events, event)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// indexAuditEvent adds the sequence key of the event to the buckets of its
+// actor and repo
+func indexAuditEvent(tx *bolt.Tx, key []byte, event *entity.AuditEvent) error {
+
+	for _, index := range []struct {
+		bucket []byte
+		name   string
+	}{{auditActorsBucket, event.Actor}, {auditReposBucket, event.Repo}} {
+
+		if index.name == "" {
+			continue
+		}
+
+		bucket := tx.Bucket(index.bucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		names, err := bucket.CreateBucketIfNotExists([]byte(index.name))
+		if err != nil {
+			return err
+		}
+
+		err = names.Put(key, []byte{})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func indexBucket(tx *bolt.Tx, index []byte, name string) *bolt.Bucket {
+
+	bucket := tx.Bucket(index)
+	if bucket == nil {
+		return nil
+	}
+
+	return bucket.Bucket([]byte(name))
+}
+
+type fileAuditSink struct {
+	path string
+	file *os.File
+	mu   sync.Mutex
+}
+
+// NewFileAuditSink is ...
+// Events are appended to the file as JSON, one per line, so that the file
+// can be shipped by log collectors. The file is created if it does not exist.
+func NewFileAuditSink(path string) (usecase.AuditSink, error) {
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileAuditSink{path: path, file: file}, nil
+}
+
+// Append writes the event as a line and syncs the file, so that appended
+// events survive a crash
+func (s *fileAuditSink) Append(event *entity.AuditEvent) error {
+
+	data, err := json.Marshal(newAuditRecord(event))
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.file.Write(append(data, '\n'))
+	if err != nil {
+		return err
+	}
+
+	return s.file.Sync()
+}
+
+// Query reads the lines of the file backwards from the cursor, which is the
+// offset of the end of the line of the first event
+func (s *fileAuditSink) Query(q *usecase.AuditQuery) (*usecase.AuditQueryResult, error) {
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// the size is read while no line is being written
+	s.mu.Lock()
+	info, err := file.Stat()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	end := info.Size()
+	if q.Cursor != "" {
+		end, err = strconv.ParseInt(q.Cursor, 10, 64)
+		if err != nil || end < 0 || end > info.Size() {
+			return nil, usecase.ErrInvalidCursor
+		}
+	}
+
+	result := &usecase.AuditQueryResult{}
+	max := q.MaxEvents()
+
+	err = scanLinesBackward(file, end, func(line []byte, lineEnd int64) (bool, error) {
+
+		var record auditRecord
+		err := json.Unmarshal(line, &record)
+		if err != nil {
+			return false, err
+		}
+
+		event := record.event()
+		if !q.Matches(event) {
+			return true, nil
+		}
+
+		if len(result.Events) == max {
+			result.NextCursor = strconv.FormatInt(lineEnd, 10)
+			return false, nil
+		}
+		result.Events = append(result.Events, event)
+
+		return true, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// scanLinesBackward calls fn with the lines of the file before the offset
+// end, last line first, and the offset of the end of each line, until fn
+// returns false. Empty lines are skipped.
+func scanLinesBackward(file *os.File, end int64, fn func(line []byte, lineEnd int64) (bool, error)) error {
+
+	const chunkSize = 64 * 1024
+
+	var rest []byte // the start of the line following the chunk read last
+	for end > 0 {
+
+		start := end - chunkSize
+		if start < 0 {
+			start = 0
+		}
+
+		chunk := make([]byte, end-start, int(end-start)+len(rest))
+		_, err := file.ReadAt(chunk, start)
+		if err != nil {
+			return err
+		}
+		data := append(chunk, rest...)
+
+		for len(data) > 0 {
+
+			line := bytes.TrimSuffix(data, []byte("\n"))
+			i := bytes.LastIndexByte(line, '\n')
+			if i < 0 && start > 0 {
+				break
+			}
+
+			lineEnd := start + int64(len(data))
+			line = line[i+1:]
+			data = data[:i+1]
+
+			if len(line) == 0 {
+				continue
+			}
+
+			more, err := fn(line, lineEnd)
+			if err != nil || !more {
+				return err
+			}
+		}
+
+		rest = data
+		end = start
+	}
+
+	return nil
+}
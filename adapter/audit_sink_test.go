@@ -0,0 +1,151 @@
+package adapter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+func TestBoltAuditSink(t *testing.T) {
+
+	d := newTestData()
+	setupRepository(d)
+	defer teardownRepository(d)
+
+	testAuditSink(t, d, NewBoltAuditSink(d.database, nil))
+}
+
+func TestFileAuditSink(t *testing.T) {
+
+	d := newTestData()
+
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.jsonl")
+	sink, err := NewFileAuditSink(path)
+	if err != nil {
+		t.Fatalf("expected file sink, got: %s", err)
+	}
+
+	testAuditSink(t, d, sink)
+
+	// events are appended to an existing file
+	sink, err = NewFileAuditSink(path)
+	if err != nil {
+		t.Fatalf("expected file sink, got: %s", err)
+	}
+	if err := sink.Append(&entity.AuditEvent{Time: 4, Action: entity.AuditLoginFailed}); err != nil {
+		t.Fatalf("expected append to succeed, got: %s", err)
+	}
+
+	result, err := sink.Query(&usecase.AuditQuery{})
+	if err != nil || len(result.Events) != 4 || result.Events[0].Time != 4 {
+		t.Errorf("expected all events, got: %+v, %v", result, err)
+	}
+
+	// lines longer than the chunks the file is read backwards in
+	long := strings.Repeat("x", 100*1024)
+	if err := sink.Append(&entity.AuditEvent{Time: 5, Action: entity.AuditLoginFailed, Details: map[string]string{"long": long}}); err != nil {
+		t.Fatalf("expected append to succeed, got: %s", err)
+	}
+
+	result, err = sink.Query(&usecase.AuditQuery{Limit: 1})
+	if err != nil || len(result.Events) != 1 || result.Events[0].Details["long"] != long {
+		t.Fatalf("expected the long event, got: %v", err)
+	}
+
+	result, err = sink.Query(&usecase.AuditQuery{Cursor: result.NextCursor})
+	if err != nil || len(result.Events) != 4 || result.Events[0].Time != 4 {
+		t.Errorf("expected the events before the long one, got: %+v, %v", result, err)
+	}
+}
+
+func testAuditSink(t *testing.T, d *TestData, sink usecase.AuditSink) {
+
+	events := []*entity.AuditEvent{
+		{Time: 1, Actor: d.userName1, Action: entity.AuditLockCreate, Repo: d.repoName, Target: "a.psd", Details: map[string]string{"id": "1"}},
+		{Time: 2, Actor: d.userName2, Action: entity.AuditObjectUpload, Repo: d.repoName, Target: d.contentOid},
+		{Time: 3, Actor: d.userName1, Action: entity.AuditUserAdd, Target: d.userName2},
+	}
+	for _, e := range events {
+		if err := sink.Append(e); err != nil {
+			t.Fatalf("expected append to succeed, got: %s", err)
+		}
+	}
+
+	all, err := sink.Query(&usecase.AuditQuery{})
+	if err != nil || len(all.Events) != 3 || all.NextCursor != "" {
+		t.Fatalf("expected all events, got: %+v, %v", all, err)
+	}
+	if last := all.Events[2]; last.Action != entity.AuditLockCreate || last.Details["id"] != "1" || last.Time != 1 {
+		t.Errorf("expected the first appended event last, got: %+v", last)
+	}
+
+	// pages continue from the cursor
+	var times []int64
+	q := &usecase.AuditQuery{Limit: 2}
+	for {
+		page, err := sink.Query(q)
+		if err != nil {
+			t.Fatalf("expected query to succeed, got: %s", err)
+		}
+		for _, e := range page.Events {
+			times = append(times, e.Time)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	if len(times) != 3 || times[0] != 3 || times[1] != 2 || times[2] != 1 {
+		t.Errorf("expected all events paged, got: %v", times)
+	}
+
+	if _, err := sink.Query(&usecase.AuditQuery{Cursor: "invalid"}); err != usecase.ErrInvalidCursor {
+		t.Errorf("expected an invalid cursor to fail, got: %v", err)
+	}
+
+	queries := map[string]struct {
+		query usecase.AuditQuery
+		times []int64
+	}{
+		"actor":      {usecase.AuditQuery{Actor: d.userName1}, []int64{3, 1}},
+		"repo":       {usecase.AuditQuery{Repo: d.repoName}, []int64{2, 1}},
+		"both":       {usecase.AuditQuery{Actor: d.userName1, Repo: d.repoName}, []int64{1}},
+		"time range": {usecase.AuditQuery{Since: 2, Until: 3}, []int64{2}},
+		"limit":      {usecase.AuditQuery{Limit: 2}, []int64{3, 2}},
+		"no match":   {usecase.AuditQuery{Actor: d.userName2, Repo: "other"}, nil},
+	}
+
+	for name, q := range queries {
+		found, err := sink.Query(&q.query)
+		if err != nil {
+			t.Errorf("%s: expected query to succeed, got: %s", name, err)
+			continue
+		}
+
+		var times []int64
+		for _, e := range found.Events {
+			times = append(times, e.Time)
+		}
+		if len(times) != len(q.times) {
+			t.Errorf("%s: expected events at %v, got %v", name, q.times, times)
+			continue
+		}
+		for i := range times {
+			if times[i] != q.times[i] {
+				t.Errorf("%s: expected events at %v, got %v", name, q.times, times)
+				break
+			}
+		}
+	}
+}
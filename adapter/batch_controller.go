@@ -2,7 +2,11 @@ package adapter
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 
+	"github.com/ikmski/git-lfs3/entity"
 	"github.com/ikmski/git-lfs3/usecase"
 )
 
@@ -11,27 +15,62 @@ type BatchController interface {
 	Batch(ctx Context)
 }
 
+var errTooManyObjects = errors.New("Too many objects in batch request")
+
 type batchController struct {
-	BatchService usecase.BatchService
+	BatchService  usecase.BatchService
+	AccessService usecase.AccessService
+	Metrics       Metrics
+	MaxObjects    int
 }
 
 // NewBatchController is ...
-func NewBatchController(s usecase.BatchService) BatchController {
+// Batch requests with more than maxObjects objects are rejected, unless it is zero.
+func NewBatchController(s usecase.BatchService, access usecase.AccessService, metrics Metrics, maxObjects int) BatchController {
 	return &batchController{
-		BatchService: s,
+		BatchService:  s,
+		AccessService: access,
+		Metrics:       metricsOrNop(metrics),
+		MaxObjects:    maxObjects,
 	}
 }
 
 func (c *batchController) Batch(ctx Context) {
 
-	req, err := parseBatchRequest(ctx)
+	req, err := parseBatchRequest(ctx, c.MaxObjects)
+	if err == errTooManyObjects {
+		writeErrorResponse(ctx, 413, fmt.Sprintf("%s, the maximum is %d", err, c.MaxObjects))
+		return
+	}
 	if err != nil {
+		writeRequestError(ctx, err, "Invalid batch request")
+		return
+	}
 
+	if req.Operation == "upload" {
+		if !authorize(ctx, c.AccessService, entity.RoleWrite, entity.ScopeWrite, "You must have push access to upload objects") {
+			return
+		}
+	} else {
+		if !authorize(ctx, c.AccessService, entity.RoleRead, entity.ScopeRead, "You must have read access to download objects") {
+			return
+		}
 	}
 
+	c.Metrics.ObserveBatch(req.Repo, req.Operation, len(req.Objects))
+
 	result, err := c.BatchService.Batch(req)
+	if err == usecase.ErrTransferNotSupported {
+		writeErrorResponse(ctx, 422, err.Error())
+		return
+	}
+	if err == usecase.ErrHashAlgoNotSupported {
+		writeErrorResponse(ctx, 409, err.Error())
+		return
+	}
 	if err != nil {
-
+		writeErrorResponse(ctx, 500, "Failed to process batch request")
+		return
 	}
 
 	res := convertBatchResponse(result)
@@ -46,21 +85,15 @@ func (c *batchController) Batch(ctx Context) {
 	ctx.GetResponseWriter().Write(json)
 }
 
-func parseBatchRequest(ctx Context) (*usecase.BatchRequest, error) {
+func parseBatchRequest(ctx Context, maxObjects int) (*usecase.BatchRequest, error) {
 
-	data, err := ctx.GetRawData()
+	req, err := decodeBatchRequest(ctx.GetRequestReader(), maxObjects)
 	if err != nil {
 		return nil, err
 	}
 
-	var req BatchRequest
-	err = json.Unmarshal(data, &req)
-	if err != nil {
-		return nil, err
-	}
-
-	//user := ctx.GetParam("user")
-	//repo := ctx.GetParam("repo")
+	user := ctx.GetUser()
+	repo := repoPath(ctx)
 
 	var objs []*usecase.ObjectRequest
 	for _, o := range req.Objects {
@@ -68,52 +101,149 @@ func parseBatchRequest(ctx Context) (*usecase.BatchRequest, error) {
 		item := &usecase.ObjectRequest{
 			Oid:  o.Oid,
 			Size: o.Size,
+			Path: o.Path,
 		}
 
 		objs = append(objs, item)
 	}
 
 	br := &usecase.BatchRequest{
-		Objects: objs,
+		Operation:  req.Operation,
+		Transfers:  req.Transfers,
+		HashAlgo:   req.HashAlgo,
+		User:       user,
+		Repo:       repo,
+		Objects:    objs,
+		ObjectsURL: objectsURL(ctx),
 	}
 
 	return br, nil
 }
 
+// decodeBatchRequest decodes the objects of a batch request one at a time,
+// so that a request with too many objects fails before it is read to the end
+func decodeBatchRequest(r io.Reader, maxObjects int) (*BatchRequest, error) {
+
+	dec := json.NewDecoder(r)
+
+	err := expectDelim(dec, '{')
+	if err != nil {
+		return nil, err
+	}
+
+	var req BatchRequest
+	for dec.More() {
+
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t {
+		case "operation":
+			err = dec.Decode(&req.Operation)
+		case "transfers":
+			err = dec.Decode(&req.Transfers)
+		case "hash_algo":
+			err = dec.Decode(&req.HashAlgo)
+		case "ref":
+			err = dec.Decode(&req.Ref)
+		case "objects":
+			req.Objects, err = decodeObjectRequests(dec, maxObjects)
+		default:
+			var ignored json.RawMessage
+			err = dec.Decode(&ignored)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = expectDelim(dec, '}')
+	if err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
+
+func decodeObjectRequests(dec *json.Decoder, maxObjects int) ([]*ObjectRequest, error) {
+
+	err := expectDelim(dec, '[')
+	if err != nil {
+		return nil, err
+	}
+
+	var objs []*ObjectRequest
+	for dec.More() {
+
+		if maxObjects > 0 && len(objs) == maxObjects {
+			return nil, errTooManyObjects
+		}
+
+		var obj ObjectRequest
+		err := dec.Decode(&obj)
+		if err != nil {
+			return nil, err
+		}
+
+		objs = append(objs, &obj)
+	}
+
+	return objs, expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if t != delim {
+		return fmt.Errorf("expected %s, got %v", delim, t)
+	}
+
+	return nil
+}
+
 func convertBatchResponse(result *usecase.BatchResult) *BatchResponse {
 
 	var objs []*ResponseObject
 
 	for _, batchObj := range result.Objects {
 
-		header := make(map[string]string)
-		header["Accept"] = contentMediaType
-
 		obj := newResponseObject()
 		obj.Oid = batchObj.Oid
 		obj.Size = batchObj.Size
 
-		if batchObj.MetaExists {
-
+		if batchObj.Error != nil {
+			obj.Error = &ObjectError{
+				Code:    batchObj.Error.Code,
+				Message: batchObj.Error.Message,
+			}
+			objs = append(objs, obj)
+			continue
 		}
 
-		if batchObj.ObjectExists {
-			obj.Actions["download"] = &Link{
-				Href:   "https://hoge",
-				Header: header,
+		for name, action := range batchObj.Actions {
+			link := &Link{
+				Href:   action.Href,
+				Header: action.Header,
 			}
-		} else {
-			obj.Actions["upload"] = &Link{
-				Href:   "https://hoge",
-				Header: header,
+			if !action.ExpiresAt.IsZero() {
+				t := action.ExpiresAt.UTC()
+				link.ExpiresAt = &t
 			}
+			obj.Actions[name] = link
 		}
 
 		objs = append(objs, obj)
 	}
 
 	res := &BatchResponse{
-		Transfer: "basic",
+		Transfer: result.Transfer,
+		HashAlgo: result.HashAlgo,
 		Objects:  objs,
 	}
 
@@ -1,11 +1,13 @@
 package adapter
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
-	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"io/ioutil"
 	"path/filepath"
 	"strconv"
 
@@ -21,8 +23,8 @@ import (
 )
 
 var (
-	errHashMismatch = errors.New("Content hash does not match OID")
-	errSizeMismatch = errors.New("Content size does not match")
+	errHashMismatch = usecase.ErrHashMismatch
+	errSizeMismatch = usecase.ErrSizeMismatch
 )
 
 type contentRepository struct {
@@ -30,6 +32,52 @@ type contentRepository struct {
 	downloader s3manageriface.DownloaderAPI
 	uploader   s3manageriface.UploaderAPI
 	bucket     string
+	logger     Logger
+	metrics    Metrics
+}
+
+// verifyingReader hashes the content while it is read and fails the read
+// that exceeds the size of the meta data or ends the content if it does not
+// match, so that the uploader aborts instead of storing the content.
+type verifyingReader struct {
+	r        io.Reader
+	meta     *entity.MetaData
+	hash     hash.Hash
+	n        int64
+	err      error
+	verified bool
+}
+
+func (vr *verifyingReader) Read(p []byte) (int, error) {
+
+	if vr.err != nil {
+		return 0, vr.err
+	}
+
+	n, err := vr.r.Read(p)
+	vr.hash.Write(p[:n])
+	vr.n += int64(n)
+
+	if err != nil && err != io.EOF {
+		// keep the error of the source, the uploader wraps it
+		vr.err = err
+	} else if vr.n > vr.meta.Size {
+		vr.err = errSizeMismatch
+	} else if err == io.EOF {
+		if vr.n != vr.meta.Size {
+			vr.err = errSizeMismatch
+		} else if hex.EncodeToString(vr.hash.Sum(nil)) != vr.meta.Oid {
+			vr.err = errHashMismatch
+		} else {
+			vr.verified = true
+		}
+	}
+
+	if vr.err != nil {
+		return n, vr.err
+	}
+
+	return n, err
 }
 
 type writerWrapper struct {
@@ -40,7 +88,8 @@ func (ww writerWrapper) WriteAt(p []byte, offset int64) (n int, err error) {
 	return ww.w.Write(p)
 }
 
-func NewContentRepository(bucket string) (usecase.ContentRepository, error) {
+// NewContentRepository is ...
+func NewContentRepository(bucket string, logger Logger, metrics Metrics) (usecase.ContentRepository, error) {
 
 	sess, err := session.NewSession()
 	if err != nil {
@@ -52,6 +101,8 @@ func NewContentRepository(bucket string) (usecase.ContentRepository, error) {
 		downloader: s3manager.NewDownloader(sess),
 		uploader:   s3manager.NewUploader(sess),
 		bucket:     bucket,
+		logger:     logger,
+		metrics:    metricsOrNop(metrics),
 	}
 
 	return r, nil
@@ -75,29 +126,56 @@ func (r *contentRepository) Get(meta *entity.MetaData, w io.Writer, from int64,
 
 func (r *contentRepository) Put(meta *entity.MetaData, reader io.Reader) error {
 
-	hash := sha256.New()
-	tee := io.TeeReader(reader, hash)
+	vr := &verifyingReader{r: reader, meta: meta, hash: sha256.New()}
 
 	uploadInput := &s3manager.UploadInput{
 		Bucket: aws.String(r.bucket),
 		Key:    aws.String(transformKey(meta.Oid)),
-		Body:   tee,
+		Body:   vr,
 	}
 
 	_, err := r.uploader.Upload(uploadInput)
+	if vr.err != nil {
+		return vr.err
+	}
 	if err != nil {
-		aerr, ok := err.(awserr.Error)
-		if ok {
-			switch aerr.Code() {
-			default:
-				fmt.Println(aerr.Error())
-			}
-		} else {
-			fmt.Println(err.Error())
-		}
+		r.handleError("Upload", meta, err)
 		return err
 	}
 
+	err = r.checkStored(meta)
+	if err != nil {
+		return err
+	}
+
+	if !vr.verified {
+		return errHashMismatch
+	}
+
+	return nil
+}
+
+// Delete removes the content of the object
+func (r *contentRepository) Delete(meta *entity.MetaData) error {
+
+	input := &s3.DeleteObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(transformKey(meta.Oid)),
+	}
+
+	_, err := r.s3.DeleteObject(input)
+	if err != nil {
+		r.handleError("DeleteObject", meta, err)
+		return err
+	}
+
+	return nil
+}
+
+// checkStored checks the size of the stored object and sets the storage
+// class of meta
+func (r *contentRepository) checkStored(meta *entity.MetaData) error {
+
 	headInput := &s3.HeadObjectInput{
 		Bucket: aws.String(r.bucket),
 		Key:    aws.String(transformKey(meta.Oid)),
@@ -105,15 +183,7 @@ func (r *contentRepository) Put(meta *entity.MetaData, reader io.Reader) error {
 
 	result, err := r.s3.HeadObject(headInput)
 	if err != nil {
-		aerr, ok := err.(awserr.Error)
-		if ok {
-			switch aerr.Code() {
-			default:
-				fmt.Println(aerr.Error())
-			}
-		} else {
-			fmt.Println(err.Error())
-		}
+		r.handleError("HeadObject", meta, err)
 		return err
 	}
 
@@ -121,14 +191,114 @@ func (r *contentRepository) Put(meta *entity.MetaData, reader io.Reader) error {
 		return errSizeMismatch
 	}
 
-	shaStr := hex.EncodeToString(hash.Sum(nil))
-	if shaStr != meta.Oid {
-		return errHashMismatch
+	// S3 omits the storage class for objects in the standard class
+	meta.StorageClass = s3.StorageClassStandard
+	if result.StorageClass != nil {
+		meta.StorageClass = *result.StorageClass
+	}
+
+	return nil
+}
+
+// CreateMultipartUpload starts an upload in parts and returns its id
+func (r *contentRepository) CreateMultipartUpload(meta *entity.MetaData) (string, error) {
+
+	input := &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(transformKey(meta.Oid)),
+	}
+
+	result, err := r.s3.CreateMultipartUpload(input)
+	if err != nil {
+		r.handleError("CreateMultipartUpload", meta, err)
+		return "", err
+	}
+
+	return aws.StringValue(result.UploadId), nil
+}
+
+// UploadPart stores a part of exactly size bytes and returns its ETag.
+// S3 needs to seek the body of a part, so the part is buffered in memory.
+func (r *contentRepository) UploadPart(meta *entity.MetaData, uploadID string, number int, reader io.Reader, size int64) (string, error) {
+
+	data, err := ioutil.ReadAll(io.LimitReader(reader, size+1))
+	if err != nil {
+		return "", err
+	}
+
+	if int64(len(data)) != size {
+		return "", errSizeMismatch
+	}
+
+	input := &s3.UploadPartInput{
+		Bucket:        aws.String(r.bucket),
+		Key:           aws.String(transformKey(meta.Oid)),
+		UploadId:      aws.String(uploadID),
+		PartNumber:    aws.Int64(int64(number)),
+		ContentLength: aws.Int64(size),
+		Body:          bytes.NewReader(data),
+	}
+
+	result, err := r.s3.UploadPart(input)
+	if err != nil {
+		r.handleError("UploadPart", meta, err)
+		return "", err
+	}
+
+	return aws.StringValue(result.ETag), nil
+}
+
+// CompleteMultipartUpload assembles the parts into the object
+func (r *contentRepository) CompleteMultipartUpload(meta *entity.MetaData, uploadID string, parts []entity.UploadPart) error {
+
+	var completed []*s3.CompletedPart
+	for _, p := range parts {
+		completed = append(completed, &s3.CompletedPart{
+			ETag:       aws.String(p.ETag),
+			PartNumber: aws.Int64(int64(p.Number)),
+		})
+	}
+
+	input := &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(r.bucket),
+		Key:             aws.String(transformKey(meta.Oid)),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: completed},
+	}
+
+	_, err := r.s3.CompleteMultipartUpload(input)
+	if err != nil {
+		r.handleError("CompleteMultipartUpload", meta, err)
+		return err
+	}
+
+	return r.checkStored(meta)
+}
+
+// AbortMultipartUpload discards the upload and its parts
+func (r *contentRepository) AbortMultipartUpload(meta *entity.MetaData, uploadID string) error {
+
+	input := &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(r.bucket),
+		Key:      aws.String(transformKey(meta.Oid)),
+		UploadId: aws.String(uploadID),
+	}
+
+	_, err := r.s3.AbortMultipartUpload(input)
+	if err != nil {
+		r.handleError("AbortMultipartUpload", meta, err)
+		return err
 	}
 
 	return nil
 }
 
+// Check returns an error unless the content is stored with the size of meta
+func (r *contentRepository) Check(meta *entity.MetaData) error {
+
+	return r.checkStored(meta)
+}
+
 func (r *contentRepository) Exists(meta *entity.MetaData) bool {
 
 	input := &s3.HeadObjectInput{
@@ -138,21 +308,93 @@ func (r *contentRepository) Exists(meta *entity.MetaData) bool {
 
 	_, err := r.s3.HeadObject(input)
 	if err != nil {
-		aerr, ok := err.(awserr.Error)
-		if ok {
-			switch aerr.Code() {
-			default:
-				fmt.Println(aerr.Error())
-			}
-		} else {
-			fmt.Println(err.Error())
-		}
+		r.handleError("HeadObject", meta, err)
 		return false
 	}
 
 	return true
 }
 
+// Stat returns the size, ETag, storage class and modification time of the
+// stored content
+func (r *contentRepository) Stat(meta *entity.MetaData) (*entity.Content, error) {
+
+	input := &s3.HeadObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(transformKey(meta.Oid)),
+	}
+
+	result, err := r.s3.HeadObject(input)
+	if err != nil {
+		r.handleError("HeadObject", meta, err)
+		return nil, err
+	}
+
+	content := &entity.Content{
+		Size:         aws.Int64Value(result.ContentLength),
+		ETag:         aws.StringValue(result.ETag),
+		StorageClass: s3.StorageClassStandard,
+	}
+
+	// S3 omits the storage class for objects in the standard class
+	if result.StorageClass != nil {
+		content.StorageClass = *result.StorageClass
+	}
+
+	if result.LastModified != nil {
+		content.LastModified = result.LastModified.Unix()
+	}
+
+	return content, nil
+}
+
+// Ping checks that the bucket exists and is accessible
+func (r *contentRepository) Ping() error {
+
+	input := &s3.HeadBucketInput{
+		Bucket: aws.String(r.bucket),
+	}
+
+	_, err := r.s3.HeadBucket(input)
+	if err != nil {
+		r.handleError("HeadBucket", nil, err)
+		return err
+	}
+
+	return nil
+}
+
+// handleError logs a failed S3 request and counts it by AWS error code
+func (r *contentRepository) handleError(op string, meta *entity.MetaData, err error) {
+
+	code := ""
+	aerr, ok := err.(awserr.Error)
+	if ok {
+		code = aerr.Code()
+	}
+
+	if r.metrics != nil {
+		r.metrics.ObserveS3Error(op, code)
+	}
+
+	if r.logger == nil {
+		return
+	}
+
+	oid := ""
+	if meta != nil {
+		oid = meta.Oid
+	}
+
+	if op == "HeadObject" && (code == "NotFound" || code == s3.ErrCodeNoSuchKey) {
+		// missing objects are expected while checking for existence
+		r.logger.Debug("s3 object not found", "op", op, "bucket", r.bucket, "oid", oid, "code", code)
+		return
+	}
+
+	r.logger.Error("s3 request failed", "op", op, "bucket", r.bucket, "oid", oid, "code", code, "error", err)
+}
+
 func transformKey(key string) string {
 
 	if len(key) < 5 {
@@ -7,7 +7,9 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
@@ -47,6 +49,10 @@ func (s TestS3) HeadObject(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, err
 	return &s.headResult, s.err
 }
 
+func (s TestS3) HeadBucket(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	return &s3.HeadBucketOutput{}, s.err
+}
+
 func (d TestDownloader) Download(w io.WriterAt, input *s3.GetObjectInput, options ...func(*s3manager.Downloader)) (n int64, err error) {
 	w.WriteAt(d.content.Bytes(), 0)
 	return int64(d.content.Len()), d.err
@@ -219,3 +225,98 @@ func TestContentStoreExists(t *testing.T) {
 		t.Fatalf("expected content to exist")
 	}
 }
+
+func TestContentStoreStat(t *testing.T) {
+
+	d := newTestData()
+	modified := time.Unix(1500000000, 0)
+
+	testContentRepository = &contentRepository{
+		s3: TestS3{
+			headResult: s3.HeadObjectOutput{
+				ContentLength: aws.Int64(d.contentSize),
+				ETag:          aws.String(`"etag"`),
+				StorageClass:  aws.String(s3.StorageClassGlacier),
+				LastModified:  &modified,
+			},
+		},
+		downloader: TestDownloader{},
+		uploader:   TestUploader{},
+		bucket:     testS3BucketName,
+	}
+
+	m := &entity.MetaData{
+		Oid:  d.contentOid,
+		Size: d.contentSize,
+	}
+
+	content, err := testContentRepository.Stat(m)
+	if err != nil {
+		t.Fatalf("expected stat to succeed, got: %s", err)
+	}
+
+	if content.Size != d.contentSize || content.ETag != `"etag"` || content.StorageClass != s3.StorageClassGlacier || content.LastModified != modified.Unix() {
+		t.Errorf("expected content details, got: %+v", content)
+	}
+
+	// S3 omits the storage class of objects in the standard class
+	testContentRepository = &contentRepository{
+		s3:         TestS3{headResult: s3.HeadObjectOutput{ContentLength: aws.Int64(d.contentSize)}},
+		downloader: TestDownloader{},
+		uploader:   TestUploader{},
+		bucket:     testS3BucketName,
+	}
+
+	content, err = testContentRepository.Stat(m)
+	if err != nil || content.StorageClass != s3.StorageClassStandard {
+		t.Errorf("expected standard storage class, got: %+v, %v", content, err)
+	}
+}
+
+func TestContentStorePing(t *testing.T) {
+
+	testContentRepository = &contentRepository{
+		s3:         TestS3{},
+		downloader: TestDownloader{},
+		uploader:   TestUploader{},
+		bucket:     testS3BucketName,
+	}
+
+	if err := testContentRepository.Ping(); err != nil {
+		t.Fatalf("expected ping to succeed, got: %s", err)
+	}
+
+	testContentRepository = &contentRepository{
+		s3: TestS3{
+			err: errors.New("error"),
+		},
+		downloader: TestDownloader{},
+		uploader:   TestUploader{},
+		bucket:     testS3BucketName,
+	}
+
+	if err := testContentRepository.Ping(); err == nil {
+		t.Fatalf("expected ping to fail")
+	}
+}
+
+func TestContentStorePutTooLarge(t *testing.T) {
+
+	d := newTestData()
+	testContentRepository = &contentRepository{
+		s3:         TestS3{},
+		downloader: TestDownloader{},
+		uploader:   TestUploader{},
+		bucket:     testS3BucketName,
+	}
+
+	m := &entity.MetaData{
+		Oid:  d.contentOid,
+		Size: d.contentSize - 1,
+	}
+
+	err := testContentRepository.Put(m, bytes.NewBufferString(d.content))
+	if err != errSizeMismatch {
+		t.Fatalf("expected size mismatch while reading, got: %v", err)
+	}
+}
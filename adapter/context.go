@@ -1,6 +1,15 @@
 package adapter
 
-import "io"
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+// ErrBodyTooLarge is returned when reading a request body beyond the limit of its endpoint
+var ErrBodyTooLarge = errors.New("Request body too large")
 
 // Context is ...
 type Context interface {
@@ -10,6 +19,27 @@ type Context interface {
 	SetStatus(int)
 	SetHeader(string, string)
 
+	// GetUser returns the name of the authenticated user
+	GetUser() string
+	// GetIdentity returns the authenticated user and the limits of its credentials
+	GetIdentity() *usecase.Identity
+
+	// GetBaseURL returns the scheme and host the request was sent to
+	GetBaseURL() string
+
 	GetResponseWriter() io.Writer
+	// GetRequestReader returns the request body, which fails with
+	// ErrBodyTooLarge when it exceeds the limit of the endpoint
 	GetRequestReader() io.Reader
 }
+
+// repoPath returns the "{user}/{repo}" path of the repo of the request.
+// Permissions are granted on it and everything of the repo is keyed by it.
+func repoPath(ctx Context) string {
+	return ctx.GetParam("user") + "/" + ctx.GetParam("repo")
+}
+
+// objectsURL returns the URL of the objects of the repo of the request
+func objectsURL(ctx Context) string {
+	return fmt.Sprintf("%s/%s/%s/objects", ctx.GetBaseURL(), ctx.GetParam("user"), ctx.GetParam("repo"))
+}
@@ -0,0 +1,143 @@
+package adapter
+
+import (
+	"time"
+
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+// DeletionController is ...
+type DeletionController interface {
+	Delete(ctx Context)
+	Clear(ctx Context)
+	Tombstones(ctx Context)
+	Deletions(ctx Context)
+}
+
+const deletionAdminMessage = "You must be an admin to delete objects"
+
+type deletionController struct {
+	DeletionService usecase.DeletionService
+	AccessService   usecase.AccessService
+}
+
+// NewDeletionController is ...
+func NewDeletionController(s usecase.DeletionService, access usecase.AccessService) DeletionController {
+	return &deletionController{
+		DeletionService: s,
+		AccessService:   access,
+	}
+}
+
+// Delete removes the object from storage and every repo and tombstones it.
+// The reason parameter is required.
+func (c *deletionController) Delete(ctx Context) {
+
+	if !authorizeAdmin(ctx, c.AccessService, deletionAdminMessage) {
+		return
+	}
+
+	oid := ctx.GetParam("oid")
+	if !validateOid(ctx, oid) {
+		return
+	}
+
+	err := c.DeletionService.Delete(oid, ctx.GetUser(), ctx.GetParam("reason"))
+	switch err {
+	case nil:
+		ctx.SetStatus(204)
+	case usecase.ErrObjectNotFound:
+		writeErrorResponse(ctx, 404, "Object not found")
+	case usecase.ErrReasonRequired:
+		writeErrorResponse(ctx, 422, err.Error())
+	default:
+		writeErrorResponse(ctx, 500, "Failed to delete object")
+	}
+}
+
+// Clear removes the tombstone of a deleted object. The reason parameter is required.
+func (c *deletionController) Clear(ctx Context) {
+
+	if !authorizeAdmin(ctx, c.AccessService, deletionAdminMessage) {
+		return
+	}
+
+	oid := ctx.GetParam("oid")
+	if !validateOid(ctx, oid) {
+		return
+	}
+
+	err := c.DeletionService.Clear(oid, ctx.GetUser(), ctx.GetParam("reason"))
+	switch err {
+	case nil:
+		ctx.SetStatus(204)
+	case usecase.ErrTombstoneNotFound:
+		writeErrorResponse(ctx, 404, err.Error())
+	case usecase.ErrReasonRequired:
+		writeErrorResponse(ctx, 422, err.Error())
+	default:
+		writeErrorResponse(ctx, 500, "Failed to clear tombstone")
+	}
+}
+
+// Tombstones returns the tombstones of all deleted objects
+func (c *deletionController) Tombstones(ctx Context) {
+
+	if !authorizeAdmin(ctx, c.AccessService, deletionAdminMessage) {
+		return
+	}
+
+	tombstones, err := c.DeletionService.Tombstones()
+	if err != nil {
+		writeErrorResponse(ctx, 500, "Failed to list tombstones")
+		return
+	}
+
+	res := &TombstoneListResponse{Tombstones: []*TombstoneResponse{}}
+	for _, t := range tombstones {
+		res.Tombstones = append(res.Tombstones, &TombstoneResponse{
+			Oid:       t.Oid,
+			Size:      t.Size,
+			DeletedBy: t.DeletedBy,
+			DeletedAt: time.Unix(t.DeletedAt, 0).UTC(),
+			Reason:    t.Reason,
+		})
+	}
+
+	writeJSONResponse(ctx, 200, res)
+}
+
+// Deletions returns the record of all deletions and cleared tombstones
+func (c *deletionController) Deletions(ctx Context) {
+
+	if !authorizeAdmin(ctx, c.AccessService, deletionAdminMessage) {
+		return
+	}
+
+	deletions, err := c.DeletionService.Deletions()
+	if err != nil {
+		writeErrorResponse(ctx, 500, "Failed to list deletions")
+		return
+	}
+
+	res := &ObjectDeletionListResponse{Deletions: []*ObjectDeletionResponse{}}
+	for _, d := range deletions {
+		res.Deletions = append(res.Deletions, convertObjectDeletionResponse(d))
+	}
+
+	writeJSONResponse(ctx, 200, res)
+}
+
+func convertObjectDeletionResponse(d *entity.ObjectDeletion) *ObjectDeletionResponse {
+
+	return &ObjectDeletionResponse{
+		Action: d.Action,
+		Oid:    d.Oid,
+		Size:   d.Size,
+		Repos:  d.Repos,
+		User:   d.User,
+		Reason: d.Reason,
+		Time:   time.Unix(d.Time, 0).UTC(),
+	}
+}
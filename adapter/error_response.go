@@ -0,0 +1,125 @@
+package adapter
+
+import (
+	"encoding/json"
+
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+// writeErrorResponse writes an error in the format of the Git LFS API
+func writeErrorResponse(ctx Context, status int, message string) {
+
+	res := &ErrorResponse{
+		Message:   message,
+		RequestID: ctx.GetHeader("X-Request-ID"),
+	}
+
+	json, _ := json.Marshal(res)
+
+	ctx.SetHeader("Content-Type", metaMediaType)
+	ctx.SetStatus(status)
+	ctx.GetResponseWriter().Write(json)
+}
+
+// writeJSONResponse writes v as the JSON body of the response
+func writeJSONResponse(ctx Context, status int, v interface{}) {
+
+	json, err := json.Marshal(v)
+	if err != nil {
+		writeErrorResponse(ctx, 500, "Failed to encode response")
+		return
+	}
+
+	ctx.SetHeader("Content-Type", "application/json")
+	ctx.SetStatus(status)
+	ctx.GetResponseWriter().Write(json)
+}
+
+// authorize checks that the authenticated user has the required role on the
+// repository, and the required scope if it authenticated with a personal
+// access token, and writes an error response if not.
+func authorize(ctx Context, s usecase.AccessService, required entity.Role, scope entity.Scope, message string) bool {
+
+	err := s.Authorize(ctx.GetIdentity(), repoPath(ctx), required, scope)
+	switch err {
+	case nil:
+		return true
+	case usecase.ErrUnauthorized:
+		writeErrorResponse(ctx, 401, "Credentials needed")
+	default:
+		writeErrorResponse(ctx, 403, message)
+	}
+
+	return false
+}
+
+// authorizeUser checks that the authenticated user may manage the account of
+// user and writes an error response if not.
+func authorizeUser(ctx Context, s usecase.AccessService, user string) bool {
+
+	err := s.AuthorizeUser(ctx.GetIdentity(), user)
+	switch err {
+	case nil:
+		return true
+	case usecase.ErrUnauthorized:
+		writeErrorResponse(ctx, 401, "Credentials needed")
+	default:
+		writeErrorResponse(ctx, 403, "You must be an admin to manage the tokens of other users")
+	}
+
+	return false
+}
+
+// authorizeAdmin checks that the authenticated user is an admin and writes
+// an error response if not.
+func authorizeAdmin(ctx Context, s usecase.AccessService, message string) bool {
+
+	err := s.AuthorizeAdmin(ctx.GetIdentity())
+	switch err {
+	case nil:
+		return true
+	case usecase.ErrUnauthorized:
+		writeErrorResponse(ctx, 401, "Credentials needed")
+	default:
+		writeErrorResponse(ctx, 403, message)
+	}
+
+	return false
+}
+
+// writeObjectError writes the response of an object that may not be
+// transferred, and reports whether err was such an error.
+func writeObjectError(ctx Context, err error) bool {
+
+	objErr, ok := err.(*usecase.ObjectError)
+	if ok {
+		writeErrorResponse(ctx, objErr.Code, objErr.Message)
+	}
+
+	return ok
+}
+
+// validateOid writes an error response unless oid is a valid SHA-256 OID,
+// so that no other string is used as a storage key.
+func validateOid(ctx Context, oid string) bool {
+
+	if !entity.ValidOid(oid) {
+		writeErrorResponse(ctx, 422, "Invalid object ID")
+		return false
+	}
+
+	return true
+}
+
+// writeRequestError writes 413 for request bodies over the limit of the
+// endpoint and 422 for other invalid requests
+func writeRequestError(ctx Context, err error, message string) {
+
+	if err == ErrBodyTooLarge {
+		writeErrorResponse(ctx, 413, err.Error())
+		return
+	}
+
+	writeErrorResponse(ctx, 422, message+": "+err.Error())
+}
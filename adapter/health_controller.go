@@ -0,0 +1,51 @@
+package adapter
+
+import (
+	"encoding/json"
+
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+// HealthController is ...
+type HealthController interface {
+	Live(ctx Context)
+	Ready(ctx Context)
+}
+
+type healthController struct {
+	HealthService usecase.HealthService
+}
+
+// NewHealthController is ...
+func NewHealthController(s usecase.HealthService) HealthController {
+	return &healthController{
+		HealthService: s,
+	}
+}
+
+// Live reports that the process is up and serving requests
+func (c *healthController) Live(ctx Context) {
+
+	writeHealthResponse(ctx, 200, &HealthResponse{Status: "ok"})
+}
+
+// Ready reports whether the meta data store and the content store are usable
+func (c *healthController) Ready(ctx Context) {
+
+	err := c.HealthService.Ready()
+	if err != nil {
+		writeHealthResponse(ctx, 503, &HealthResponse{Status: "unavailable", Message: err.Error()})
+		return
+	}
+
+	writeHealthResponse(ctx, 200, &HealthResponse{Status: "ok"})
+}
+
+func writeHealthResponse(ctx Context, status int, res *HealthResponse) {
+
+	json, _ := json.Marshal(res)
+
+	ctx.SetHeader("Content-Type", "application/json")
+	ctx.SetStatus(status)
+	ctx.GetResponseWriter().Write(json)
+}
@@ -0,0 +1,101 @@
+package adapter
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+// ImportController is ...
+type ImportController interface {
+	Import(ctx Context)
+}
+
+type importController struct {
+	ImportService usecase.ImportService
+	AccessService usecase.AccessService
+}
+
+// NewImportController is ...
+func NewImportController(s usecase.ImportService, access usecase.AccessService) ImportController {
+	return &importController{
+		ImportService: s,
+		AccessService: access,
+	}
+}
+
+// Import adds objects of the source repo to the repo of the request.
+// It needs admin access to the repo and read access to the source.
+func (c *importController) Import(ctx Context) {
+
+	if !authorize(ctx, c.AccessService, entity.RoleAdmin, entity.ScopeWrite, "You must have admin access to import objects") {
+		return
+	}
+
+	req, err := parseImportRequest(ctx)
+	if err != nil {
+		writeRequestError(ctx, err, "Invalid import request")
+		return
+	}
+
+	err = c.AccessService.Authorize(ctx.GetIdentity(), req.Source, entity.RoleRead, entity.ScopeRead)
+	if err != nil {
+		writeErrorResponse(ctx, 403, "You must have read access to the source repository")
+		return
+	}
+
+	result, err := c.ImportService.Import(req)
+	if err != nil {
+		writeErrorResponse(ctx, 500, "Failed to import objects")
+		return
+	}
+
+	res := &ImportResponse{Objects: []*ImportObject{}}
+	for _, o := range result.Objects {
+		obj := &ImportObject{
+			Oid:      o.Oid,
+			Size:     o.Size,
+			Imported: o.Imported,
+		}
+		if o.Error != nil {
+			obj.Error = &ObjectError{Code: o.Error.Code, Message: o.Error.Message}
+		}
+		res.Objects = append(res.Objects, obj)
+	}
+
+	writeJSONResponse(ctx, 200, res)
+}
+
+func parseImportRequest(ctx Context) (*usecase.ImportRequest, error) {
+
+	data, err := ctx.GetRawData()
+	if err != nil {
+		return nil, err
+	}
+
+	var req ImportRequest
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !entity.ValidRepositoryPath(req.Source) {
+		return nil, errors.New("source must be <owner>/<repo>")
+	}
+
+	if req.All == (len(req.Oids) > 0) {
+		return nil, errors.New("either oids or all is required")
+	}
+
+	ir := &usecase.ImportRequest{
+		User:   ctx.GetUser(),
+		Repo:   repoPath(ctx),
+		Source: req.Source,
+		Oids:   req.Oids,
+		All:    req.All,
+	}
+
+	return ir, nil
+}
@@ -0,0 +1,279 @@
+package adapter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ikmski/git-lfs3/usecase"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	defaultJWKSRefresh = time.Hour
+	// minJWKSRefetch limits how often an unknown key id triggers a refetch
+	minJWKSRefetch = time.Minute
+	jwtLeeway      = time.Minute
+)
+
+var (
+	errNoJWKS        = errors.New("Either a JWKS file or a JWKS URL is required")
+	errNoIssuer      = errors.New("The token issuer is required")
+	errNoAudience    = errors.New("The token audience is required")
+	errUnknownKey    = errors.New("Token is signed with an unknown key")
+	errMissingClaim  = errors.New("Token has no user claim")
+	errGroupUser     = errors.New("Token has a group as user claim")
+	errInvalidGroups = errors.New("Token has an invalid groups claim")
+)
+
+// JWTConfig is ...
+type JWTConfig struct {
+	Issuer      string
+	Audience    string
+	UserClaim   string // defaults to sub
+	GroupsClaim string // defaults to groups
+	JWKSFile    string
+	JWKSURL     string
+	JWKSRefresh time.Duration // how long keys fetched from JWKSURL are cached
+}
+
+type jwtVerifier struct {
+	conf   JWTConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      *jose.JSONWebKeySet
+	fetchedAt time.Time
+}
+
+// NewJWTVerifier returns a verifier for JWTs signed by a key of the JWKS.
+// A JWKS file is loaded once, a JWKS URL is fetched now and whenever the
+// cache expires or a token names an unknown key.
+func NewJWTVerifier(conf JWTConfig) (usecase.BearerTokenVerifier, error) {
+
+	// tokens issued by any issuer or for any audience trusting the keys
+	// would be accepted otherwise
+	if conf.Issuer == "" {
+		return nil, errNoIssuer
+	}
+	if conf.Audience == "" {
+		return nil, errNoAudience
+	}
+
+	if conf.UserClaim == "" {
+		conf.UserClaim = "sub"
+	}
+	if conf.GroupsClaim == "" {
+		conf.GroupsClaim = "groups"
+	}
+	if conf.JWKSRefresh <= 0 {
+		conf.JWKSRefresh = defaultJWKSRefresh
+	}
+
+	v := &jwtVerifier{
+		conf:   conf,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+
+	switch {
+	case conf.JWKSFile != "":
+		data, err := ioutil.ReadFile(conf.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		v.keys, err = parseJWKS(data)
+		if err != nil {
+			return nil, err
+		}
+	case conf.JWKSURL != "":
+		err := v.fetch()
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errNoJWKS
+	}
+
+	return v, nil
+}
+
+// Verify checks the signature, issuer, audience and expiry of the token and
+// maps its claims to an identity
+func (v *jwtVerifier) Verify(token string) (*usecase.Identity, error) {
+
+	tok, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tok.Headers) != 1 {
+		return nil, errUnknownKey
+	}
+
+	key, err := v.key(tok.Headers[0].KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims jwt.Claims
+	var custom map[string]interface{}
+	err = tok.Claims(key, &claims, &custom)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := jwt.Expected{
+		Issuer:   v.conf.Issuer,
+		Audience: jwt.Audience{v.conf.Audience},
+		Time:     time.Now(),
+	}
+
+	err = claims.ValidateWithLeeway(expected, jwtLeeway)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Expiry == nil {
+		return nil, jwt.ErrExpired
+	}
+
+	user, _ := custom[v.conf.UserClaim].(string)
+	if user == "" {
+		return nil, errMissingClaim
+	}
+
+	// the user would be granted the permissions of the group
+	if strings.HasPrefix(user, usecase.GroupPrefix) {
+		return nil, errGroupUser
+	}
+
+	groups, err := stringsClaim(custom[v.conf.GroupsClaim])
+	if err != nil {
+		return nil, err
+	}
+
+	return &usecase.Identity{User: user, Groups: groups}, nil
+}
+
+func (v *jwtVerifier) key(kid string) (*jose.JSONWebKey, error) {
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.conf.JWKSURL != "" && time.Since(v.fetchedAt) > v.conf.JWKSRefresh {
+		v.fetchLocked()
+	}
+
+	if key := findKey(v.keys, kid); key != nil {
+		return key, nil
+	}
+
+	// the provider may have rotated its keys
+	if v.conf.JWKSURL != "" && time.Since(v.fetchedAt) > minJWKSRefetch {
+		v.fetchLocked()
+		if key := findKey(v.keys, kid); key != nil {
+			return key, nil
+		}
+	}
+
+	return nil, errUnknownKey
+}
+
+func (v *jwtVerifier) fetch() error {
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.fetchLocked()
+}
+
+// fetchLocked replaces the cached keys. On failure the previous keys are kept.
+func (v *jwtVerifier) fetchLocked() error {
+
+	v.fetchedAt = time.Now()
+
+	res, err := v.client.Get(v.conf.JWKSURL)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("Fetching JWKS failed with status %d", res.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	v.keys = keys
+	return nil
+}
+
+func parseJWKS(data []byte) (*jose.JSONWebKeySet, error) {
+
+	var keys jose.JSONWebKeySet
+	err := json.Unmarshal(data, &keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &keys, nil
+}
+
+func findKey(keys *jose.JSONWebKeySet, kid string) *jose.JSONWebKey {
+
+	if keys == nil {
+		return nil
+	}
+
+	if kid == "" {
+		// without a key id the only key of the set is used
+		if len(keys.Keys) == 1 {
+			return &keys.Keys[0]
+		}
+		return nil
+	}
+
+	found := keys.Key(kid)
+	if len(found) == 0 {
+		return nil
+	}
+
+	return &found[0]
+}
+
+// stringsClaim accepts a claim holding either a string or a list of strings
+func stringsClaim(v interface{}) ([]string, error) {
+
+	switch c := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{c}, nil
+	case []interface{}:
+		var values []string
+		for _, e := range c {
+			s, ok := e.(string)
+			if !ok {
+				return nil, errInvalidGroups
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	}
+
+	return nil, errInvalidGroups
+}
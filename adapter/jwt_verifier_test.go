@@ -0,0 +1,191 @@
+package adapter
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ikmski/git-lfs3/usecase"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	testJWKSFile   = "test-jwks.json"
+	testIssuer     = "https://idp.example.com"
+	testAudience   = "git-lfs3"
+	testSigningKID = "test-key"
+)
+
+type testClaims struct {
+	jwt.Claims
+	Groups []string `json:"groups,omitempty"`
+}
+
+func newTestSigningKey(t *testing.T, kid string) (*rsa.PrivateKey, jose.Signer, []byte) {
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %s", err)
+	}
+
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", kid))
+	if err != nil {
+		t.Fatalf("error creating signer: %s", err)
+	}
+
+	jwks, err := json.Marshal(&jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: kid, Algorithm: string(jose.RS256), Use: "sig"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("error encoding jwks: %s", err)
+	}
+
+	return key, signer, jwks
+}
+
+func signTestToken(t *testing.T, signer jose.Signer, claims testClaims) string {
+
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	if err != nil {
+		t.Fatalf("error signing token: %s", err)
+	}
+
+	return token
+}
+
+func validTestClaims(d *TestData) testClaims {
+
+	return testClaims{
+		Claims: jwt.Claims{
+			Subject:  d.userName1,
+			Issuer:   testIssuer,
+			Audience: jwt.Audience{testAudience},
+			Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Groups: []string{"designers"},
+	}
+}
+
+func TestJWTVerifier(t *testing.T) {
+
+	d := newTestData()
+	_, signer, jwks := newTestSigningKey(t, testSigningKID)
+
+	if err := ioutil.WriteFile(testJWKSFile, jwks, 0600); err != nil {
+		t.Fatalf("error writing jwks: %s", err)
+	}
+	defer os.Remove(testJWKSFile)
+
+	v, err := NewJWTVerifier(JWTConfig{
+		Issuer:   testIssuer,
+		Audience: testAudience,
+		JWKSFile: testJWKSFile,
+	})
+	if err != nil {
+		t.Fatalf("expected verifier, got: %s", err)
+	}
+
+	if _, err := NewJWTVerifier(JWTConfig{Audience: testAudience, JWKSFile: testJWKSFile}); err != errNoIssuer {
+		t.Errorf("expected a verifier without issuer to fail, got: %v", err)
+	}
+	if _, err := NewJWTVerifier(JWTConfig{Issuer: testIssuer, JWKSFile: testJWKSFile}); err != errNoAudience {
+		t.Errorf("expected a verifier without audience to fail, got: %v", err)
+	}
+
+	id, err := v.Verify(signTestToken(t, signer, validTestClaims(d)))
+	if err != nil {
+		t.Fatalf("expected token to verify, got: %s", err)
+	}
+
+	if id.User != d.userName1 {
+		t.Errorf("expected user to match, got: %s", id.User)
+	}
+
+	if len(id.Groups) != 1 || id.Groups[0] != "designers" {
+		t.Errorf("expected groups to match, got: %v", id.Groups)
+	}
+
+	claims := validTestClaims(d)
+	claims.Audience = jwt.Audience{"other"}
+	if _, err := v.Verify(signTestToken(t, signer, claims)); err == nil {
+		t.Errorf("expected token for other audience to fail")
+	}
+
+	claims = validTestClaims(d)
+	claims.Issuer = "https://evil.example.com"
+	if _, err := v.Verify(signTestToken(t, signer, claims)); err == nil {
+		t.Errorf("expected token of other issuer to fail")
+	}
+
+	claims = validTestClaims(d)
+	claims.Expiry = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	if _, err := v.Verify(signTestToken(t, signer, claims)); err == nil {
+		t.Errorf("expected expired token to fail")
+	}
+
+	claims = validTestClaims(d)
+	claims.Subject = usecase.GroupPrefix + "admins"
+	if _, err := v.Verify(signTestToken(t, signer, claims)); err != errGroupUser {
+		t.Errorf("expected token with a group as user to fail, got: %v", err)
+	}
+
+	claims = validTestClaims(d)
+	claims.Expiry = nil
+	if _, err := v.Verify(signTestToken(t, signer, claims)); err == nil {
+		t.Errorf("expected token without expiry to fail")
+	}
+
+	_, otherSigner, _ := newTestSigningKey(t, testSigningKID)
+	if _, err := v.Verify(signTestToken(t, otherSigner, validTestClaims(d))); err == nil {
+		t.Errorf("expected token signed by other key to fail")
+	}
+}
+
+func TestJWTVerifierFetchesRotatedKeys(t *testing.T) {
+
+	d := newTestData()
+	_, signer, jwks := newTestSigningKey(t, testSigningKID)
+
+	var current atomic.Value
+	current.Store(jwks)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(current.Load().([]byte))
+	}))
+	defer server.Close()
+
+	verifier, err := NewJWTVerifier(JWTConfig{
+		Issuer:   testIssuer,
+		Audience: testAudience,
+		JWKSURL:  server.URL,
+	})
+	if err != nil {
+		t.Fatalf("expected verifier, got: %s", err)
+	}
+
+	if _, err := verifier.Verify(signTestToken(t, signer, validTestClaims(d))); err != nil {
+		t.Fatalf("expected token to verify, got: %s", err)
+	}
+
+	// rotate the key and let the refetch limit pass
+	_, signer, jwks = newTestSigningKey(t, "rotated-key")
+	current.Store(jwks)
+	v := verifier.(*jwtVerifier)
+	v.fetchedAt = time.Now().Add(-2 * minJWKSRefetch)
+
+	if _, err := verifier.Verify(signTestToken(t, signer, validTestClaims(d))); err != nil {
+		t.Fatalf("expected token signed by rotated key to verify, got: %s", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/ikmski/git-lfs3/entity"
 	"github.com/ikmski/git-lfs3/usecase"
 )
 
@@ -17,23 +18,33 @@ type LockController interface {
 }
 
 type lockController struct {
-	LockService usecase.LockService
+	LockService   usecase.LockService
+	AccessService usecase.AccessService
+	Metrics       Metrics
 }
 
-func NewLockController(s usecase.LockService) LockController {
+func NewLockController(s usecase.LockService, access usecase.AccessService, metrics Metrics) LockController {
 	return &lockController{
-		LockService: s,
+		LockService:   s,
+		AccessService: access,
+		Metrics:       metricsOrNop(metrics),
 	}
 }
 
 func (c *lockController) Lock(ctx Context) {
 
+	if !authorize(ctx, c.AccessService, entity.RoleWrite, entity.ScopeLock, "You must have push access to create a lock") {
+		return
+	}
+
 	req, err := parseLockRequest(ctx)
 	if err != nil {
-
+		writeRequestError(ctx, err, "Invalid lock request")
+		return
 	}
 
 	result, err := c.LockService.Lock(req)
+	c.Metrics.ObserveLockOperation(req.Repo, "lock", err)
 	if err != nil {
 
 	}
@@ -51,14 +62,35 @@ func (c *lockController) Lock(ctx Context) {
 
 func (c *lockController) Unlock(ctx Context) {
 
+	if !authorize(ctx, c.AccessService, entity.RoleWrite, entity.ScopeLock, "You must have push access to delete a lock") {
+		return
+	}
+
 	req, err := parseUnlockRequest(ctx)
 	if err != nil {
+		writeRequestError(ctx, err, "Invalid unlock request")
+		return
+	}
 
+	if req.Force {
+		if !authorize(ctx, c.AccessService, entity.RoleAdmin, entity.ScopeLock, "You must have admin access to force delete a lock") {
+			return
+		}
 	}
 
 	result, err := c.LockService.Unlock(req)
-	if err != nil {
-
+	c.Metrics.ObserveLockOperation(req.Repo, "unlock", err)
+	switch err {
+	case nil:
+	case usecase.ErrLockNotFound:
+		writeErrorResponse(ctx, 404, err.Error())
+		return
+	case usecase.ErrNotLockOwner:
+		writeErrorResponse(ctx, 403, err.Error())
+		return
+	default:
+		writeErrorResponse(ctx, 500, "Failed to delete the lock")
+		return
 	}
 
 	res := convertUnlockResponce(result)
@@ -74,12 +106,18 @@ func (c *lockController) Unlock(ctx Context) {
 
 func (c *lockController) List(ctx Context) {
 
+	if !authorize(ctx, c.AccessService, entity.RoleRead, entity.ScopeLock, "You must have read access to list locks") {
+		return
+	}
+
 	req, err := parseListRequest(ctx)
 	if err != nil {
-
+		writeRequestError(ctx, err, "Invalid list request")
+		return
 	}
 
 	result, err := c.LockService.List(req)
+	c.Metrics.ObserveLockOperation(req.Repo, "list", err)
 	if err != nil {
 
 	}
@@ -97,12 +135,18 @@ func (c *lockController) List(ctx Context) {
 
 func (c *lockController) Verify(ctx Context) {
 
+	if !authorize(ctx, c.AccessService, entity.RoleWrite, entity.ScopeWrite, "You must have push access to verify locks") {
+		return
+	}
+
 	req, err := parseVerifyRequest(ctx)
 	if err != nil {
-
+		writeRequestError(ctx, err, "Invalid verify request")
+		return
 	}
 
 	result, err := c.LockService.Verify(req)
+	c.Metrics.ObserveLockOperation(req.Repo, "verify", err)
 	if err != nil {
 
 	}
@@ -131,8 +175,8 @@ func parseLockRequest(ctx Context) (*usecase.LockRequest, error) {
 		return nil, err
 	}
 
-	req.Repo = ctx.GetParam("repo")
-	req.User = ctx.GetParam("user")
+	req.Repo = repoPath(ctx)
+	req.User = ctx.GetUser()
 
 	return &req, nil
 }
@@ -166,8 +210,8 @@ func parseUnlockRequest(ctx Context) (*usecase.UnlockRequest, error) {
 		return nil, err
 	}
 
-	req.Repo = ctx.GetParam("repo")
-	req.User = ctx.GetParam("user")
+	req.Repo = repoPath(ctx)
+	req.User = ctx.GetUser()
 	req.ID = ctx.GetParam("id")
 
 	return &req, nil
@@ -191,7 +235,7 @@ func convertUnlockResponce(result *usecase.LockResult) *UnlockResponse {
 
 func parseListRequest(ctx Context) (*usecase.LockListRequest, error) {
 
-	repo := ctx.GetParam("repo")
+	repo := repoPath(ctx)
 	path := ctx.GetParam("path")
 	cursor := ctx.GetParam("cursor")
 	limitValue := ctx.GetParam("limit")
@@ -247,8 +291,8 @@ func parseVerifyRequest(ctx Context) (*usecase.LockVerifyRequest, error) {
 		return nil, err
 	}
 
-	req.Repo = ctx.GetParam("repo")
-	req.User = ctx.GetParam("user")
+	req.Repo = repoPath(ctx)
+	req.User = ctx.GetUser()
 	req.Path = ctx.GetParam("path")
 
 	return &req, nil
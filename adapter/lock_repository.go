@@ -1,7 +1,6 @@
 package adapter
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
@@ -15,15 +14,15 @@ import (
 
 var (
 	locksBucket = []byte("locks")
-	errNotOwner = errors.New("Attempt to delete other user's lock")
 )
 
 type lockRepository struct {
-	db *bolt.DB
+	db      *bolt.DB
+	metrics Metrics
 }
 
 // NewLockRepository is ...
-func NewLockRepository(db *bolt.DB) usecase.LockRepository {
+func NewLockRepository(db *bolt.DB, metrics Metrics) usecase.LockRepository {
 
 	db.Update(func(tx *bolt.Tx) error {
 
@@ -35,13 +34,13 @@ func NewLockRepository(db *bolt.DB) usecase.LockRepository {
 
 	})
 
-	return &lockRepository{db: db}
+	return &lockRepository{db: db, metrics: metricsOrNop(metrics)}
 }
 
 // Add write locks to the store for the repo.
 func (r *lockRepository) Add(repo string, l ...entity.Lock) error {
 
-	err := r.db.Update(func(tx *bolt.Tx) error {
+	err := boltUpdate(r.db, r.metrics, "lock.add", func(tx *bolt.Tx) error {
 
 		bucket := tx.Bucket(locksBucket)
 		if bucket == nil {
@@ -51,13 +50,13 @@ func (r *lockRepository) Add(repo string, l ...entity.Lock) error {
 		var locks []entity.Lock
 		data := bucket.Get([]byte(repo))
 		if data != nil {
-			if err := json.Unmarshal(data, &locks); err != nil {
+			if err := decodeRecord(data, &locks); err != nil {
 				return err
 			}
 		}
 		locks = append(locks, l...)
 		sort.Sort(LocksByCreatedAt(locks))
-		data, err := json.Marshal(&locks)
+		data, err := encodeRecord(&locks)
 		if err != nil {
 			return err
 		}
@@ -71,7 +70,7 @@ func (r *lockRepository) Add(repo string, l ...entity.Lock) error {
 func (r *lockRepository) Fetch(repo string) ([]entity.Lock, error) {
 
 	var locks []entity.Lock
-	err := r.db.View(func(tx *bolt.Tx) error {
+	err := boltView(r.db, r.metrics, "lock.fetch", func(tx *bolt.Tx) error {
 
 		bucket := tx.Bucket(locksBucket)
 		if bucket == nil {
@@ -80,7 +79,7 @@ func (r *lockRepository) Fetch(repo string) ([]entity.Lock, error) {
 
 		data := bucket.Get([]byte(repo))
 		if data != nil {
-			if err := json.Unmarshal(data, &locks); err != nil {
+			if err := decodeRecord(data, &locks); err != nil {
 				return err
 			}
 		}
@@ -148,7 +147,7 @@ func (r *lockRepository) FilteredFetch(repo string, path string, cursor string,
 func (r *lockRepository) Delete(repo string, user string, id string, force bool) (*entity.Lock, error) {
 
 	var deleted *entity.Lock
-	err := r.db.Update(func(tx *bolt.Tx) error {
+	err := boltUpdate(r.db, r.metrics, "lock.delete", func(tx *bolt.Tx) error {
 
 		bucket := tx.Bucket(locksBucket)
 		if bucket == nil {
@@ -158,7 +157,7 @@ func (r *lockRepository) Delete(repo string, user string, id string, force bool)
 		var locks []entity.Lock
 		data := bucket.Get([]byte(repo))
 		if data != nil {
-			if err := json.Unmarshal(data, &locks); err != nil {
+			if err := decodeRecord(data, &locks); err != nil {
 				return err
 			}
 		}
@@ -168,7 +167,7 @@ func (r *lockRepository) Delete(repo string, user string, id string, force bool)
 		for _, l := range locks {
 			if l.ID == id {
 				if l.Owner.Name != user && !force {
-					return errNotOwner
+					return usecase.ErrNotLockOwner
 				}
 				lock = l
 			} else if len(l.ID) > 0 {
@@ -184,7 +183,7 @@ func (r *lockRepository) Delete(repo string, user string, id string, force bool)
 			return bucket.Delete([]byte(repo))
 		}
 
-		data, err := json.Marshal(&newLocks)
+		data, err := encodeRecord(&newLocks)
 		if err != nil {
 			return err
 		}
@@ -197,7 +196,7 @@ func (r *lockRepository) Delete(repo string, user string, id string, force bool)
 func (s *lockRepository) FetchAll() ([]entity.Lock, error) {
 
 	var locks []entity.Lock
-	err := s.db.View(func(tx *bolt.Tx) error {
+	err := boltView(s.db, s.metrics, "lock.fetch_all", func(tx *bolt.Tx) error {
 
 		bucket := tx.Bucket(locksBucket)
 		if bucket == nil {
@@ -207,7 +206,7 @@ func (s *lockRepository) FetchAll() ([]entity.Lock, error) {
 		bucket.ForEach(func(k, v []byte) error {
 
 			var l []entity.Lock
-			if err := json.Unmarshal(v, &l); err != nil {
+			if err := decodeRecord(v, &l); err != nil {
 				return err
 			}
 
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
 )
 
 func TestLocks(t *testing.T) {
@@ -128,7 +129,7 @@ func TestDeleteLockNotOwner(t *testing.T) {
 		t.Errorf("expected DeleteLock to failed")
 	}
 
-	if err != errNotOwner {
+	if err != usecase.ErrNotLockOwner {
 		t.Errorf("expected DeleteLock error match, got: %s", err)
 	}
 }
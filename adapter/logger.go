@@ -0,0 +1,169 @@
+package adapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Logger is ...
+// Fields are given as alternating keys and values.
+type Logger interface {
+	Debug(msg string, fields ...interface{})
+	Info(msg string, fields ...interface{})
+	Warn(msg string, fields ...interface{})
+	Error(msg string, fields ...interface{})
+}
+
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+var logLevelNames = map[logLevel]string{
+	levelDebug: "debug",
+	levelInfo:  "info",
+	levelWarn:  "warn",
+	levelError: "error",
+}
+
+type logger struct {
+	mu     sync.Mutex
+	w      io.Writer
+	level  logLevel
+	format string
+}
+
+// NewLogger returns a logger writing to w.
+// level is one of debug, info, warn or error and defaults to info.
+// format is either json or logfmt and defaults to logfmt.
+func NewLogger(w io.Writer, level string, format string) (Logger, error) {
+
+	l := &logger{
+		w:      w,
+		level:  levelInfo,
+		format: "logfmt",
+	}
+
+	if level != "" {
+		found := false
+		for lv, name := range logLevelNames {
+			if strings.EqualFold(level, name) {
+				l.level = lv
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Unknown log level: %s", level)
+		}
+	}
+
+	switch strings.ToLower(format) {
+	case "":
+	case "json", "logfmt":
+		l.format = strings.ToLower(format)
+	default:
+		return nil, fmt.Errorf("Unknown log format: %s", format)
+	}
+
+	return l, nil
+}
+
+func (l *logger) Debug(msg string, fields ...interface{}) {
+	l.log(levelDebug, msg, fields)
+}
+
+func (l *logger) Info(msg string, fields ...interface{}) {
+	l.log(levelInfo, msg, fields)
+}
+
+func (l *logger) Warn(msg string, fields ...interface{}) {
+	l.log(levelWarn, msg, fields)
+}
+
+func (l *logger) Error(msg string, fields ...interface{}) {
+	l.log(levelError, msg, fields)
+}
+
+func (l *logger) log(level logLevel, msg string, fields []interface{}) {
+
+	if level < l.level {
+		return
+	}
+
+	kv := []interface{}{
+		"time", time.Now().UTC().Format(time.RFC3339Nano),
+		"level", logLevelNames[level],
+		"msg", msg,
+	}
+	kv = append(kv, fields...)
+	if len(kv)%2 != 0 {
+		kv = append(kv, nil)
+	}
+
+	var buf bytes.Buffer
+	if l.format == "json" {
+		writeJSON(&buf, kv)
+	} else {
+		writeLogfmt(&buf, kv)
+	}
+	buf.WriteByte('\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(buf.Bytes())
+}
+
+func writeJSON(buf *bytes.Buffer, kv []interface{}) {
+
+	buf.WriteByte('{')
+	for i := 0; i < len(kv); i += 2 {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, _ := json.Marshal(fmt.Sprint(kv[i]))
+		buf.Write(key)
+		buf.WriteByte(':')
+
+		value := kv[i+1]
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			data, _ = json.Marshal(fmt.Sprint(value))
+		}
+		buf.Write(data)
+	}
+	buf.WriteByte('}')
+}
+
+func writeLogfmt(buf *bytes.Buffer, kv []interface{}) {
+
+	for i := 0; i < len(kv); i += 2 {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+
+		buf.WriteString(fmt.Sprint(kv[i]))
+		buf.WriteByte('=')
+
+		value := ""
+		if kv[i+1] != nil {
+			value = fmt.Sprint(kv[i+1])
+		}
+		if value == "" || strings.ContainsAny(value, " =\"\t\n") {
+			value = fmt.Sprintf("%q", value)
+		}
+		buf.WriteString(value)
+	}
+}
@@ -0,0 +1,66 @@
+package adapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoggerJSON(t *testing.T) {
+
+	var buf bytes.Buffer
+	l, err := NewLogger(&buf, "info", "json")
+	if err != nil {
+		t.Fatalf("expected logger, got: %s", err)
+	}
+
+	l.Debug("hidden")
+	l.Info("request", "status", 200, "error", errors.New("boom"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected debug message to be filtered, got: %v", lines)
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("expected json log entry, got: %s", err)
+	}
+
+	if entry["level"] != "info" || entry["msg"] != "request" {
+		t.Errorf("expected level and message, got: %v", entry)
+	}
+
+	if entry["status"] != float64(200) || entry["error"] != "boom" {
+		t.Errorf("expected fields, got: %v", entry)
+	}
+}
+
+func TestLoggerLogfmt(t *testing.T) {
+
+	var buf bytes.Buffer
+	l, err := NewLogger(&buf, "", "")
+	if err != nil {
+		t.Fatalf("expected logger, got: %s", err)
+	}
+
+	l.Warn("s3 request failed", "code", "NoSuchKey", "route", "")
+
+	line := buf.String()
+	if !strings.Contains(line, `level=warn msg="s3 request failed" code=NoSuchKey route=""`) {
+		t.Errorf("expected logfmt entry, got: %s", line)
+	}
+}
+
+func TestLoggerInvalidConfig(t *testing.T) {
+
+	if _, err := NewLogger(&bytes.Buffer{}, "verbose", ""); err == nil {
+		t.Errorf("expected unknown level to fail")
+	}
+
+	if _, err := NewLogger(&bytes.Buffer{}, "", "xml"); err == nil {
+		t.Errorf("expected unknown format to fail")
+	}
+}
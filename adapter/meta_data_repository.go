@@ -1,9 +1,8 @@
 package adapter
 
 import (
-	"bytes"
-	"encoding/gob"
 	"errors"
+	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/ikmski/git-lfs3/entity"
@@ -11,27 +10,39 @@ import (
 )
 
 var (
-	metaBucket = []byte("meta")
+	metaBucket      = []byte("meta")
+	repoUsageBucket = []byte("repo_usage")
+	userUsageBucket = []byte("user_usage")
+	// repoRefsBucket holds a bucket per repo with the oids it references
+	repoRefsBucket = []byte("repo_refs")
 )
 
+type usageRecord struct {
+	Size    int64 `json:"size"`
+	Objects int64 `json:"objects"`
+}
+
 type metaDataRepository struct {
-	db *bolt.DB
+	db      *bolt.DB
+	metrics Metrics
 }
 
 // NewMetaDataRepository is ...
-func NewMetaDataRepository(db *bolt.DB) usecase.MetaDataRepository {
+func NewMetaDataRepository(db *bolt.DB, metrics Metrics) usecase.MetaDataRepository {
 
 	db.Update(func(tx *bolt.Tx) error {
 
-		_, err := tx.CreateBucketIfNotExists(metaBucket)
-		if err != nil {
-			return err
+		for _, name := range [][]byte{metaBucket, repoUsageBucket, userUsageBucket, repoRefsBucket} {
+			_, err := tx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return err
+			}
 		}
 		return nil
 
 	})
 
-	return &metaDataRepository{db: db}
+	return &metaDataRepository{db: db, metrics: metricsOrNop(metrics)}
 }
 
 // Get retrieves the Meta information for an object given information in
@@ -49,7 +60,7 @@ func (r *metaDataRepository) UnsafeGet(oid string) (*entity.MetaData, error) {
 
 	var meta entity.MetaData
 
-	err := r.db.View(func(tx *bolt.Tx) error {
+	err := boltView(r.db, r.metrics, "meta.get", func(tx *bolt.Tx) error {
 
 		bucket := tx.Bucket(metaBucket)
 		if bucket == nil {
@@ -61,8 +72,7 @@ func (r *metaDataRepository) UnsafeGet(oid string) (*entity.MetaData, error) {
 			return errors.New("Object not found")
 		}
 
-		dec := gob.NewDecoder(bytes.NewBuffer(value))
-		return dec.Decode(&meta)
+		return decodeRecord(value, &meta)
 	})
 
 	if err != nil {
@@ -72,68 +82,299 @@ func (r *metaDataRepository) UnsafeGet(oid string) (*entity.MetaData, error) {
 	return &meta, nil
 }
 
-// Put writes meta information from Object to the store.
-func (r *metaDataRepository) Put(oid string, size int64) (*entity.MetaData, error) {
+// GetMany retrieves the meta information of many objects in one
+// transaction. Objects that are not found are left out.
+func (r *metaDataRepository) GetMany(oids []string) (map[string]*entity.MetaData, error) {
 
-	// Check if it exists first
-	meta, err := r.Get(oid)
-	if err == nil {
-		return meta, nil
-	}
+	metas := make(map[string]*entity.MetaData, len(oids))
+
+	err := boltView(r.db, r.metrics, "meta.get_many", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(metaBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		for _, oid := range oids {
+
+			value := bucket.Get([]byte(oid))
+			if len(value) == 0 {
+				continue
+			}
 
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
-	meta = &entity.MetaData{
-		Oid:  oid,
-		Size: size,
+			var meta entity.MetaData
+			err := decodeRecord(value, &meta)
+			if err != nil {
+				return err
+			}
+
+			metas[oid] = &meta
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
-	err = enc.Encode(meta)
+	return metas, nil
+}
+
+// Put writes meta information from Object to the store.
+// If the object already exists, the repos of meta are added to it and the
+// stored meta information is returned.
+// The usage of the uploader of a new object and of every repo the object is
+// added to grows by its size.
+func (r *metaDataRepository) Put(meta *entity.MetaData) (*entity.MetaData, error) {
+
+	var stored entity.MetaData
+
+	err := boltUpdate(r.db, r.metrics, "meta.put", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(metaBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		value := bucket.Get([]byte(meta.Oid))
+		if len(value) == 0 {
+			stored = *meta
+			stored.Repos = nil
+			if stored.CreatedAt == 0 {
+				stored.CreatedAt = time.Now().Unix()
+			}
+
+			err := addUsage(tx, userUsageBucket, stored.Uploader.Name, stored.Size, 1)
+			if err != nil {
+				return err
+			}
+		} else {
+			err := decodeRecord(value, &stored)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, repo := range meta.Repos {
+			err := addReference(tx, &stored, repo)
+			if err != nil {
+				return err
+			}
+		}
+
+		return putMetaData(bucket, &stored)
+	})
+
 	if err != nil {
 		return nil, err
 	}
 
-	err = r.db.Update(func(tx *bolt.Tx) error {
+	return &stored, nil
+}
+
+// Touch records that the content of the object was accessed at accessedAt.
+// Only the access time is written, so that it cannot undo a concurrent
+// change of the references.
+func (r *metaDataRepository) Touch(oid string, accessedAt int64) error {
+
+	_, err := r.modify("meta.touch", oid, func(tx *bolt.Tx, meta *entity.MetaData) error {
+		meta.LastAccessedAt = accessedAt
+		return nil
+	})
+
+	return err
+}
+
+// MarkComplete records that the content of the object is stored in the
+// storage class
+func (r *metaDataRepository) MarkComplete(oid string, storageClass string) error {
+
+	_, err := r.modify("meta.mark_complete", oid, func(tx *bolt.Tx, meta *entity.MetaData) error {
+		meta.Complete = true
+		meta.StorageClass = storageClass
+		return nil
+	})
+
+	return err
+}
+
+// Delete removes the meta information from Object to the store and its
+// size from the usage of its uploader and repos.
+func (r *metaDataRepository) Delete(oid string) error {
+
+	err := boltUpdate(r.db, r.metrics, "meta.delete", func(tx *bolt.Tx) error {
 
 		bucket := tx.Bucket(metaBucket)
 		if bucket == nil {
 			return errors.New("Bucket not found")
 		}
 
-		err = bucket.Put([]byte(oid), buf.Bytes())
+		value := bucket.Get([]byte(oid))
+		if len(value) == 0 {
+			return nil
+		}
+
+		var meta entity.MetaData
+		err := decodeRecord(value, &meta)
 		if err != nil {
 			return err
 		}
 
-		return nil
+		err = addUsage(tx, userUsageBucket, meta.Uploader.Name, -meta.Size, -1)
+		if err != nil {
+			return err
+		}
+
+		for _, repo := range meta.Repos {
+			err = removeReference(tx, &meta, repo)
+			if err != nil {
+				return err
+			}
+		}
+
+		return bucket.Delete([]byte(oid))
+	})
+
+	return err
+}
+
+// DeleteUnreferenced deletes the meta information of the object unless a repo
+// references it or it is shared, checking the references in the same
+// transaction, and returns it if it was deleted.
+func (r *metaDataRepository) DeleteUnreferenced(oid string) (*entity.MetaData, error) {
+
+	var deleted *entity.MetaData
+
+	err := boltUpdate(r.db, r.metrics, "meta.delete_unreferenced", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(metaBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		value := bucket.Get([]byte(oid))
+		if len(value) == 0 {
+			return nil
+		}
+
+		var meta entity.MetaData
+		err := decodeRecord(value, &meta)
+		if err != nil {
+			return err
+		}
+
+		if len(meta.Repos) > 0 || meta.Shared {
+			return nil
+		}
+
+		err = addUsage(tx, userUsageBucket, meta.Uploader.Name, -meta.Size, -1)
+		if err != nil {
+			return err
+		}
+
+		deleted = &meta
+		return bucket.Delete([]byte(oid))
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	return meta, nil
+	return deleted, nil
 }
 
-// Delete removes the meta information from Object to the store.
-func (r *metaDataRepository) Delete(oid string) error {
+// AddReference adds the object to the repo and returns the number of repos
+// referencing it. The usage of the repo grows by its size if it is new to it.
+func (r *metaDataRepository) AddReference(repo string, oid string) (int, error) {
+
+	return r.updateReferences("meta.add_reference", oid, func(tx *bolt.Tx, meta *entity.MetaData) error {
+		return addReference(tx, meta, repo)
+	})
+}
+
+// RemoveReference removes the object from the repo and returns the number of
+// repos still referencing it. The meta information is kept when none do, to
+// be deleted by DeleteUnreferenced.
+func (r *metaDataRepository) RemoveReference(repo string, oid string) (int, error) {
+
+	return r.updateReferences("meta.remove_reference", oid, func(tx *bolt.Tx, meta *entity.MetaData) error {
+		return removeReference(tx, meta, repo)
+	})
+}
 
-	err := r.db.Update(func(tx *bolt.Tx) error {
+func (r *metaDataRepository) updateReferences(op string, oid string, update func(tx *bolt.Tx, meta *entity.MetaData) error) (int, error) {
+
+	meta, err := r.modify(op, oid, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(meta.Repos), nil
+}
+
+// modify applies update to the meta information of the object and writes it
+// back in the same transaction
+func (r *metaDataRepository) modify(op string, oid string, update func(tx *bolt.Tx, meta *entity.MetaData) error) (*entity.MetaData, error) {
+
+	var meta entity.MetaData
+
+	err := boltUpdate(r.db, r.metrics, op, func(tx *bolt.Tx) error {
 
 		bucket := tx.Bucket(metaBucket)
 		if bucket == nil {
 			return errors.New("Bucket not found")
 		}
 
-		err := bucket.Delete([]byte(oid))
+		value := bucket.Get([]byte(oid))
+		if len(value) == 0 {
+			return usecase.ErrObjectNotFound
+		}
+
+		err := decodeRecord(value, &meta)
 		if err != nil {
 			return err
 		}
 
-		return nil
+		err = update(tx, &meta)
+		if err != nil {
+			return err
+		}
+
+		return putMetaData(bucket, &meta)
 	})
 
-	return err
+	if err != nil {
+		return nil, err
+	}
+
+	return &meta, nil
+}
+
+// References returns the oids of the objects the repo references
+func (r *metaDataRepository) References(repo string) ([]string, error) {
+
+	var oids []string
+
+	err := boltView(r.db, r.metrics, "meta.references", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(repoRefsBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		repoBucket := bucket.Bucket([]byte(repo))
+		if repoBucket == nil {
+			return nil
+		}
+
+		return repoBucket.ForEach(func(k, v []byte) error {
+			oids = append(oids, string(k))
+			return nil
+		})
+	})
+
+	return oids, err
 }
 
 // Objects returns all MetaObjects in the meta store
@@ -141,7 +382,7 @@ func (r *metaDataRepository) Objects() ([]*entity.MetaData, error) {
 
 	var objects []*entity.MetaData
 
-	err := r.db.View(func(tx *bolt.Tx) error {
+	err := boltView(r.db, r.metrics, "meta.objects", func(tx *bolt.Tx) error {
 
 		bucket := tx.Bucket(metaBucket)
 		if bucket == nil {
@@ -151,8 +392,7 @@ func (r *metaDataRepository) Objects() ([]*entity.MetaData, error) {
 		bucket.ForEach(func(k, v []byte) error {
 
 			var meta entity.MetaData
-			dec := gob.NewDecoder(bytes.NewBuffer(v))
-			err := dec.Decode(&meta)
+			err := decodeRecord(v, &meta)
 			if err != nil {
 				return err
 			}
@@ -165,3 +405,180 @@ func (r *metaDataRepository) Objects() ([]*entity.MetaData, error) {
 
 	return objects, err
 }
+
+// RepoUsage returns the storage used by the objects of the repo
+func (r *metaDataRepository) RepoUsage(repo string) (*entity.Usage, error) {
+
+	return r.usage("meta.repo_usage", repoUsageBucket, repo)
+}
+
+// UserUsage returns the storage used by the objects the user uploaded
+func (r *metaDataRepository) UserUsage(user string) (*entity.Usage, error) {
+
+	return r.usage("meta.user_usage", userUsageBucket, user)
+}
+
+func (r *metaDataRepository) usage(op string, name []byte, key string) (*entity.Usage, error) {
+
+	var record usageRecord
+
+	err := boltView(r.db, r.metrics, op, func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(name)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		value := bucket.Get([]byte(key))
+		if len(value) == 0 {
+			return nil
+		}
+
+		return decodeRecord(value, &record)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.Usage{Size: record.Size, Objects: record.Objects}, nil
+}
+
+// Ping checks that the store answers a read transaction
+func (r *metaDataRepository) Ping() error {
+
+	err := boltView(r.db, r.metrics, "meta.ping", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(metaBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+func putMetaData(bucket *bolt.Bucket, meta *entity.MetaData) error {
+
+	data, err := encodeRecord(meta)
+	if err != nil {
+		return err
+	}
+
+	return bucket.Put([]byte(meta.Oid), data)
+}
+
+// addReference adds repo to the repos of meta unless it is there, and counts
+// the object in the usage and references of the repo
+func addReference(tx *bolt.Tx, meta *entity.MetaData, repo string) error {
+
+	n := len(meta.Repos)
+	meta.Repos = appendRepo(meta.Repos, repo)
+	if len(meta.Repos) == n {
+		return nil
+	}
+
+	err := addUsage(tx, repoUsageBucket, repo, meta.Size, 1)
+	if err != nil {
+		return err
+	}
+
+	refs, err := tx.CreateBucketIfNotExists(repoRefsBucket)
+	if err != nil {
+		return err
+	}
+
+	repoBucket, err := refs.CreateBucketIfNotExists([]byte(repo))
+	if err != nil {
+		return err
+	}
+
+	return repoBucket.Put([]byte(meta.Oid), []byte{})
+}
+
+// removeReference is the reverse of addReference
+func removeReference(tx *bolt.Tx, meta *entity.MetaData, repo string) error {
+
+	var repos []string
+	for _, r := range meta.Repos {
+		if r != repo {
+			repos = append(repos, r)
+		}
+	}
+	if len(repos) == len(meta.Repos) {
+		return nil
+	}
+	meta.Repos = repos
+
+	err := addUsage(tx, repoUsageBucket, repo, -meta.Size, -1)
+	if err != nil {
+		return err
+	}
+
+	refs := tx.Bucket(repoRefsBucket)
+	if refs == nil {
+		return nil
+	}
+
+	repoBucket := refs.Bucket([]byte(repo))
+	if repoBucket == nil {
+		return nil
+	}
+
+	return repoBucket.Delete([]byte(meta.Oid))
+}
+
+func appendRepo(repos []string, repo string) []string {
+
+	if repo == "" {
+		return repos
+	}
+
+	for _, r := range repos {
+		if r == repo {
+			return repos
+		}
+	}
+
+	return append(repos, repo)
+}
+
+// addUsage adds size and objects to the usage counter of key
+func addUsage(tx *bolt.Tx, name []byte, key string, size int64, objects int64) error {
+
+	if key == "" {
+		return nil
+	}
+
+	bucket, err := tx.CreateBucketIfNotExists(name)
+	if err != nil {
+		return err
+	}
+
+	var record usageRecord
+	value := bucket.Get([]byte(key))
+	if len(value) != 0 {
+		err := decodeRecord(value, &record)
+		if err != nil {
+			return err
+		}
+	}
+
+	record.Size += size
+	record.Objects += objects
+	if record.Size < 0 {
+		record.Size = 0
+	}
+	if record.Objects < 0 {
+		record.Objects = 0
+	}
+
+	data, err := encodeRecord(&record)
+	if err != nil {
+		return err
+	}
+
+	return bucket.Put([]byte(key), data)
+}
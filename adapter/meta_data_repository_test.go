@@ -2,6 +2,9 @@ package adapter
 
 import (
 	"testing"
+
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
 )
 
 func TestGetMeta(t *testing.T) {
@@ -24,13 +27,33 @@ func TestGetMeta(t *testing.T) {
 	}
 }
 
+func TestGetManyMeta(t *testing.T) {
+
+	d := newTestData()
+	setupRepository(d)
+	defer teardownRepository(d)
+
+	metas, err := d.metaDataRepository.GetMany([]string{d.contentOid, d.nonExistContentOid})
+	if err != nil {
+		t.Fatalf("Error retreiving metas: %s", err)
+	}
+
+	if len(metas) != 1 {
+		t.Fatalf("expected only the existing object, got: %v", metas)
+	}
+
+	if meta := metas[d.contentOid]; meta == nil || meta.Size != d.contentSize {
+		t.Errorf("expected to get content meta, got: %+v", meta)
+	}
+}
+
 func TestPutMeta(t *testing.T) {
 
 	d := newTestData()
 	setupRepository(d)
 	defer teardownRepository(d)
 
-	meta, err := d.metaDataRepository.Put(d.nonExistContentOid, d.nonExitContentSize)
+	meta, err := d.metaDataRepository.Put(&entity.MetaData{Oid: d.nonExistContentOid, Size: d.nonExitContentSize})
 	if err != nil {
 		t.Errorf("expected put to succeed, got : %s", err)
 	}
@@ -54,7 +77,7 @@ func TestPutMeta(t *testing.T) {
 		t.Errorf("expected sizes to match, got: %d", meta.Size)
 	}
 
-	meta, err = d.metaDataRepository.Put(d.nonExistContentOid, d.nonExitContentSize)
+	meta, err = d.metaDataRepository.Put(&entity.MetaData{Oid: d.nonExistContentOid, Size: d.nonExitContentSize})
 	if err != nil {
 		t.Errorf("expected put to succeed, got : %s", err)
 	}
@@ -65,3 +88,237 @@ func TestPutMeta(t *testing.T) {
 		}
 	*/
 }
+
+func TestPutMetaWithDetails(t *testing.T) {
+
+	d := newTestData()
+	setupRepository(d)
+	defer teardownRepository(d)
+
+	_, err := d.metaDataRepository.Put(&entity.MetaData{
+		Oid:      d.nonExistContentOid,
+		Size:     d.nonExitContentSize,
+		Uploader: entity.User{Name: d.userName1},
+		Repos:    []string{d.repoName},
+	})
+	if err != nil {
+		t.Fatalf("expected put to succeed, got : %s", err)
+	}
+
+	meta, err := d.metaDataRepository.Put(&entity.MetaData{
+		Oid:      d.nonExistContentOid,
+		Size:     d.nonExitContentSize,
+		Uploader: entity.User{Name: d.userName2},
+		Repos:    []string{"other-repo"},
+	})
+	if err != nil {
+		t.Fatalf("expected put to succeed, got : %s", err)
+	}
+
+	if meta.CreatedAt == 0 {
+		t.Errorf("expected created time to be set")
+	}
+
+	if meta.Uploader.Name != d.userName1 {
+		t.Errorf("expected uploader to be kept, got: %s", meta.Uploader.Name)
+	}
+
+	if len(meta.Repos) != 2 || meta.Repos[0] != d.repoName || meta.Repos[1] != "other-repo" {
+		t.Errorf("expected repos to be merged, got: %v", meta.Repos)
+	}
+
+	if meta.Complete {
+		t.Errorf("expected meta to be incomplete")
+	}
+}
+
+func TestTouchAndMarkCompleteMeta(t *testing.T) {
+
+	d := newTestData()
+	setupRepository(d)
+	defer teardownRepository(d)
+
+	if err := d.metaDataRepository.MarkComplete(d.contentOid, "STANDARD_IA"); err != nil {
+		t.Fatalf("expected mark complete to succeed, got : %s", err)
+	}
+
+	// a reference added meanwhile is not overwritten
+	if _, err := d.metaDataRepository.AddReference("other", d.contentOid); err != nil {
+		t.Fatalf("expected reference to succeed, got : %s", err)
+	}
+
+	if err := d.metaDataRepository.Touch(d.contentOid, 1588291200); err != nil {
+		t.Fatalf("expected touch to succeed, got : %s", err)
+	}
+
+	meta, err := d.metaDataRepository.Get(d.contentOid)
+	if err != nil {
+		t.Fatalf("Error retreiving meta: %s", err)
+	}
+
+	if !meta.Complete {
+		t.Errorf("expected meta to be complete")
+	}
+
+	if meta.StorageClass != "STANDARD_IA" {
+		t.Errorf("expected storage class to match, got: %s", meta.StorageClass)
+	}
+
+	if meta.LastAccessedAt != 1588291200 {
+		t.Errorf("expected last accessed time to match, got: %d", meta.LastAccessedAt)
+	}
+
+	if len(meta.Repos) != 1 || meta.Repos[0] != "other" {
+		t.Errorf("expected the added reference to be kept, got: %v", meta.Repos)
+	}
+
+	if err := d.metaDataRepository.Touch(d.nonExistContentOid, 1588291200); err == nil {
+		t.Errorf("expected touch of non existing meta to fail")
+	}
+
+	if err := d.metaDataRepository.MarkComplete(d.nonExistContentOid, "STANDARD_IA"); err == nil {
+		t.Errorf("expected mark complete of non existing meta to fail")
+	}
+}
+
+func TestMetaDataUsage(t *testing.T) {
+
+	d := newTestData()
+	setupRepository(d)
+	defer teardownRepository(d)
+
+	meta := &entity.MetaData{
+		Oid:      d.nonExistContentOid,
+		Size:     d.nonExitContentSize,
+		Uploader: entity.User{Name: d.userName1},
+		Repos:    []string{d.repoName},
+	}
+
+	if _, err := d.metaDataRepository.Put(meta); err != nil {
+		t.Fatalf("expected put to succeed, got: %s", err)
+	}
+
+	// adding the object again or to another repo does not count for the uploader
+	meta.Repos = []string{d.repoName, "other"}
+	if _, err := d.metaDataRepository.Put(meta); err != nil {
+		t.Fatalf("expected put to succeed, got: %s", err)
+	}
+
+	usage, err := d.metaDataRepository.RepoUsage(d.repoName)
+	if err != nil {
+		t.Fatalf("expected usage, got: %s", err)
+	}
+	if usage.Size != d.nonExitContentSize || usage.Objects != 1 {
+		t.Errorf("expected repo usage of one object, got: %+v", usage)
+	}
+
+	usage, _ = d.metaDataRepository.RepoUsage("other")
+	if usage.Size != d.nonExitContentSize || usage.Objects != 1 {
+		t.Errorf("expected other repo usage of one object, got: %+v", usage)
+	}
+
+	usage, _ = d.metaDataRepository.UserUsage(d.userName1)
+	if usage.Size != d.nonExitContentSize || usage.Objects != 1 {
+		t.Errorf("expected user usage of one object, got: %+v", usage)
+	}
+
+	if err := d.metaDataRepository.Delete(d.nonExistContentOid); err != nil {
+		t.Fatalf("expected delete to succeed, got: %s", err)
+	}
+
+	usage, _ = d.metaDataRepository.RepoUsage(d.repoName)
+	if usage.Size != 0 || usage.Objects != 0 {
+		t.Errorf("expected no repo usage after delete, got: %+v", usage)
+	}
+
+	usage, _ = d.metaDataRepository.UserUsage(d.userName1)
+	if usage.Size != 0 || usage.Objects != 0 {
+		t.Errorf("expected no user usage after delete, got: %+v", usage)
+	}
+}
+
+func TestMetaDataReferences(t *testing.T) {
+
+	d := newTestData()
+	setupRepository(d)
+	defer teardownRepository(d)
+
+	meta := &entity.MetaData{
+		Oid:      d.nonExistContentOid,
+		Size:     d.nonExitContentSize,
+		Uploader: entity.User{Name: d.userName1},
+		Repos:    []string{d.repoName},
+	}
+
+	if _, err := d.metaDataRepository.Put(meta); err != nil {
+		t.Fatalf("expected put to succeed, got: %s", err)
+	}
+
+	if _, err := d.metaDataRepository.AddReference("other", d.contentOid+"0"); err != usecase.ErrObjectNotFound {
+		t.Errorf("expected reference to a missing object to fail, got: %v", err)
+	}
+
+	refs, err := d.metaDataRepository.AddReference("other", d.nonExistContentOid)
+	if err != nil || refs != 2 {
+		t.Fatalf("expected two references, got %d: %v", refs, err)
+	}
+
+	// references are counted once per repo
+	refs, _ = d.metaDataRepository.AddReference("other", d.nonExistContentOid)
+	if refs != 2 {
+		t.Errorf("expected two references after adding one again, got %d", refs)
+	}
+
+	oids, err := d.metaDataRepository.References("other")
+	if err != nil || len(oids) != 1 || oids[0] != d.nonExistContentOid {
+		t.Errorf("expected the other repo to reference the object, got: %v, %v", oids, err)
+	}
+
+	usage, _ := d.metaDataRepository.RepoUsage("other")
+	if usage.Size != d.nonExitContentSize || usage.Objects != 1 {
+		t.Errorf("expected other repo usage of one object, got: %+v", usage)
+	}
+
+	refs, _ = d.metaDataRepository.RemoveReference(d.repoName, d.nonExistContentOid)
+	if refs != 1 {
+		t.Errorf("expected one reference left, got %d", refs)
+	}
+
+	refs, _ = d.metaDataRepository.RemoveReference("other", d.nonExistContentOid)
+	if refs != 0 {
+		t.Errorf("expected no references left, got %d", refs)
+	}
+
+	if oids, _ := d.metaDataRepository.References("other"); len(oids) != 0 {
+		t.Errorf("expected no references of the other repo, got: %v", oids)
+	}
+
+	usage, _ = d.metaDataRepository.RepoUsage("other")
+	if usage.Size != 0 || usage.Objects != 0 {
+		t.Errorf("expected no other repo usage, got: %+v", usage)
+	}
+
+	// the meta data is kept until it is deleted on its own
+	if _, err := d.metaDataRepository.Get(d.nonExistContentOid); err != nil {
+		t.Errorf("expected meta without references to be kept, got: %s", err)
+	}
+
+	// and is not deleted once referenced again
+	d.metaDataRepository.AddReference("other", d.nonExistContentOid)
+	if deleted, err := d.metaDataRepository.DeleteUnreferenced(d.nonExistContentOid); err != nil || deleted != nil {
+		t.Errorf("expected referenced meta not to be deleted, got: %+v, %v", deleted, err)
+	}
+
+	d.metaDataRepository.RemoveReference("other", d.nonExistContentOid)
+	if deleted, err := d.metaDataRepository.DeleteUnreferenced(d.nonExistContentOid); err != nil || deleted == nil || deleted.Oid != d.nonExistContentOid {
+		t.Errorf("expected unreferenced meta to be deleted, got: %+v, %v", deleted, err)
+	}
+
+	if _, err := d.metaDataRepository.Get(d.nonExistContentOid); err == nil {
+		t.Errorf("expected meta to be deleted")
+	}
+
+	if usage, _ := d.metaDataRepository.UserUsage(d.userName1); usage.Objects != 0 {
+		t.Errorf("expected no user usage after delete, got: %+v", usage)
+	}
+}
@@ -0,0 +1,146 @@
+package adapter
+
+import (
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics is ...
+type Metrics interface {
+	ObserveBatch(repo string, operation string, objects int)
+	ObserveTransfer(repo string, direction string, bytes int64, d time.Duration)
+	ObserveS3Error(op string, code string)
+	ObserveLockOperation(repo string, op string, err error)
+	ObserveDBTransaction(op string, d time.Duration)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveBatch(string, string, int)                     {}
+func (nopMetrics) ObserveTransfer(string, string, int64, time.Duration) {}
+func (nopMetrics) ObserveS3Error(string, string)                        {}
+func (nopMetrics) ObserveLockOperation(string, string, error)           {}
+func (nopMetrics) ObserveDBTransaction(string, time.Duration)           {}
+
+func metricsOrNop(m Metrics) Metrics {
+	if m == nil {
+		return nopMetrics{}
+	}
+	return m
+}
+
+type prometheusMetrics struct {
+	batchObjects      *prometheus.CounterVec
+	transferBytes     *prometheus.CounterVec
+	transferDurations *prometheus.HistogramVec
+	s3Errors          *prometheus.CounterVec
+	lockOperations    *prometheus.CounterVec
+	dbTransactions    *prometheus.HistogramVec
+}
+
+// NewPrometheusMetrics registers the server metrics with reg.
+func NewPrometheusMetrics(reg prometheus.Registerer) (Metrics, error) {
+
+	m := &prometheusMetrics{
+		batchObjects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gitlfs3",
+			Name:      "batch_objects_total",
+			Help:      "Number of objects requested through the batch API.",
+		}, []string{"repo", "operation"}),
+		transferBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gitlfs3",
+			Name:      "transfer_bytes_total",
+			Help:      "Number of bytes uploaded or downloaded.",
+		}, []string{"repo", "direction"}),
+		transferDurations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gitlfs3",
+			Name:      "transfer_duration_seconds",
+			Help:      "Duration of uploads and downloads.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 4, 10),
+		}, []string{"repo", "direction"}),
+		s3Errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gitlfs3",
+			Name:      "s3_errors_total",
+			Help:      "Number of failed S3 requests by AWS error code.",
+		}, []string{"op", "code"}),
+		lockOperations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gitlfs3",
+			Name:      "lock_operations_total",
+			Help:      "Number of lock operations.",
+		}, []string{"repo", "op", "result"}),
+		dbTransactions: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gitlfs3",
+			Name:      "db_transaction_duration_seconds",
+			Help:      "Latency of bolt transactions.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, []string{"op"}),
+	}
+
+	collectors := []prometheus.Collector{
+		m.batchObjects,
+		m.transferBytes,
+		m.transferDurations,
+		m.s3Errors,
+		m.lockOperations,
+		m.dbTransactions,
+	}
+
+	for _, c := range collectors {
+		err := reg.Register(c)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func (m *prometheusMetrics) ObserveBatch(repo string, operation string, objects int) {
+	m.batchObjects.WithLabelValues(repo, operation).Add(float64(objects))
+}
+
+func (m *prometheusMetrics) ObserveTransfer(repo string, direction string, bytes int64, d time.Duration) {
+	m.transferBytes.WithLabelValues(repo, direction).Add(float64(bytes))
+	m.transferDurations.WithLabelValues(repo, direction).Observe(d.Seconds())
+}
+
+func (m *prometheusMetrics) ObserveS3Error(op string, code string) {
+	if code == "" {
+		code = "unknown"
+	}
+	m.s3Errors.WithLabelValues(op, code).Inc()
+}
+
+func (m *prometheusMetrics) ObserveLockOperation(repo string, op string, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	m.lockOperations.WithLabelValues(repo, op, result).Inc()
+}
+
+func (m *prometheusMetrics) ObserveDBTransaction(op string, d time.Duration) {
+	m.dbTransactions.WithLabelValues(op).Observe(d.Seconds())
+}
+
+// boltView runs fn in a read-only transaction and records its latency.
+func boltView(db *bolt.DB, m Metrics, op string, fn func(tx *bolt.Tx) error) error {
+
+	start := time.Now()
+	err := db.View(fn)
+	m.ObserveDBTransaction(op, time.Since(start))
+
+	return err
+}
+
+// boltUpdate runs fn in a read-write transaction and records its latency.
+func boltUpdate(db *bolt.DB, m Metrics, op string, fn func(tx *bolt.Tx) error) error {
+
+	start := time.Now()
+	err := db.Update(fn)
+	m.ObserveDBTransaction(op, time.Since(start))
+
+	return err
+}
@@ -0,0 +1,74 @@
+package adapter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+
+	d := newTestData()
+
+	reg := prometheus.NewRegistry()
+	metrics, err := NewPrometheusMetrics(reg)
+	if err != nil {
+		t.Fatalf("expected metrics to register, got: %s", err)
+	}
+	m := metrics.(*prometheusMetrics)
+
+	r := &contentRepository{
+		s3: TestS3{
+			err: awserr.New("AccessDenied", "Access Denied", nil),
+		},
+		downloader: TestDownloader{},
+		uploader:   TestUploader{},
+		bucket:     testS3BucketName,
+		metrics:    metrics,
+	}
+
+	meta := &entity.MetaData{
+		Oid:  d.contentOid,
+		Size: d.contentSize,
+	}
+
+	if r.Exists(meta) {
+		t.Fatalf("expected content not to exist")
+	}
+
+	if v := testutil.ToFloat64(m.s3Errors.WithLabelValues("HeadObject", "AccessDenied")); v != 1 {
+		t.Errorf("expected one s3 error, got: %v", v)
+	}
+
+	metrics.ObserveBatch(d.repoName, "upload", 3)
+	if v := testutil.ToFloat64(m.batchObjects.WithLabelValues(d.repoName, "upload")); v != 3 {
+		t.Errorf("expected three batch objects, got: %v", v)
+	}
+
+	metrics.ObserveTransfer(d.repoName, "download", d.contentSize, time.Second)
+	if v := testutil.ToFloat64(m.transferBytes.WithLabelValues(d.repoName, "download")); v != float64(d.contentSize) {
+		t.Errorf("expected transferred bytes, got: %v", v)
+	}
+
+	metrics.ObserveLockOperation(d.repoName, "unlock", usecase.ErrNotLockOwner)
+	if v := testutil.ToFloat64(m.lockOperations.WithLabelValues(d.repoName, "unlock", "error")); v != 1 {
+		t.Errorf("expected one failed lock operation, got: %v", v)
+	}
+
+	setupRepository(d)
+	defer teardownRepository(d)
+
+	repo := NewMetaDataRepository(d.database, metrics)
+	if _, err := repo.Get(d.contentOid); err != nil {
+		t.Fatalf("expected meta, got: %s", err)
+	}
+
+	if n := testutil.CollectAndCount(m.dbTransactions); n != 1 {
+		t.Errorf("expected db transaction latency to be recorded, got: %d", n)
+	}
+}
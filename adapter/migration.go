@@ -0,0 +1,477 @@
+package adapter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/boltdb/bolt"
+	"github.com/ikmski/git-lfs3/entity"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	schemaBucket     = []byte("schema")
+	schemaVersionKey = []byte("version")
+)
+
+type migration struct {
+	version     int
+	description string
+	migrate     func(tx *bolt.Tx) error
+}
+
+// migrations must be kept in ascending order of version.
+// A database without a schema version record is at version 0.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "encode all records with a version marker",
+		migrate:     migrateVersionedRecords,
+	},
+	{
+		version:     2,
+		description: "hash user passwords",
+		migrate:     migrateHashedPasswords,
+	},
+	{
+		version:     3,
+		description: "count the storage usage of repos and users",
+		migrate:     migrateUsage,
+	},
+	{
+		version:     4,
+		description: "index the objects referenced by each repo",
+		migrate:     migrateRepoRefs,
+	},
+	{
+		version:     5,
+		description: "index the webhook deliveries by url and due time",
+		migrate:     migrateWebhookQueues,
+	},
+	{
+		version:     6,
+		description: "index the audit events by actor and repo",
+		migrate:     migrateAuditIndexes,
+	},
+	{
+		version:     7,
+		description: "key the locks by owner/repo",
+		migrate:     migrateLockRepos,
+	},
+}
+
+// userRecordV1 is the user record written by schema version 1.
+type userRecordV1 struct {
+	Password string `json:"password"`
+}
+
+// SchemaVersion returns the schema version of the database.
+func SchemaVersion(db *bolt.DB) (int, error) {
+
+	var version int
+
+	err := db.View(func(tx *bolt.Tx) error {
+		var err error
+		version, err = schemaVersion(tx)
+		return err
+	})
+
+	return version, err
+}
+
+// LatestSchemaVersion returns the schema version this build writes.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// Migrate applies all pending migrations to the database.
+// Each migration runs in its own transaction together with the update of
+// the schema version, so an interrupted run can safely be resumed.
+func Migrate(db *bolt.DB) error {
+
+	version, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	if version > LatestSchemaVersion() {
+		return fmt.Errorf("Database schema version %d is newer than supported version %d", version, LatestSchemaVersion())
+	}
+
+	for _, m := range migrations {
+
+		if m.version <= version {
+			continue
+		}
+
+		err := db.Update(func(tx *bolt.Tx) error {
+
+			err := m.migrate(tx)
+			if err != nil {
+				return err
+			}
+
+			return setSchemaVersion(tx, m.version)
+		})
+
+		if err != nil {
+			return fmt.Errorf("Migration to schema version %d (%s) failed: %s", m.version, m.description, err)
+		}
+	}
+
+	return nil
+}
+
+func schemaVersion(tx *bolt.Tx) (int, error) {
+
+	bucket := tx.Bucket(schemaBucket)
+	if bucket == nil {
+		return 0, nil
+	}
+
+	value := bucket.Get(schemaVersionKey)
+	if len(value) == 0 {
+		return 0, nil
+	}
+
+	if len(value) != 8 {
+		return 0, fmt.Errorf("Invalid schema version record")
+	}
+
+	return int(binary.BigEndian.Uint64(value)), nil
+}
+
+func setSchemaVersion(tx *bolt.Tx, version int) error {
+
+	bucket, err := tx.CreateBucketIfNotExists(schemaBucket)
+	if err != nil {
+		return err
+	}
+
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, uint64(version))
+
+	return bucket.Put(schemaVersionKey, value)
+}
+
+// rewriteBucket replaces every value of the bucket with the result of fn.
+// Missing buckets are skipped.
+func rewriteBucket(tx *bolt.Tx, name []byte, fn func(v []byte) ([]byte, error)) error {
+
+	bucket := tx.Bucket(name)
+	if bucket == nil {
+		return nil
+	}
+
+	records := make(map[string][]byte)
+
+	err := bucket.ForEach(func(k, v []byte) error {
+
+		data, err := fn(v)
+		if err != nil {
+			return fmt.Errorf("%s/%s: %s", name, k, err)
+		}
+
+		records[string(k)] = data
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for k, data := range records {
+		err := bucket.Put([]byte(k), data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// migrateVersionedRecords converts the gob encoded meta data, the plain JSON
+// lock lists and the raw user passwords into versioned records.
+// Legacy objects are taken as complete, as their uploads were not tracked,
+// and batch requests still check that their content is stored. They are
+// shared by all repos, as the repos they were uploaded to are not known.
+func migrateVersionedRecords(tx *bolt.Tx) error {
+
+	err := rewriteBucket(tx, metaBucket, func(v []byte) ([]byte, error) {
+
+		var meta entity.MetaData
+		dec := gob.NewDecoder(bytes.NewBuffer(v))
+		err := dec.Decode(&meta)
+		if err != nil {
+			return nil, err
+		}
+		meta.Complete = true
+		meta.Shared = true
+
+		return encodeRecord(&meta)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = rewriteBucket(tx, locksBucket, func(v []byte) ([]byte, error) {
+
+		var locks []entity.Lock
+		err := json.Unmarshal(v, &locks)
+		if err != nil {
+			return nil, err
+		}
+
+		return encodeRecord(&locks)
+	})
+	if err != nil {
+		return err
+	}
+
+	return rewriteBucket(tx, usersBucket, func(v []byte) ([]byte, error) {
+		return encodeRecord(&userRecordV1{Password: string(v)})
+	})
+}
+
+// migrateHashedPasswords replaces the plain text user passwords with bcrypt hashes.
+func migrateHashedPasswords(tx *bolt.Tx) error {
+
+	return rewriteBucket(tx, usersBucket, func(v []byte) ([]byte, error) {
+
+		var record userRecordV1
+		err := decodeRecord(v, &record)
+		if err != nil {
+			return nil, err
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(record.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+
+		return encodeRecord(&userRecord{PasswordHash: string(hash)})
+	})
+}
+
+// migrateUsage computes the usage counters from the existing meta data.
+func migrateUsage(tx *bolt.Tx) error {
+
+	for _, name := range [][]byte{repoUsageBucket, userUsageBucket} {
+		if tx.Bucket(name) != nil {
+			err := tx.DeleteBucket(name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	bucket := tx.Bucket(metaBucket)
+	if bucket == nil {
+		return nil
+	}
+
+	return bucket.ForEach(func(k, v []byte) error {
+
+		var meta entity.MetaData
+		err := decodeRecord(v, &meta)
+		if err != nil {
+			return fmt.Errorf("%s/%s: %s", metaBucket, k, err)
+		}
+
+		err = addUsage(tx, userUsageBucket, meta.Uploader.Name, meta.Size, 1)
+		if err != nil {
+			return err
+		}
+
+		for _, repo := range meta.Repos {
+			err = addUsage(tx, repoUsageBucket, repo, meta.Size, 1)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// migrateRepoRefs indexes the repos of every object, so that the objects of
+// a repo can be found without reading all meta data.
+func migrateRepoRefs(tx *bolt.Tx) error {
+
+	if tx.Bucket(repoRefsBucket) != nil {
+		err := tx.DeleteBucket(repoRefsBucket)
+		if err != nil {
+			return err
+		}
+	}
+
+	refs, err := tx.CreateBucket(repoRefsBucket)
+	if err != nil {
+		return err
+	}
+
+	bucket := tx.Bucket(metaBucket)
+	if bucket == nil {
+		return nil
+	}
+
+	return bucket.ForEach(func(k, v []byte) error {
+
+		var meta entity.MetaData
+		err := decodeRecord(v, &meta)
+		if err != nil {
+			return fmt.Errorf("%s/%s: %s", metaBucket, k, err)
+		}
+
+		for _, repo := range meta.Repos {
+			repoBucket, err := refs.CreateBucketIfNotExists([]byte(repo))
+			if err != nil {
+				return err
+			}
+
+			err = repoBucket.Put(k, []byte{})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// migrateWebhookQueues queues the deliveries by URL in the order they were
+// enqueued, and indexes the first of each URL by the time of its next attempt.
+func migrateWebhookQueues(tx *bolt.Tx) error {
+
+	for _, name := range [][]byte{webhookQueuesBucket, webhookDueBucket} {
+		if tx.Bucket(name) != nil {
+			err := tx.DeleteBucket(name)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.CreateBucket(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	bucket := tx.Bucket(webhookDeliveriesBucket)
+	if bucket == nil {
+		return nil
+	}
+
+	return bucket.ForEach(func(k, v []byte) error {
+
+		var delivery entity.WebhookDelivery
+		err := decodeRecord(v, &delivery)
+		if err != nil {
+			return fmt.Errorf("%s/%s: %s", webhookDeliveriesBucket, k, err)
+		}
+
+		return queueDelivery(tx, k, &delivery)
+	})
+}
+
+// migrateAuditIndexes indexes the audit events by actor and repo.
+func migrateAuditIndexes(tx *bolt.Tx) error {
+
+	for _, name := range [][]byte{auditActorsBucket, auditReposBucket} {
+		if tx.Bucket(name) != nil {
+			err := tx.DeleteBucket(name)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.CreateBucket(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	bucket := tx.Bucket(auditEventsBucket)
+	if bucket == nil {
+		return nil
+	}
+
+	return bucket.ForEach(func(k, v []byte) error {
+
+		var record auditRecord
+		err := decodeRecord(v, &record)
+		if err != nil {
+			return fmt.Errorf("%s/%s: %s", auditEventsBucket, k, err)
+		}
+
+		return indexAuditEvent(tx, k, record.event())
+	})
+}
+
+// migrateLockRepos moves the locks keyed by a bare repo name, as servers
+// before repos were keyed by owner/repo stored them, to the owner/repo of
+// the user who created each lock, which is the only owner they record.
+func migrateLockRepos(tx *bolt.Tx) error {
+
+	bucket := tx.Bucket(locksBucket)
+	if bucket == nil {
+		return nil
+	}
+
+	legacy := make(map[string][]entity.Lock)
+	err := bucket.ForEach(func(k, v []byte) error {
+
+		if bytes.IndexByte(k, '/') >= 0 {
+			return nil
+		}
+
+		var locks []entity.Lock
+		err := decodeRecord(v, &locks)
+		if err != nil {
+			return fmt.Errorf("%s/%s: %s", locksBucket, k, err)
+		}
+		legacy[string(k)] = locks
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for repo, locks := range legacy {
+
+		err := bucket.Delete([]byte(repo))
+		if err != nil {
+			return err
+		}
+
+		for _, lock := range locks {
+			key := []byte(lock.Owner.Name + "/" + repo)
+
+			var moved []entity.Lock
+			if data := bucket.Get(key); data != nil {
+				err := decodeRecord(data, &moved)
+				if err != nil {
+					return err
+				}
+			}
+			moved = append(moved, lock)
+			sort.Sort(LocksByCreatedAt(moved))
+
+			data, err := encodeRecord(&moved)
+			if err != nil {
+				return err
+			}
+
+			err = bucket.Put(key, data)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
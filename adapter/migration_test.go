@@ -0,0 +1,267 @@
+package adapter
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+const legacyDatabaseFile = "test-legacy-database.db"
+
+// legacyMetaData is the layout of entity.MetaData before schema version 1.
+type legacyMetaData struct {
+	Oid  string
+	Size int64
+}
+
+// openLegacyFixture creates a database in the layout written before
+// schema versioning was introduced, when objects were not tracked by repo
+// and locks were keyed by the bare repo name.
+func openLegacyFixture(t *testing.T, d *TestData) *bolt.DB {
+
+	os.Remove(legacyDatabaseFile)
+
+	db, err := bolt.Open(legacyDatabaseFile, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		t.Fatalf("error creating legacy fixture: %s", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		enc := gob.NewEncoder(&buf)
+		err = enc.Encode(&legacyMetaData{Oid: d.contentOid, Size: d.contentSize})
+		if err != nil {
+			return err
+		}
+
+		err = meta.Put([]byte(d.contentOid), buf.Bytes())
+		if err != nil {
+			return err
+		}
+
+		locks, err := tx.CreateBucketIfNotExists(locksBucket)
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal([]entity.Lock{
+			NewTestLock(d.lockID, d.lockPath, d.userName1),
+		})
+		if err != nil {
+			return err
+		}
+
+		err = locks.Put([]byte(d.repoName), data)
+		if err != nil {
+			return err
+		}
+
+		users, err := tx.CreateBucketIfNotExists(usersBucket)
+		if err != nil {
+			return err
+		}
+
+		return users.Put([]byte(d.userName1), []byte(d.userPass1))
+	})
+	if err != nil {
+		db.Close()
+		t.Fatalf("error seeding legacy fixture: %s", err)
+	}
+
+	return db
+}
+
+func TestMigrateEmptyDatabase(t *testing.T) {
+
+	os.Remove(legacyDatabaseFile)
+	defer os.Remove(legacyDatabaseFile)
+
+	db, err := bolt.Open(legacyDatabaseFile, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		t.Fatalf("error creating database: %s", err)
+	}
+	defer db.Close()
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("expected migration to succeed, got: %s", err)
+	}
+
+	version, err := SchemaVersion(db)
+	if err != nil {
+		t.Fatalf("expected schema version, got: %s", err)
+	}
+
+	if version != LatestSchemaVersion() {
+		t.Errorf("expected schema version %d, got: %d", LatestSchemaVersion(), version)
+	}
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+
+	d := newTestData()
+	db := openLegacyFixture(t, d)
+	defer os.Remove(legacyDatabaseFile)
+	defer db.Close()
+
+	version, err := SchemaVersion(db)
+	if err != nil {
+		t.Fatalf("expected schema version, got: %s", err)
+	}
+
+	if version != 0 {
+		t.Errorf("expected legacy schema version 0, got: %d", version)
+	}
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("expected migration to succeed, got: %s", err)
+	}
+
+	// running the migrations again must be a no-op
+	if err := Migrate(db); err != nil {
+		t.Fatalf("expected second migration to succeed, got: %s", err)
+	}
+
+	meta, err := NewMetaDataRepository(db, nil).Get(d.contentOid)
+	if err != nil {
+		t.Fatalf("expected migrated meta, got: %s", err)
+	}
+
+	if meta.Oid != d.contentOid || meta.Size != d.contentSize || !meta.Complete || !meta.Shared {
+		t.Errorf("expected migrated meta to match, got: %v", meta)
+	}
+
+	// the object is readable in the repos that used it before
+	if !meta.InRepo(d.userName1 + "/" + d.repoName) {
+		t.Errorf("expected migrated meta to be in the repo, got: %v", meta.Repos)
+	}
+
+	lockRepo := NewLockRepository(db, nil)
+	locks, err := lockRepo.Fetch(d.userName1 + "/" + d.repoName)
+	if err != nil {
+		t.Fatalf("expected migrated locks, got: %s", err)
+	}
+
+	if len(locks) != 1 || locks[0].ID != d.lockID || locks[0].Owner.Name != d.userName1 {
+		t.Errorf("expected migrated locks to match, got: %v", locks)
+	}
+
+	if locks, _ := lockRepo.Fetch(d.repoName); len(locks) != 0 {
+		t.Errorf("expected no locks left under the bare repo name, got: %v", locks)
+	}
+
+	if _, err := lockRepo.Delete(d.userName1+"/"+d.repoName, d.userName1, d.lockID, false); err != nil {
+		t.Errorf("expected the migrated lock to be unlocked, got: %s", err)
+	}
+
+	users, err := NewUserRepository(db, nil).Users()
+	if err != nil {
+		t.Fatalf("expected migrated users, got: %s", err)
+	}
+
+	if len(users) != 1 || users[0].Name != d.userName1 {
+		t.Errorf("expected migrated users to match, got: %v", users)
+	}
+
+	if _, err := NewUserRepository(db, nil).Authenticate(d.userName1, d.userPass1); err != nil {
+		t.Errorf("expected migrated password to authenticate, got: %s", err)
+	}
+}
+
+func TestMigrateNewerDatabase(t *testing.T) {
+
+	os.Remove(legacyDatabaseFile)
+	defer os.Remove(legacyDatabaseFile)
+
+	db, err := bolt.Open(legacyDatabaseFile, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		t.Fatalf("error creating database: %s", err)
+	}
+	defer db.Close()
+
+	db.Update(func(tx *bolt.Tx) error {
+		return setSchemaVersion(tx, LatestSchemaVersion()+1)
+	})
+
+	if err := Migrate(db); err == nil {
+		t.Errorf("expected migration of newer database to fail")
+	}
+}
+
+func TestMigrateUsage(t *testing.T) {
+
+	d := newTestData()
+	os.Remove(legacyDatabaseFile)
+	defer os.Remove(legacyDatabaseFile)
+
+	db, err := bolt.Open(legacyDatabaseFile, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		t.Fatalf("error creating database: %s", err)
+	}
+	defer db.Close()
+
+	// meta data written by schema version 2, without usage counters
+	err = db.Update(func(tx *bolt.Tx) error {
+
+		bucket, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+
+		err = putMetaData(bucket, &entity.MetaData{
+			Oid:      d.contentOid,
+			Size:     d.contentSize,
+			Uploader: entity.User{Name: d.userName1},
+			Repos:    []string{d.repoName},
+		})
+		if err != nil {
+			return err
+		}
+
+		return setSchemaVersion(tx, 2)
+	})
+	if err != nil {
+		t.Fatalf("error seeding database: %s", err)
+	}
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("expected migration to succeed, got: %s", err)
+	}
+
+	repo := NewMetaDataRepository(db, nil)
+
+	usage, err := repo.RepoUsage(d.repoName)
+	if err != nil {
+		t.Fatalf("expected usage, got: %s", err)
+	}
+	if usage.Size != d.contentSize || usage.Objects != 1 {
+		t.Errorf("expected repo usage of one object, got: %+v", usage)
+	}
+
+	usage, err = repo.UserUsage(d.userName1)
+	if err != nil {
+		t.Fatalf("expected usage, got: %s", err)
+	}
+	if usage.Size != d.contentSize || usage.Objects != 1 {
+		t.Errorf("expected user usage of one object, got: %+v", usage)
+	}
+
+	oids, err := repo.References(d.repoName)
+	if err != nil {
+		t.Fatalf("expected references, got: %s", err)
+	}
+	if len(oids) != 1 || oids[0] != d.contentOid {
+		t.Errorf("expected the repo to reference the object, got: %v", oids)
+	}
+}
@@ -2,10 +2,14 @@ package adapter
 
 import (
 	"bytes"
+	"crypto/md5"
 	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
@@ -14,7 +18,8 @@ import (
 )
 
 type MockedS3Data struct {
-	data *bytes.Buffer
+	data     *bytes.Buffer
+	modified time.Time
 }
 
 func NewMockedS3Data() *MockedS3Data {
@@ -26,6 +31,7 @@ func (d *MockedS3Data) Read(p []byte) (int, error) {
 }
 
 func (d *MockedS3Data) Write(p []byte) (int, error) {
+	d.modified = time.Now()
 	d.data.Reset()
 	return d.data.Write(p)
 }
@@ -44,6 +50,9 @@ func (d *MockedS3Data) Len() int64 {
 
 var mockedDataStore map[string]*MockedS3Data
 
+// mockedUploads holds the parts of multipart uploads by upload id
+var mockedUploads map[string]map[int64][]byte
+
 type MockedS3 struct {
 	s3iface.S3API
 	getResult  s3.GetObjectOutput
@@ -82,8 +91,11 @@ func (ms MockedS3) HeadObject(input *s3.HeadObjectInput) (*s3.HeadObjectOutput,
 	d, ok := mockedDataStore[*input.Key]
 	if ok {
 		len := d.Len()
+		etag := fmt.Sprintf("%q", fmt.Sprintf("%x", md5.Sum(d.Bytes())))
 		result := s3.HeadObjectOutput{
 			ContentLength: &len,
+			ETag:          &etag,
+			LastModified:  &d.modified,
 		}
 
 		return &result, nil
@@ -92,6 +104,70 @@ func (ms MockedS3) HeadObject(input *s3.HeadObjectInput) (*s3.HeadObjectOutput,
 	return &s3.HeadObjectOutput{}, errors.New("")
 }
 
+func (ms MockedS3) DeleteObject(input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+
+	delete(mockedDataStore, *input.Key)
+
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (ms MockedS3) HeadBucket(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func (ms MockedS3) CreateMultipartUpload(input *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error) {
+
+	uploadID := fmt.Sprintf("upload-%d", len(mockedUploads)+1)
+	mockedUploads[uploadID] = make(map[int64][]byte)
+
+	return &s3.CreateMultipartUploadOutput{UploadId: &uploadID}, nil
+}
+
+func (ms MockedS3) UploadPart(input *s3.UploadPartInput) (*s3.UploadPartOutput, error) {
+
+	parts, ok := mockedUploads[*input.UploadId]
+	if !ok {
+		return &s3.UploadPartOutput{}, errors.New("NoSuchUpload")
+	}
+
+	data, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return &s3.UploadPartOutput{}, err
+	}
+	parts[*input.PartNumber] = data
+
+	etag := fmt.Sprintf("etag-%d", *input.PartNumber)
+	return &s3.UploadPartOutput{ETag: &etag}, nil
+}
+
+func (ms MockedS3) CompleteMultipartUpload(input *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
+
+	parts, ok := mockedUploads[*input.UploadId]
+	if !ok {
+		return &s3.CompleteMultipartUploadOutput{}, errors.New("NoSuchUpload")
+	}
+
+	var content []byte
+	for _, p := range input.MultipartUpload.Parts {
+		content = append(content, parts[*p.PartNumber]...)
+	}
+
+	d := NewMockedS3Data()
+	d.Write(content)
+	mockedDataStore[*input.Key] = d
+	delete(mockedUploads, *input.UploadId)
+
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (ms MockedS3) AbortMultipartUpload(input *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error) {
+
+	delete(mockedUploads, *input.UploadId)
+
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
 func (md MockedDownloader) Download(w io.WriterAt, input *s3.GetObjectInput, options ...func(*s3manager.Downloader)) (n int64, err error) {
 
 	d, ok := mockedDataStore[*input.Key]
@@ -140,6 +216,7 @@ func (mu MockedUploader) Upload(input *s3manager.UploadInput, options ...func(*s
 func NewMockedContentRepository(bucket string) (*contentRepository, error) {
 
 	mockedDataStore = make(map[string]*MockedS3Data)
+	mockedUploads = make(map[string]map[int64][]byte)
 	contentStore := &contentRepository{
 		s3:         MockedS3{},
 		downloader: MockedDownloader{},
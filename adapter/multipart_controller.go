@@ -0,0 +1,166 @@
+package adapter
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+// MultipartController is ...
+type MultipartController interface {
+	Start(ctx Context)
+	UploadPart(ctx Context)
+	Complete(ctx Context)
+	Abort(ctx Context)
+}
+
+type multipartController struct {
+	MultipartService usecase.MultipartService
+	AccessService    usecase.AccessService
+	Metrics          Metrics
+}
+
+// NewMultipartController is ...
+func NewMultipartController(s usecase.MultipartService, access usecase.AccessService, metrics Metrics) MultipartController {
+	return &multipartController{
+		MultipartService: s,
+		AccessService:    access,
+		Metrics:          metricsOrNop(metrics),
+	}
+}
+
+// Start starts the upload of an object or returns the progress of the upload in progress
+func (c *multipartController) Start(ctx Context) {
+
+	if !authorize(ctx, c.AccessService, entity.RoleWrite, entity.ScopeWrite, "You must have push access to upload objects") {
+		return
+	}
+
+	o := parseObjectRequest(ctx)
+	if !validateOid(ctx, o.Oid) {
+		return
+	}
+
+	upload, err := c.MultipartService.Start(o)
+	if err != nil {
+		writeMultipartError(ctx, err)
+		return
+	}
+
+	writeJSONResponse(ctx, 200, convertMultipartUploadResponse(upload))
+}
+
+// UploadPart stores the next part of the upload
+func (c *multipartController) UploadPart(ctx Context) {
+
+	if !authorize(ctx, c.AccessService, entity.RoleWrite, entity.ScopeWrite, "You must have push access to upload objects") {
+		return
+	}
+
+	o := parseObjectRequest(ctx)
+	if !validateOid(ctx, o.Oid) {
+		return
+	}
+
+	number, err := strconv.Atoi(ctx.GetParam("part"))
+	if err != nil {
+		writeErrorResponse(ctx, 400, "Invalid part number")
+		return
+	}
+
+	o.Size = -1
+	if cl := ctx.GetHeader("Content-Length"); cl != "" {
+		n, err := strconv.ParseInt(cl, 10, 64)
+		if err != nil || n < 0 {
+			writeErrorResponse(ctx, 400, "Invalid Content-Length")
+			return
+		}
+		o.Size = n
+	}
+
+	start := time.Now()
+	r := &countingReader{r: ctx.GetRequestReader()}
+	upload, err := c.MultipartService.UploadPart(o, number, r)
+	if err != nil {
+		writeMultipartError(ctx, err)
+		return
+	}
+
+	c.Metrics.ObserveTransfer(o.Repo, "upload", r.n, time.Since(start))
+
+	writeJSONResponse(ctx, 200, convertMultipartUploadResponse(upload))
+}
+
+// Complete assembles the uploaded parts into the object
+func (c *multipartController) Complete(ctx Context) {
+
+	if !authorize(ctx, c.AccessService, entity.RoleWrite, entity.ScopeWrite, "You must have push access to upload objects") {
+		return
+	}
+
+	o := parseObjectRequest(ctx)
+	if !validateOid(ctx, o.Oid) {
+		return
+	}
+
+	err := c.MultipartService.Complete(o)
+	if err != nil {
+		writeMultipartError(ctx, err)
+		return
+	}
+
+	ctx.SetStatus(200)
+}
+
+// Abort discards the upload and its parts
+func (c *multipartController) Abort(ctx Context) {
+
+	if !authorize(ctx, c.AccessService, entity.RoleWrite, entity.ScopeWrite, "You must have push access to upload objects") {
+		return
+	}
+
+	o := parseObjectRequest(ctx)
+	if !validateOid(ctx, o.Oid) {
+		return
+	}
+
+	err := c.MultipartService.Abort(o)
+	if err != nil {
+		writeMultipartError(ctx, err)
+		return
+	}
+
+	ctx.SetStatus(204)
+}
+
+func writeMultipartError(ctx Context, err error) {
+
+	switch err {
+	case usecase.ErrObjectNotFound, usecase.ErrUploadNotStarted:
+		writeErrorResponse(ctx, 404, err.Error())
+	case usecase.ErrContentLengthMismatch:
+		writeErrorResponse(ctx, 400, err.Error())
+	case usecase.ErrUnexpectedPart:
+		writeErrorResponse(ctx, 409, err.Error())
+	case usecase.ErrSizeMismatch, usecase.ErrHashMismatch, usecase.ErrUploadIncomplete:
+		writeErrorResponse(ctx, 422, err.Error())
+	default:
+		if !writeObjectError(ctx, err) {
+			writeErrorResponse(ctx, 500, "Failed to store the object")
+		}
+	}
+}
+
+func convertMultipartUploadResponse(upload *entity.MultipartUpload) *MultipartUploadResponse {
+
+	return &MultipartUploadResponse{
+		Oid:       upload.Oid,
+		Size:      upload.Size,
+		PartSize:  upload.PartSize,
+		PartCount: upload.PartCount(),
+		NextPart:  upload.NextPart(),
+		Uploaded:  upload.Uploaded(),
+	}
+}
@@ -0,0 +1,99 @@
+package adapter
+
+import (
+	"errors"
+
+	"github.com/boltdb/bolt"
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+var (
+	multipartUploadsBucket = []byte("multipart_uploads")
+)
+
+type multipartUploadRepository struct {
+	db      *bolt.DB
+	metrics Metrics
+}
+
+// NewMultipartUploadRepository is ...
+// Uploads in progress are kept by OID, there is at most one per object.
+func NewMultipartUploadRepository(db *bolt.DB, metrics Metrics) usecase.MultipartUploadRepository {
+
+	db.Update(func(tx *bolt.Tx) error {
+
+		_, err := tx.CreateBucketIfNotExists(multipartUploadsBucket)
+		if err != nil {
+			return err
+		}
+		return nil
+
+	})
+
+	return &multipartUploadRepository{db: db, metrics: metricsOrNop(metrics)}
+}
+
+// Get returns the upload in progress for the object.
+func (r *multipartUploadRepository) Get(oid string) (*entity.MultipartUpload, error) {
+
+	var upload entity.MultipartUpload
+
+	err := boltView(r.db, r.metrics, "multipart.get", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(multipartUploadsBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		value := bucket.Get([]byte(oid))
+		if len(value) == 0 {
+			return errors.New("Upload not found")
+		}
+
+		return decodeRecord(value, &upload)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &upload, nil
+}
+
+// Put writes the state of the upload.
+func (r *multipartUploadRepository) Put(upload *entity.MultipartUpload) error {
+
+	err := boltUpdate(r.db, r.metrics, "multipart.put", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(multipartUploadsBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		data, err := encodeRecord(upload)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte(upload.Oid), data)
+	})
+
+	return err
+}
+
+// Delete removes the upload of the object.
+func (r *multipartUploadRepository) Delete(oid string) error {
+
+	err := boltUpdate(r.db, r.metrics, "multipart.delete", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(multipartUploadsBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		return bucket.Delete([]byte(oid))
+	})
+
+	return err
+}
@@ -0,0 +1,50 @@
+package adapter
+
+import (
+	"testing"
+
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+func TestMultipartUploads(t *testing.T) {
+
+	d := newTestData()
+	setupRepository(d)
+	defer teardownRepository(d)
+
+	repo := NewMultipartUploadRepository(d.database, nil)
+
+	if _, err := repo.Get(d.contentOid); err == nil {
+		t.Errorf("expected get of a missing upload to fail")
+	}
+
+	upload := &entity.MultipartUpload{
+		Oid:       d.contentOid,
+		Size:      20,
+		UploadID:  "upload-1",
+		PartSize:  8,
+		Parts:     []entity.UploadPart{{Number: 1, Size: 8, ETag: "etag-1"}},
+		HashState: []byte("state"),
+	}
+
+	if err := repo.Put(upload); err != nil {
+		t.Fatalf("expected put to succeed, got: %s", err)
+	}
+
+	got, err := repo.Get(d.contentOid)
+	if err != nil {
+		t.Fatalf("expected upload, got: %s", err)
+	}
+
+	if got.UploadID != upload.UploadID || got.PartCount() != 3 || got.NextPart() != 2 || got.Uploaded() != 8 {
+		t.Errorf("expected stored upload, got: %+v", got)
+	}
+
+	if err := repo.Delete(d.contentOid); err != nil {
+		t.Fatalf("expected delete to succeed, got: %s", err)
+	}
+
+	if _, err := repo.Get(d.contentOid); err == nil {
+		t.Errorf("expected get of a deleted upload to fail")
+	}
+}
@@ -0,0 +1,152 @@
+package adapter
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+// ObjectController is ...
+type ObjectController interface {
+	List(ctx Context)
+	Info(ctx Context)
+}
+
+type objectController struct {
+	ObjectService usecase.ObjectService
+	AccessService usecase.AccessService
+}
+
+// NewObjectController is ...
+func NewObjectController(s usecase.ObjectService, access usecase.AccessService) ObjectController {
+	return &objectController{
+		ObjectService: s,
+		AccessService: access,
+	}
+}
+
+// List returns a page of the objects of the repo, sorted by the sort
+// parameter, "oid", "size" or "date", in the order of the order parameter,
+// "asc" or "desc".
+func (c *objectController) List(ctx Context) {
+
+	if !authorize(ctx, c.AccessService, entity.RoleRead, entity.ScopeRead, "You must have read access to list objects") {
+		return
+	}
+
+	req, err := parseObjectListRequest(ctx)
+	if err != nil {
+		writeErrorResponse(ctx, 422, "Invalid list request: "+err.Error())
+		return
+	}
+
+	result, err := c.ObjectService.List(req)
+	if err == usecase.ErrInvalidCursor {
+		writeErrorResponse(ctx, 422, err.Error())
+		return
+	}
+	if err != nil {
+		writeErrorResponse(ctx, 500, "Failed to list objects")
+		return
+	}
+
+	res := &ObjectListResponse{
+		Objects:    []*ObjectInfoResponse{},
+		NextCursor: result.NextCursor,
+	}
+	for _, meta := range result.Objects {
+		res.Objects = append(res.Objects, convertObjectInfoResponse(meta, nil))
+	}
+
+	writeJSONResponse(ctx, 200, res)
+}
+
+// Info returns the meta data of an object and the details of its content
+func (c *objectController) Info(ctx Context) {
+
+	if !authorize(ctx, c.AccessService, entity.RoleRead, entity.ScopeRead, "You must have read access to inspect objects") {
+		return
+	}
+
+	oid := ctx.GetParam("oid")
+	if !validateOid(ctx, oid) {
+		return
+	}
+
+	result, err := c.ObjectService.Info(repoPath(ctx), oid)
+	if err == usecase.ErrObjectNotFound {
+		writeErrorResponse(ctx, 404, "Object not found")
+		return
+	}
+	if err != nil {
+		writeErrorResponse(ctx, 500, "Failed to get object")
+		return
+	}
+
+	writeJSONResponse(ctx, 200, convertObjectInfoResponse(result.MetaData, result.Content))
+}
+
+func parseObjectListRequest(ctx Context) (*usecase.ObjectListRequest, error) {
+
+	req := &usecase.ObjectListRequest{
+		Repo:   repoPath(ctx),
+		Sort:   ctx.GetParam("sort"),
+		Cursor: ctx.GetParam("cursor"),
+	}
+
+	switch req.Sort {
+	case "":
+		req.Sort = usecase.ObjectSortOid
+	case usecase.ObjectSortOid, usecase.ObjectSortSize, usecase.ObjectSortDate:
+	default:
+		return nil, fmt.Errorf("unknown sort %q", req.Sort)
+	}
+
+	switch order := ctx.GetParam("order"); order {
+	case "", "asc":
+	case "desc":
+		req.Descending = true
+	default:
+		return nil, fmt.Errorf("unknown order %q", order)
+	}
+
+	if limit := ctx.GetParam("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid limit %q", limit)
+		}
+		req.Limit = n
+	}
+
+	return req, nil
+}
+
+func convertObjectInfoResponse(meta *entity.MetaData, content *entity.Content) *ObjectInfoResponse {
+
+	res := &ObjectInfoResponse{
+		Oid:          meta.Oid,
+		Size:         meta.Size,
+		CreatedAt:    time.Unix(meta.CreatedAt, 0).UTC(),
+		Uploader:     meta.Uploader.Name,
+		StorageClass: meta.StorageClass,
+	}
+
+	if meta.LastAccessedAt != 0 {
+		t := time.Unix(meta.LastAccessedAt, 0).UTC()
+		res.LastAccessedAt = &t
+	}
+
+	if content != nil {
+		res.ETag = content.ETag
+		res.StorageClass = content.StorageClass
+		if content.LastModified != 0 {
+			t := time.Unix(content.LastModified, 0).UTC()
+			res.LastModified = &t
+		}
+	}
+
+	return res
+}
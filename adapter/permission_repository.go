@@ -0,0 +1,172 @@
+package adapter
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+var (
+	permissionsBucket = []byte("permissions")
+)
+
+type permissionRecord struct {
+	Role entity.Role `json:"role"`
+}
+
+type permissionRepository struct {
+	db      *bolt.DB
+	metrics Metrics
+}
+
+// NewPermissionRepository is ...
+// Permissions are kept in a bucket per repository, keyed by user name.
+func NewPermissionRepository(db *bolt.DB, metrics Metrics) usecase.PermissionRepository {
+
+	db.Update(func(tx *bolt.Tx) error {
+
+		_, err := tx.CreateBucketIfNotExists(permissionsBucket)
+		if err != nil {
+			return err
+		}
+		return nil
+
+	})
+
+	return &permissionRepository{db: db, metrics: metricsOrNop(metrics)}
+}
+
+// Grant gives the user the role on the repo, replacing any previous role.
+func (r *permissionRepository) Grant(user string, repo string, role entity.Role) error {
+
+	if !role.Valid() {
+		return fmt.Errorf("Invalid role: %s", role)
+	}
+
+	err := boltUpdate(r.db, r.metrics, "permission.grant", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(permissionsBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		repoBucket, err := bucket.CreateBucketIfNotExists([]byte(repo))
+		if err != nil {
+			return err
+		}
+
+		data, err := encodeRecord(&permissionRecord{Role: role})
+		if err != nil {
+			return err
+		}
+
+		return repoBucket.Put([]byte(user), data)
+	})
+
+	return err
+}
+
+// Revoke removes the role of the user on the repo.
+func (r *permissionRepository) Revoke(user string, repo string) error {
+
+	err := boltUpdate(r.db, r.metrics, "permission.revoke", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(permissionsBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		repoBucket := bucket.Bucket([]byte(repo))
+		if repoBucket == nil {
+			return nil
+		}
+
+		return repoBucket.Delete([]byte(user))
+	})
+
+	return err
+}
+
+// Get returns the permission of the user on the repo.
+func (r *permissionRepository) Get(user string, repo string) (*entity.Permission, error) {
+
+	var perm *entity.Permission
+
+	err := boltView(r.db, r.metrics, "permission.get", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(permissionsBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		repoBucket := bucket.Bucket([]byte(repo))
+		if repoBucket == nil {
+			return errors.New("Permission not found")
+		}
+
+		value := repoBucket.Get([]byte(user))
+		if len(value) == 0 {
+			return errors.New("Permission not found")
+		}
+
+		var record permissionRecord
+		err := decodeRecord(value, &record)
+		if err != nil {
+			return err
+		}
+
+		perm = &entity.Permission{
+			User: user,
+			Repo: repo,
+			Role: record.Role,
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return perm, nil
+}
+
+// Permissions returns all permissions granted on the repo.
+func (r *permissionRepository) Permissions(repo string) ([]*entity.Permission, error) {
+
+	var perms []*entity.Permission
+
+	err := boltView(r.db, r.metrics, "permission.list", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(permissionsBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		repoBucket := bucket.Bucket([]byte(repo))
+		if repoBucket == nil {
+			return nil
+		}
+
+		return repoBucket.ForEach(func(k, v []byte) error {
+
+			var record permissionRecord
+			err := decodeRecord(v, &record)
+			if err != nil {
+				return err
+			}
+
+			perms = append(perms, &entity.Permission{
+				User: string(k),
+				Repo: repo,
+				Role: record.Role,
+			})
+			return nil
+		})
+	})
+
+	return perms, err
+}
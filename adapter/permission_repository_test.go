@@ -0,0 +1,86 @@
+package adapter
+
+import (
+	"testing"
+
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+func TestPermissions(t *testing.T) {
+
+	d := newTestData()
+	setupRepository(d)
+	defer teardownRepository(d)
+
+	repo := NewPermissionRepository(d.database, nil)
+
+	if _, err := repo.Get(d.userName1, d.repoName); err == nil {
+		t.Errorf("expected missing permission to fail")
+	}
+
+	if err := repo.Grant(d.userName1, d.repoName, entity.RoleWrite); err != nil {
+		t.Fatalf("expected grant to succeed, got: %s", err)
+	}
+
+	if err := repo.Grant(d.userName2, d.repoName, entity.RoleRead); err != nil {
+		t.Fatalf("expected grant to succeed, got: %s", err)
+	}
+
+	if err := repo.Grant(d.userName2, d.repoName, entity.Role("owner")); err == nil {
+		t.Errorf("expected grant of unknown role to fail")
+	}
+
+	perm, err := repo.Get(d.userName1, d.repoName)
+	if err != nil {
+		t.Fatalf("expected permission, got: %s", err)
+	}
+
+	if perm.Role != entity.RoleWrite {
+		t.Errorf("expected write role, got: %s", perm.Role)
+	}
+
+	if !perm.Role.Allows(entity.RoleRead) || perm.Role.Allows(entity.RoleAdmin) {
+		t.Errorf("expected write role to allow read but not admin")
+	}
+
+	perms, err := repo.Permissions(d.repoName)
+	if err != nil {
+		t.Fatalf("expected permissions, got: %s", err)
+	}
+
+	if len(perms) != 2 {
+		t.Errorf("expected two permissions, got: %d", len(perms))
+	}
+
+	if err := repo.Revoke(d.userName1, d.repoName); err != nil {
+		t.Fatalf("expected revoke to succeed, got: %s", err)
+	}
+
+	if _, err := repo.Get(d.userName1, d.repoName); err == nil {
+		t.Errorf("expected revoked permission to be missing")
+	}
+}
+
+func TestAuthenticateUser(t *testing.T) {
+
+	d := newTestData()
+	setupRepository(d)
+	defer teardownRepository(d)
+
+	user, err := d.userRepository.Authenticate(d.userName1, d.userPass1)
+	if err != nil {
+		t.Fatalf("expected authentication to succeed, got: %s", err)
+	}
+
+	if user.Name != d.userName1 {
+		t.Errorf("expected user name to match, got: %s", user.Name)
+	}
+
+	if _, err := d.userRepository.Authenticate(d.userName1, d.userPass2); err == nil {
+		t.Errorf("expected wrong password to fail")
+	}
+
+	if _, err := d.userRepository.Authenticate(d.userName2, d.userPass2); err == nil {
+		t.Errorf("expected unknown user to fail")
+	}
+}
@@ -0,0 +1,44 @@
+package adapter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// recordVersion is the encoding version written in front of every record
+// stored in the database.
+const recordVersion byte = 1
+
+var (
+	errEmptyRecord = errors.New("Record is empty")
+)
+
+type userRecord struct {
+	PasswordHash string `json:"password_hash"`
+}
+
+// encodeRecord encodes v as a versioned record.
+func encodeRecord(v interface{}) ([]byte, error) {
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{recordVersion}, data...), nil
+}
+
+// decodeRecord decodes a versioned record into v.
+func decodeRecord(data []byte, v interface{}) error {
+
+	if len(data) == 0 {
+		return errEmptyRecord
+	}
+
+	if data[0] != recordVersion {
+		return fmt.Errorf("Unsupported record version: %d", data[0])
+	}
+
+	return json.Unmarshal(data[1:], v)
+}
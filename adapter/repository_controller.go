@@ -0,0 +1,203 @@
+package adapter
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+// RepositoryController is ...
+type RepositoryController interface {
+	Create(ctx Context)
+	Get(ctx Context)
+	List(ctx Context)
+	Rename(ctx Context)
+	Archive(ctx Context)
+	Unarchive(ctx Context)
+	Delete(ctx Context)
+}
+
+const repositoryAdminMessage = "You must be an admin to manage repositories"
+
+type repositoryController struct {
+	RepositoryService usecase.RepositoryService
+	AccessService     usecase.AccessService
+}
+
+// NewRepositoryController is ...
+func NewRepositoryController(s usecase.RepositoryService, access usecase.AccessService) RepositoryController {
+	return &repositoryController{
+		RepositoryService: s,
+		AccessService:     access,
+	}
+}
+
+// Create registers the repository named in the request
+func (c *repositoryController) Create(ctx Context) {
+
+	if !authorizeAdmin(ctx, c.AccessService, repositoryAdminMessage) {
+		return
+	}
+
+	req, err := parseRepositoryRequest(ctx)
+	if err != nil {
+		writeRequestError(ctx, err, "Invalid repository request")
+		return
+	}
+
+	repo, err := c.RepositoryService.Create(req.Name, ctx.GetUser())
+	if err != nil {
+		writeRepositoryError(ctx, err, "Failed to create repository")
+		return
+	}
+
+	writeJSONResponse(ctx, 201, convertRepositoryResponse(repo))
+}
+
+// Get returns the repository
+func (c *repositoryController) Get(ctx Context) {
+
+	if !authorizeAdmin(ctx, c.AccessService, repositoryAdminMessage) {
+		return
+	}
+
+	repo, err := c.RepositoryService.Get(repositoryName(ctx))
+	if err != nil {
+		writeRepositoryError(ctx, err, "Failed to get repository")
+		return
+	}
+
+	writeJSONResponse(ctx, 200, convertRepositoryResponse(repo))
+}
+
+// List returns all registered repositories
+func (c *repositoryController) List(ctx Context) {
+
+	if !authorizeAdmin(ctx, c.AccessService, repositoryAdminMessage) {
+		return
+	}
+
+	repos, err := c.RepositoryService.List()
+	if err != nil {
+		writeErrorResponse(ctx, 500, "Failed to list repositories")
+		return
+	}
+
+	res := &RepositoryListResponse{Repositories: []*RepositoryResponse{}}
+	for _, r := range repos {
+		res.Repositories = append(res.Repositories, convertRepositoryResponse(r))
+	}
+
+	writeJSONResponse(ctx, 200, res)
+}
+
+// Rename moves the repository to the name in the request
+func (c *repositoryController) Rename(ctx Context) {
+
+	if !authorizeAdmin(ctx, c.AccessService, repositoryAdminMessage) {
+		return
+	}
+
+	req, err := parseRepositoryRequest(ctx)
+	if err != nil {
+		writeRequestError(ctx, err, "Invalid repository request")
+		return
+	}
+
+	repo, err := c.RepositoryService.Rename(repositoryName(ctx), req.Name, ctx.GetUser())
+	if err != nil {
+		writeRepositoryError(ctx, err, "Failed to rename repository")
+		return
+	}
+
+	writeJSONResponse(ctx, 200, convertRepositoryResponse(repo))
+}
+
+// Archive makes the repository read-only
+func (c *repositoryController) Archive(ctx Context) {
+
+	c.setArchived(ctx, true)
+}
+
+// Unarchive makes the repository writable again
+func (c *repositoryController) Unarchive(ctx Context) {
+
+	c.setArchived(ctx, false)
+}
+
+func (c *repositoryController) setArchived(ctx Context, archived bool) {
+
+	if !authorizeAdmin(ctx, c.AccessService, repositoryAdminMessage) {
+		return
+	}
+
+	repo, err := c.RepositoryService.Archive(repositoryName(ctx), archived, ctx.GetUser())
+	if err != nil {
+		writeRepositoryError(ctx, err, "Failed to archive repository")
+		return
+	}
+
+	writeJSONResponse(ctx, 200, convertRepositoryResponse(repo))
+}
+
+// Delete removes the repository with its locks and objects
+func (c *repositoryController) Delete(ctx Context) {
+
+	if !authorizeAdmin(ctx, c.AccessService, repositoryAdminMessage) {
+		return
+	}
+
+	err := c.RepositoryService.Delete(repositoryName(ctx), ctx.GetUser())
+	if err != nil {
+		writeRepositoryError(ctx, err, "Failed to delete repository")
+		return
+	}
+
+	ctx.SetStatus(204)
+}
+
+// repositoryName returns the <owner>/<repo> path of the repository of the request
+func repositoryName(ctx Context) string {
+	return ctx.GetParam("owner") + "/" + ctx.GetParam("name")
+}
+
+func writeRepositoryError(ctx Context, err error, message string) {
+
+	switch err {
+	case usecase.ErrRepositoryNotFound:
+		writeErrorResponse(ctx, 404, err.Error())
+	case usecase.ErrRepositoryExists:
+		writeErrorResponse(ctx, 409, err.Error())
+	case usecase.ErrInvalidRepositoryName:
+		writeErrorResponse(ctx, 422, err.Error())
+	default:
+		writeErrorResponse(ctx, 500, message)
+	}
+}
+
+func parseRepositoryRequest(ctx Context) (*RepositoryRequest, error) {
+
+	data, err := ctx.GetRawData()
+	if err != nil {
+		return nil, err
+	}
+
+	var req RepositoryRequest
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
+
+func convertRepositoryResponse(repo *entity.Repository) *RepositoryResponse {
+
+	return &RepositoryResponse{
+		Name:      repo.Name,
+		Archived:  repo.Archived,
+		CreatedAt: time.Unix(repo.CreatedAt, 0).UTC(),
+	}
+}
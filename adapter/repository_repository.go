@@ -0,0 +1,426 @@
+package adapter
+
+import (
+	"errors"
+
+	"github.com/boltdb/bolt"
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+var (
+	repositoriesBucket = []byte("repositories")
+)
+
+type repositoryRecord struct {
+	Archived  bool  `json:"archived,omitempty"`
+	CreatedAt int64 `json:"created_at"`
+}
+
+type repositoryRepository struct {
+	db      *bolt.DB
+	metrics Metrics
+}
+
+// NewRepositoryRepository is ...
+// Repositories are keyed by name, which is also the key of their locks,
+// usage, object references and permissions.
+func NewRepositoryRepository(db *bolt.DB, metrics Metrics) usecase.RepositoryRepository {
+
+	db.Update(func(tx *bolt.Tx) error {
+
+		for _, name := range [][]byte{repositoriesBucket, locksBucket, repoUsageBucket, repoRefsBucket, metaBucket, permissionsBucket} {
+			_, err := tx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	})
+
+	return &repositoryRepository{db: db, metrics: metricsOrNop(metrics)}
+}
+
+// Create registers the repo unless its name is taken
+func (r *repositoryRepository) Create(repo *entity.Repository) error {
+
+	err := boltUpdate(r.db, r.metrics, "repository.create", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(repositoriesBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		if bucket.Get([]byte(repo.Name)) != nil {
+			return usecase.ErrRepositoryExists
+		}
+
+		return putRepository(bucket, repo)
+	})
+
+	return err
+}
+
+// Get returns the repo
+func (r *repositoryRepository) Get(name string) (*entity.Repository, error) {
+
+	var repo *entity.Repository
+
+	err := boltView(r.db, r.metrics, "repository.get", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(repositoriesBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		var err error
+		repo, err = getRepository(bucket, name)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// Repositories returns all repos in order of name
+func (r *repositoryRepository) Repositories() ([]*entity.Repository, error) {
+
+	var repos []*entity.Repository
+
+	err := boltView(r.db, r.metrics, "repository.list", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(repositoriesBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+
+			repo, err := decodeRepository(k, v)
+			if err != nil {
+				return err
+			}
+
+			repos = append(repos, repo)
+			return nil
+		})
+	})
+
+	return repos, err
+}
+
+// SetArchived archives or unarchives the repo
+func (r *repositoryRepository) SetArchived(name string, archived bool) error {
+
+	err := boltUpdate(r.db, r.metrics, "repository.set_archived", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(repositoriesBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		repo, err := getRepository(bucket, name)
+		if err != nil {
+			return err
+		}
+
+		repo.Archived = archived
+		return putRepository(bucket, repo)
+	})
+
+	return err
+}
+
+// Rename moves the repo to newName in one transaction, together with its
+// locks, usage, object references and permissions. The repos of the
+// referenced objects are renamed too. A name that has locks, objects or
+// permissions is in use even if it was never registered.
+func (r *repositoryRepository) Rename(name string, newName string) error {
+
+	err := boltUpdate(r.db, r.metrics, "repository.rename", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(repositoriesBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		repo, err := getRepository(bucket, name)
+		if err != nil {
+			return err
+		}
+
+		locks := tx.Bucket(locksBucket)
+		usage := tx.Bucket(repoUsageBucket)
+		refs := tx.Bucket(repoRefsBucket)
+		metas := tx.Bucket(metaBucket)
+		perms := tx.Bucket(permissionsBucket)
+		if locks == nil || usage == nil || refs == nil || metas == nil || perms == nil {
+			return errors.New("Bucket not found")
+		}
+
+		key := []byte(newName)
+		if bucket.Get(key) != nil || locks.Get(key) != nil || refs.Bucket(key) != nil || perms.Bucket(key) != nil {
+			return usecase.ErrRepositoryExists
+		}
+
+		err = movePermissions(perms, []byte(name), key)
+		if err != nil {
+			return err
+		}
+
+		err = moveKey(locks, []byte(name), key)
+		if err != nil {
+			return err
+		}
+
+		err = moveKey(usage, []byte(name), key)
+		if err != nil {
+			return err
+		}
+
+		err = moveReferences(refs, metas, name, newName)
+		if err != nil {
+			return err
+		}
+
+		err = bucket.Delete([]byte(name))
+		if err != nil {
+			return err
+		}
+
+		repo.Name = newName
+		return putRepository(bucket, repo)
+	})
+
+	return err
+}
+
+// Delete removes the repo, its locks, usage, permissions and object references
+// in one transaction, so that no reference can be added to the repo while it
+// is being deleted. The objects it referenced last are returned to be
+// collected, as their content cannot be deleted in the transaction.
+func (r *repositoryRepository) Delete(name string) ([]string, error) {
+
+	var unreferenced []string
+
+	err := boltUpdate(r.db, r.metrics, "repository.delete", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(repositoriesBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		_, err := getRepository(bucket, name)
+		if err != nil {
+			return err
+		}
+
+		locks := tx.Bucket(locksBucket)
+		usage := tx.Bucket(repoUsageBucket)
+		refs := tx.Bucket(repoRefsBucket)
+		metas := tx.Bucket(metaBucket)
+		perms := tx.Bucket(permissionsBucket)
+		if locks == nil || usage == nil || refs == nil || metas == nil || perms == nil {
+			return errors.New("Bucket not found")
+		}
+
+		err = locks.Delete([]byte(name))
+		if err != nil {
+			return err
+		}
+
+		if perms.Bucket([]byte(name)) != nil {
+			err = perms.DeleteBucket([]byte(name))
+			if err != nil {
+				return err
+			}
+		}
+
+		if repoBucket := refs.Bucket([]byte(name)); repoBucket != nil {
+			var oids []string
+			repoBucket.ForEach(func(k, v []byte) error {
+				oids = append(oids, string(k))
+				return nil
+			})
+
+			for _, oid := range oids {
+				value := metas.Get([]byte(oid))
+				if len(value) == 0 {
+					continue
+				}
+
+				var meta entity.MetaData
+				err = decodeRecord(value, &meta)
+				if err != nil {
+					return err
+				}
+
+				err = removeReference(tx, &meta, name)
+				if err != nil {
+					return err
+				}
+
+				err = putMetaData(metas, &meta)
+				if err != nil {
+					return err
+				}
+
+				if len(meta.Repos) == 0 {
+					unreferenced = append(unreferenced, oid)
+				}
+			}
+
+			err = refs.DeleteBucket([]byte(name))
+			if err != nil {
+				return err
+			}
+		}
+
+		err = usage.Delete([]byte(name))
+		if err != nil {
+			return err
+		}
+
+		return bucket.Delete([]byte(name))
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return unreferenced, nil
+}
+
+// moveReferences moves the references of repo name to newName and renames
+// the repo in the meta data of the referenced objects
+func moveReferences(refs *bolt.Bucket, metas *bolt.Bucket, name string, newName string) error {
+
+	repoBucket := refs.Bucket([]byte(name))
+	if repoBucket == nil {
+		return nil
+	}
+
+	newBucket, err := refs.CreateBucket([]byte(newName))
+	if err != nil {
+		return err
+	}
+
+	err = repoBucket.ForEach(func(k, v []byte) error {
+
+		err := newBucket.Put(k, v)
+		if err != nil {
+			return err
+		}
+
+		value := metas.Get(k)
+		if len(value) == 0 {
+			return nil
+		}
+
+		var meta entity.MetaData
+		err = decodeRecord(value, &meta)
+		if err != nil {
+			return err
+		}
+
+		for i, repo := range meta.Repos {
+			if repo == name {
+				meta.Repos[i] = newName
+			}
+		}
+
+		return putMetaData(metas, &meta)
+	})
+	if err != nil {
+		return err
+	}
+
+	return refs.DeleteBucket([]byte(name))
+}
+
+// movePermissions moves the permissions of the repo key to newKey
+func movePermissions(perms *bolt.Bucket, key []byte, newKey []byte) error {
+
+	repoBucket := perms.Bucket(key)
+	if repoBucket == nil {
+		return nil
+	}
+
+	newBucket, err := perms.CreateBucket(newKey)
+	if err != nil {
+		return err
+	}
+
+	err = repoBucket.ForEach(func(k, v []byte) error {
+		return newBucket.Put(k, v)
+	})
+	if err != nil {
+		return err
+	}
+
+	return perms.DeleteBucket(key)
+}
+
+// moveKey moves the value of key to newKey, if there is one
+func moveKey(bucket *bolt.Bucket, key []byte, newKey []byte) error {
+
+	value := bucket.Get(key)
+	if value == nil {
+		return nil
+	}
+
+	// values are only valid for the life of the transaction and must not
+	// be written back while the page they point to is being modified
+	data := append([]byte(nil), value...)
+
+	err := bucket.Put(newKey, data)
+	if err != nil {
+		return err
+	}
+
+	return bucket.Delete(key)
+}
+
+func getRepository(bucket *bolt.Bucket, name string) (*entity.Repository, error) {
+
+	value := bucket.Get([]byte(name))
+	if len(value) == 0 {
+		return nil, usecase.ErrRepositoryNotFound
+	}
+
+	return decodeRepository([]byte(name), value)
+}
+
+func decodeRepository(name []byte, value []byte) (*entity.Repository, error) {
+
+	var record repositoryRecord
+	err := decodeRecord(value, &record)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &entity.Repository{
+		Name:      string(name),
+		Archived:  record.Archived,
+		CreatedAt: record.CreatedAt,
+	}
+
+	return repo, nil
+}
+
+func putRepository(bucket *bolt.Bucket, repo *entity.Repository) error {
+
+	data, err := encodeRecord(&repositoryRecord{
+		Archived:  repo.Archived,
+		CreatedAt: repo.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return bucket.Put([]byte(repo.Name), data)
+}
@@ -0,0 +1,175 @@
+package adapter
+
+import (
+	"testing"
+
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+func TestRepositories(t *testing.T) {
+
+	d := newTestData()
+	setupRepository(d)
+	defer teardownRepository(d)
+
+	repos := NewRepositoryRepository(d.database, nil)
+
+	if _, err := repos.Get(d.repoName); err != usecase.ErrRepositoryNotFound {
+		t.Errorf("expected ErrRepositoryNotFound, got: %v", err)
+	}
+
+	if err := repos.Create(&entity.Repository{Name: d.repoName, CreatedAt: 1}); err != nil {
+		t.Fatalf("expected create to succeed, got: %s", err)
+	}
+
+	if err := repos.Create(&entity.Repository{Name: d.repoName}); err != usecase.ErrRepositoryExists {
+		t.Errorf("expected ErrRepositoryExists, got: %v", err)
+	}
+
+	if err := repos.SetArchived(d.repoName, true); err != nil {
+		t.Fatalf("expected archive to succeed, got: %s", err)
+	}
+
+	repo, err := repos.Get(d.repoName)
+	if err != nil || !repo.Archived || repo.CreatedAt != 1 {
+		t.Errorf("expected archived repo, got: %+v, %v", repo, err)
+	}
+
+	all, err := repos.Repositories()
+	if err != nil || len(all) != 1 || all[0].Name != d.repoName {
+		t.Errorf("expected one repo, got: %+v, %v", all, err)
+	}
+}
+
+func TestRenameRepository(t *testing.T) {
+
+	d := newTestData()
+	setupRepository(d)
+	defer teardownRepository(d)
+
+	repos := NewRepositoryRepository(d.database, nil)
+	perms := NewPermissionRepository(d.database, nil)
+	newName := "renamed"
+
+	if err := repos.Create(&entity.Repository{Name: d.repoName}); err != nil {
+		t.Fatalf("expected create to succeed, got: %s", err)
+	}
+	if err := perms.Grant(d.userName1, d.repoName, entity.RoleWrite); err != nil {
+		t.Fatalf("expected grant to succeed, got: %s", err)
+	}
+	if err := d.lockRepository.Add(d.repoName, NewTestLock(d.lockID, d.lockPath, d.userName1)); err != nil {
+		t.Fatalf("expected lock to succeed, got: %s", err)
+	}
+	if _, err := d.metaDataRepository.AddReference(d.repoName, d.contentOid); err != nil {
+		t.Fatalf("expected reference to succeed, got: %s", err)
+	}
+
+	if err := d.lockRepository.Add("taken", NewTestLock(d.nonExistLockID, d.lockPath, d.userName1)); err != nil {
+		t.Fatalf("expected lock to succeed, got: %s", err)
+	}
+	if err := repos.Rename(d.repoName, "taken"); err != usecase.ErrRepositoryExists {
+		t.Errorf("expected rename to a repo with locks to fail, got: %v", err)
+	}
+
+	if err := perms.Grant(d.userName2, "granted", entity.RoleRead); err != nil {
+		t.Fatalf("expected grant to succeed, got: %s", err)
+	}
+	if err := repos.Rename(d.repoName, "granted"); err != usecase.ErrRepositoryExists {
+		t.Errorf("expected rename to a repo with permissions to fail, got: %v", err)
+	}
+
+	if err := repos.Rename(d.repoName, newName); err != nil {
+		t.Fatalf("expected rename to succeed, got: %s", err)
+	}
+
+	if _, err := repos.Get(d.repoName); err != usecase.ErrRepositoryNotFound {
+		t.Errorf("expected old name to be gone, got: %v", err)
+	}
+	if _, err := repos.Get(newName); err != nil {
+		t.Errorf("expected new name to be registered, got: %s", err)
+	}
+
+	locks, err := d.lockRepository.Fetch(newName)
+	if err != nil || len(locks) != 1 || locks[0].ID != d.lockID {
+		t.Errorf("expected lock to move, got: %+v, %v", locks, err)
+	}
+	if locks, _ := d.lockRepository.Fetch(d.repoName); len(locks) != 0 {
+		t.Errorf("expected no locks left under the old name, got: %+v", locks)
+	}
+
+	oids, err := d.metaDataRepository.References(newName)
+	if err != nil || len(oids) != 1 || oids[0] != d.contentOid {
+		t.Errorf("expected reference to move, got: %v, %v", oids, err)
+	}
+
+	meta, err := d.metaDataRepository.Get(d.contentOid)
+	if err != nil || len(meta.Repos) != 1 || meta.Repos[0] != newName {
+		t.Errorf("expected object to be in the renamed repo, got: %+v, %v", meta, err)
+	}
+
+	usage, err := d.metaDataRepository.RepoUsage(newName)
+	if err != nil || usage.Objects != 1 || usage.Size != d.contentSize {
+		t.Errorf("expected usage to move, got: %+v, %v", usage, err)
+	}
+
+	if perm, err := perms.Get(d.userName1, newName); err != nil || perm.Role != entity.RoleWrite {
+		t.Errorf("expected permission to move, got: %+v, %v", perm, err)
+	}
+	if _, err := perms.Get(d.userName1, d.repoName); err == nil {
+		t.Errorf("expected no permission left under the old name")
+	}
+}
+
+func TestDeleteRepository(t *testing.T) {
+
+	d := newTestData()
+	setupRepository(d)
+	defer teardownRepository(d)
+
+	repos := NewRepositoryRepository(d.database, nil)
+
+	if _, err := repos.Delete(d.repoName); err != usecase.ErrRepositoryNotFound {
+		t.Errorf("expected ErrRepositoryNotFound, got: %v", err)
+	}
+
+	if err := repos.Create(&entity.Repository{Name: d.repoName}); err != nil {
+		t.Fatalf("expected create to succeed, got: %s", err)
+	}
+	if err := d.lockRepository.Add(d.repoName, NewTestLock(d.lockID, d.lockPath, d.userName1)); err != nil {
+		t.Fatalf("expected lock to succeed, got: %s", err)
+	}
+	if _, err := d.metaDataRepository.AddReference(d.repoName, d.contentOid); err != nil {
+		t.Fatalf("expected reference to succeed, got: %s", err)
+	}
+	perms := NewPermissionRepository(d.database, nil)
+	if err := perms.Grant(d.userName1, d.repoName, entity.RoleAdmin); err != nil {
+		t.Fatalf("expected grant to succeed, got: %s", err)
+	}
+
+	oids, err := repos.Delete(d.repoName)
+	if err != nil {
+		t.Fatalf("expected delete to succeed, got: %s", err)
+	}
+	if len(oids) != 1 || oids[0] != d.contentOid {
+		t.Errorf("expected the unreferenced object to be returned, got: %v", oids)
+	}
+
+	if _, err := repos.Get(d.repoName); err != usecase.ErrRepositoryNotFound {
+		t.Errorf("expected repo to be deleted, got: %v", err)
+	}
+	if locks, _ := d.lockRepository.Fetch(d.repoName); len(locks) != 0 {
+		t.Errorf("expected locks to be deleted, got: %+v", locks)
+	}
+	if oids, _ := d.metaDataRepository.References(d.repoName); len(oids) != 0 {
+		t.Errorf("expected references to be deleted, got: %v", oids)
+	}
+	if perms, _ := perms.Permissions(d.repoName); len(perms) != 0 {
+		t.Errorf("expected permissions to be deleted, got: %+v", perms)
+	}
+
+	meta, err := d.metaDataRepository.Get(d.contentOid)
+	if err != nil || len(meta.Repos) != 0 {
+		t.Errorf("expected object to be kept without repos, got: %+v, %v", meta, err)
+	}
+}
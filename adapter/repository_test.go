@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/boltdb/bolt"
+	"github.com/ikmski/git-lfs3/entity"
 )
 
 func TestMain(m *testing.M) {
@@ -21,6 +22,11 @@ func setupRepository(d *TestData) {
 		os.Exit(1)
 	}
 
+	if err := Migrate(db); err != nil {
+		fmt.Printf("error migrating test meta store: %s\n", err)
+		os.Exit(1)
+	}
+
 	db.Update(func(tx *bolt.Tx) error {
 
 		if _, err := tx.CreateBucketIfNotExists(usersBucket); err != nil {
@@ -38,9 +44,9 @@ func setupRepository(d *TestData) {
 		return nil
 	})
 
-	d.metaDataRepository = NewMetaDataRepository(db)
-	d.lockRepository = NewLockRepository(db)
-	d.userRepository = NewUserRepository(db)
+	d.metaDataRepository = NewMetaDataRepository(db, nil)
+	d.lockRepository = NewLockRepository(db, nil)
+	d.userRepository = NewUserRepository(db, nil)
 	d.database = db
 
 	if err := d.userRepository.AddUser(d.userName1, d.userPass1); err != nil {
@@ -49,7 +55,7 @@ func setupRepository(d *TestData) {
 		os.Exit(1)
 	}
 
-	if _, err := d.metaDataRepository.Put(d.contentOid, d.contentSize); err != nil {
+	if _, err := d.metaDataRepository.Put(&entity.MetaData{Oid: d.contentOid, Size: d.contentSize}); err != nil {
 		teardownRepository(d)
 		fmt.Printf("error seeding test meta store: %s\n", err)
 		os.Exit(1)
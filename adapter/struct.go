@@ -13,6 +13,7 @@ const (
 type BatchRequest struct {
 	Operation string           `json:"operation"`
 	Transfers []string         `json:"transfers,omitempty"`
+	HashAlgo  string           `json:"hash_algo,omitempty"`
 	Ref       Ref              `json:"ref,omitempty"`
 	Objects   []*ObjectRequest `json:"objects"`
 }
@@ -21,6 +22,7 @@ type BatchRequest struct {
 type ObjectRequest struct {
 	Oid      string `json:"oid"`
 	Size     int64  `json:"size"`
+	Path     string `json:"path,omitempty"`
 	User     string `json:"user"`
 	Password string `json:"password"`
 	Repo     string `json:"repo"`
@@ -34,6 +36,7 @@ type Ref struct {
 // BatchResponse is ...
 type BatchResponse struct {
 	Transfer string            `json:"transfer,omitempty"`
+	HashAlgo string            `json:"hash_algo,omitempty"`
 	Objects  []*ResponseObject `json:"objects"`
 }
 
@@ -98,11 +101,52 @@ func newResponseObject() *ResponseObject {
 	return r
 }
 
+// ImportRequest is ...
+// Source is the <owner>/<repo> to import from. Either Oids or All is set.
+type ImportRequest struct {
+	Source string   `json:"source"`
+	Oids   []string `json:"oids,omitempty"`
+	All    bool     `json:"all,omitempty"`
+}
+
+// ImportResponse is ...
+type ImportResponse struct {
+	Objects []*ImportObject `json:"objects"`
+}
+
+// ImportObject is ...
+type ImportObject struct {
+	Oid      string       `json:"oid"`
+	Size     int64        `json:"size"`
+	Imported bool         `json:"imported"`
+	Error    *ObjectError `json:"error,omitempty"`
+}
+
+// ObjectListResponse is ...
+type ObjectListResponse struct {
+	Objects    []*ObjectInfoResponse `json:"objects"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+// ObjectInfoResponse is ...
+// ETag and LastModified are only set by the info endpoint, and not if the
+// content of the object is not stored.
+type ObjectInfoResponse struct {
+	Oid            string     `json:"oid"`
+	Size           int64      `json:"size"`
+	CreatedAt      time.Time  `json:"created_at"`
+	Uploader       string     `json:"uploader,omitempty"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+	ETag           string     `json:"etag,omitempty"`
+	StorageClass   string     `json:"storage_class,omitempty"`
+	LastModified   *time.Time `json:"last_modified,omitempty"`
+}
+
 // Link is ...
 type Link struct {
 	Href      string            `json:"href"`
 	Header    map[string]string `json:"header,omitempty"`
-	ExpiresAt time.Time         `json:"expires_at,omitempty"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"`
 }
 
 // ObjectError is ...
@@ -110,3 +154,136 @@ type ObjectError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 }
+
+// AccessTokenRequest is ...
+type AccessTokenRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	Repo      string   `json:"repo,omitempty"`
+	ExpiresIn int64    `json:"expires_in,omitempty"` // seconds, the token does not expire if zero
+}
+
+// AccessTokenResponse is ...
+// Token is only set when the token is created.
+type AccessTokenResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	Repo       string     `json:"repo,omitempty"`
+	Token      string     `json:"token,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// AccessTokenListResponse is ...
+type AccessTokenListResponse struct {
+	Tokens []*AccessTokenResponse `json:"tokens"`
+}
+
+// RepositoryRequest is ...
+// Name is the name of a new repository, or the new name when renaming one.
+type RepositoryRequest struct {
+	Name string `json:"name"`
+}
+
+// RepositoryResponse is ...
+type RepositoryResponse struct {
+	Name      string    `json:"name"`
+	Archived  bool      `json:"archived"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RepositoryListResponse is ...
+type RepositoryListResponse struct {
+	Repositories []*RepositoryResponse `json:"repositories"`
+}
+
+// TombstoneResponse is ...
+type TombstoneResponse struct {
+	Oid       string    `json:"oid"`
+	Size      int64     `json:"size"`
+	DeletedBy string    `json:"deleted_by"`
+	DeletedAt time.Time `json:"deleted_at"`
+	Reason    string    `json:"reason"`
+}
+
+// TombstoneListResponse is ...
+type TombstoneListResponse struct {
+	Tombstones []*TombstoneResponse `json:"tombstones"`
+}
+
+// ObjectDeletionResponse is ...
+type ObjectDeletionResponse struct {
+	Action string    `json:"action"`
+	Oid    string    `json:"oid"`
+	Size   int64     `json:"size,omitempty"`
+	Repos  []string  `json:"repos,omitempty"`
+	User   string    `json:"user"`
+	Reason string    `json:"reason"`
+	Time   time.Time `json:"time"`
+}
+
+// ObjectDeletionListResponse is ...
+type ObjectDeletionListResponse struct {
+	Deletions []*ObjectDeletionResponse `json:"deletions"`
+}
+
+// AuditEventResponse is ...
+type AuditEventResponse struct {
+	Time    time.Time         `json:"time"`
+	Actor   string            `json:"actor,omitempty"`
+	Action  string            `json:"action"`
+	Repo    string            `json:"repo,omitempty"`
+	Target  string            `json:"target,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// AuditEventListResponse is ...
+type AuditEventListResponse struct {
+	Events     []*AuditEventResponse `json:"events"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+// WebhookPayload is the body of a webhook request. The ID is the same
+// on every attempt of a delivery.
+type WebhookPayload struct {
+	ID     string         `json:"id"`
+	Event  string         `json:"event"`
+	Repo   string         `json:"repo"`
+	Actor  string         `json:"actor"`
+	Time   time.Time      `json:"time"`
+	Lock   *Lock          `json:"lock,omitempty"`
+	Object *WebhookObject `json:"object,omitempty"`
+}
+
+// WebhookObject is ...
+type WebhookObject struct {
+	Oid  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// MultipartUploadResponse is ...
+// Parts are uploaded in order with PUT to {href}/{part number}, and the
+// upload is completed with POST to {href}/complete.
+type MultipartUploadResponse struct {
+	Oid       string `json:"oid"`
+	Size      int64  `json:"size"`
+	PartSize  int64  `json:"part_size"`
+	PartCount int    `json:"part_count"`
+	NextPart  int    `json:"next_part"`
+	Uploaded  int64  `json:"uploaded"`
+}
+
+// HealthResponse is ...
+type HealthResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// ErrorResponse is ...
+type ErrorResponse struct {
+	Message          string `json:"message"`
+	DocumentationURL string `json:"documentation_url,omitempty"`
+	RequestID        string `json:"request_id,omitempty"`
+}
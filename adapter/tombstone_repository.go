@@ -0,0 +1,269 @@
+package adapter
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/boltdb/bolt"
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+var (
+	tombstonesBucket = []byte("tombstones")
+	// objectDeletionsBucket holds the deletion records keyed by sequence
+	objectDeletionsBucket = []byte("object_deletions")
+)
+
+type tombstoneRecord struct {
+	Size      int64  `json:"size"`
+	DeletedBy string `json:"deleted_by"`
+	DeletedAt int64  `json:"deleted_at"`
+	Reason    string `json:"reason"`
+}
+
+type tombstoneRepository struct {
+	db      *bolt.DB
+	metrics Metrics
+}
+
+// NewTombstoneRepository is ...
+// Tombstones are keyed by oid. Deletion records are only ever appended.
+func NewTombstoneRepository(db *bolt.DB, metrics Metrics) usecase.TombstoneRepository {
+
+	db.Update(func(tx *bolt.Tx) error {
+
+		for _, name := range [][]byte{tombstonesBucket, objectDeletionsBucket} {
+			_, err := tx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	})
+
+	return &tombstoneRepository{db: db, metrics: metricsOrNop(metrics)}
+}
+
+// Add tombstones the deleted object and records its deletion in one transaction
+func (r *tombstoneRepository) Add(deletion *entity.ObjectDeletion) error {
+
+	err := boltUpdate(r.db, r.metrics, "tombstone.add", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(tombstonesBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		data, err := encodeRecord(&tombstoneRecord{
+			Size:      deletion.Size,
+			DeletedBy: deletion.User,
+			DeletedAt: deletion.Time,
+			Reason:    deletion.Reason,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = bucket.Put([]byte(deletion.Oid), data)
+		if err != nil {
+			return err
+		}
+
+		return appendDeletion(tx, deletion)
+	})
+
+	return err
+}
+
+// Get returns the tombstone of the object
+func (r *tombstoneRepository) Get(oid string) (*entity.Tombstone, error) {
+
+	var tombstone *entity.Tombstone
+
+	err := boltView(r.db, r.metrics, "tombstone.get", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(tombstonesBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		value := bucket.Get([]byte(oid))
+		if len(value) == 0 {
+			return usecase.ErrTombstoneNotFound
+		}
+
+		var err error
+		tombstone, err = decodeTombstone([]byte(oid), value)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return tombstone, nil
+}
+
+// GetMany returns the tombstones of the objects that have one in one transaction
+func (r *tombstoneRepository) GetMany(oids []string) (map[string]*entity.Tombstone, error) {
+
+	tombstones := make(map[string]*entity.Tombstone)
+
+	err := boltView(r.db, r.metrics, "tombstone.get_many", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(tombstonesBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		for _, oid := range oids {
+
+			value := bucket.Get([]byte(oid))
+			if len(value) == 0 {
+				continue
+			}
+
+			tombstone, err := decodeTombstone([]byte(oid), value)
+			if err != nil {
+				return err
+			}
+
+			tombstones[oid] = tombstone
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return tombstones, nil
+}
+
+// Clear removes the tombstone and records the clearing in one transaction
+func (r *tombstoneRepository) Clear(deletion *entity.ObjectDeletion) error {
+
+	err := boltUpdate(r.db, r.metrics, "tombstone.clear", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(tombstonesBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		value := bucket.Get([]byte(deletion.Oid))
+		if len(value) == 0 {
+			return usecase.ErrTombstoneNotFound
+		}
+
+		err := bucket.Delete([]byte(deletion.Oid))
+		if err != nil {
+			return err
+		}
+
+		return appendDeletion(tx, deletion)
+	})
+
+	return err
+}
+
+// Tombstones returns all tombstones in order of oid
+func (r *tombstoneRepository) Tombstones() ([]*entity.Tombstone, error) {
+
+	var tombstones []*entity.Tombstone
+
+	err := boltView(r.db, r.metrics, "tombstone.list", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(tombstonesBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+
+			tombstone, err := decodeTombstone(k, v)
+			if err != nil {
+				return err
+			}
+
+			tombstones = append(tombstones, tombstone)
+			return nil
+		})
+	})
+
+	return tombstones, err
+}
+
+// Deletions returns the deletion records in the order they were added
+func (r *tombstoneRepository) Deletions() ([]*entity.ObjectDeletion, error) {
+
+	var deletions []*entity.ObjectDeletion
+
+	err := boltView(r.db, r.metrics, "tombstone.deletions", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(objectDeletionsBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+
+			var deletion entity.ObjectDeletion
+			err := decodeRecord(v, &deletion)
+			if err != nil {
+				return err
+			}
+
+			deletions = append(deletions, &deletion)
+			return nil
+		})
+	})
+
+	return deletions, err
+}
+
+// appendDeletion adds the record under the next sequence number, which
+// keeps the records in the order they were added
+func appendDeletion(tx *bolt.Tx, deletion *entity.ObjectDeletion) error {
+
+	bucket := tx.Bucket(objectDeletionsBucket)
+	if bucket == nil {
+		return errors.New("Bucket not found")
+	}
+
+	seq, err := bucket.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	data, err := encodeRecord(deletion)
+	if err != nil {
+		return err
+	}
+
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+
+	return bucket.Put(key, data)
+}
+
+func decodeTombstone(oid []byte, value []byte) (*entity.Tombstone, error) {
+
+	var record tombstoneRecord
+	err := decodeRecord(value, &record)
+	if err != nil {
+		return nil, err
+	}
+
+	tombstone := &entity.Tombstone{
+		Oid:       string(oid),
+		Size:      record.Size,
+		DeletedBy: record.DeletedBy,
+		DeletedAt: record.DeletedAt,
+		Reason:    record.Reason,
+	}
+
+	return tombstone, nil
+}
@@ -0,0 +1,64 @@
+package adapter
+
+import (
+	"testing"
+
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+func TestTombstones(t *testing.T) {
+
+	d := newTestData()
+	setupRepository(d)
+	defer teardownRepository(d)
+
+	repo := NewTombstoneRepository(d.database, nil)
+
+	if _, err := repo.Get(d.contentOid); err != usecase.ErrTombstoneNotFound {
+		t.Errorf("expected ErrTombstoneNotFound, got: %v", err)
+	}
+
+	err := repo.Add(&entity.ObjectDeletion{
+		Action: entity.DeletionActionDelete,
+		Oid:    d.contentOid,
+		Size:   d.contentSize,
+		Repos:  []string{d.repoName},
+		User:   d.userName1,
+		Reason: "leaked secret",
+		Time:   1,
+	})
+	if err != nil {
+		t.Fatalf("expected add to succeed, got: %s", err)
+	}
+
+	tombstone, err := repo.Get(d.contentOid)
+	if err != nil || tombstone.DeletedBy != d.userName1 || tombstone.Reason != "leaked secret" || tombstone.Size != d.contentSize {
+		t.Errorf("expected tombstone, got: %+v, %v", tombstone, err)
+	}
+
+	tombstones, err := repo.GetMany([]string{d.contentOid, d.nonExistContentOid})
+	if err != nil || len(tombstones) != 1 || tombstones[d.contentOid] == nil {
+		t.Errorf("expected one tombstone, got: %+v, %v", tombstones, err)
+	}
+
+	clear := &entity.ObjectDeletion{Action: entity.DeletionActionClear, Oid: d.contentOid, User: d.userName2, Reason: "false alarm", Time: 2}
+	if err := repo.Clear(clear); err != nil {
+		t.Fatalf("expected clear to succeed, got: %s", err)
+	}
+	if err := repo.Clear(clear); err != usecase.ErrTombstoneNotFound {
+		t.Errorf("expected clearing twice to fail, got: %v", err)
+	}
+
+	if all, _ := repo.Tombstones(); len(all) != 0 {
+		t.Errorf("expected no tombstones, got: %+v", all)
+	}
+
+	deletions, err := repo.Deletions()
+	if err != nil || len(deletions) != 2 {
+		t.Fatalf("expected two deletion records, got: %+v, %v", deletions, err)
+	}
+	if deletions[0].Action != entity.DeletionActionDelete || deletions[0].Repos[0] != d.repoName || deletions[1].Action != entity.DeletionActionClear || deletions[1].User != d.userName2 {
+		t.Errorf("expected deletion records in order, got: %+v, %+v", deletions[0], deletions[1])
+	}
+}
@@ -0,0 +1,145 @@
+package adapter
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+const (
+	multipartTransfer   = "multipart"
+	contentSHA256Header = "X-Amz-Content-Sha256"
+)
+
+type basicTransferAdapter struct{}
+
+// NewBasicTransferAdapter is ...
+// Objects are transferred through the server in one request.
+func NewBasicTransferAdapter() usecase.TransferAdapter {
+	return &basicTransferAdapter{}
+}
+
+func (a *basicTransferAdapter) Name() string {
+	return usecase.BasicTransfer
+}
+
+func (a *basicTransferAdapter) Actions(req *usecase.BatchRequest, obj *usecase.ObjectResult) (map[string]*usecase.Action, error) {
+
+	href := req.ObjectsURL + "/" + obj.Oid
+	if obj.ObjectExists {
+		return map[string]*usecase.Action{"download": contentAction(href)}, nil
+	}
+
+	return map[string]*usecase.Action{"upload": contentAction(href)}, nil
+}
+
+type multipartTransferAdapter struct{}
+
+// NewMultipartTransferAdapter is ...
+// Objects are uploaded through the server in parts and downloaded in one
+// piece, which is resumed with Range requests.
+func NewMultipartTransferAdapter() usecase.TransferAdapter {
+	return &multipartTransferAdapter{}
+}
+
+func (a *multipartTransferAdapter) Name() string {
+	return multipartTransfer
+}
+
+func (a *multipartTransferAdapter) Actions(req *usecase.BatchRequest, obj *usecase.ObjectResult) (map[string]*usecase.Action, error) {
+
+	href := req.ObjectsURL + "/" + obj.Oid
+	if obj.ObjectExists {
+		return map[string]*usecase.Action{"download": contentAction(href)}, nil
+	}
+
+	upload := &usecase.Action{
+		Href:   href + "/multipart",
+		Header: map[string]string{"Accept": metaMediaType},
+	}
+
+	return map[string]*usecase.Action{"upload": upload}, nil
+}
+
+type presignedTransferAdapter struct {
+	s3     s3iface.S3API
+	bucket string
+	expiry time.Duration
+}
+
+// NewPresignedTransferAdapter is ...
+// Objects are transferred directly from and to S3 with presigned URLs,
+// which basic clients follow like any other link, so the adapter replaces
+// the basic adapter. S3 checks the hash of uploads, whose size is verified by
+// the server afterwards.
+func NewPresignedTransferAdapter(bucket string, expiry time.Duration) (usecase.TransferAdapter, error) {
+
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, err
+	}
+
+	return newPresignedTransferAdapter(s3.New(sess), bucket, expiry), nil
+}
+
+func newPresignedTransferAdapter(client s3iface.S3API, bucket string, expiry time.Duration) *presignedTransferAdapter {
+	return &presignedTransferAdapter{
+		s3:     client,
+		bucket: bucket,
+		expiry: expiry,
+	}
+}
+
+func (a *presignedTransferAdapter) Name() string {
+	return usecase.BasicTransfer
+}
+
+func (a *presignedTransferAdapter) Actions(req *usecase.BatchRequest, obj *usecase.ObjectResult) (map[string]*usecase.Action, error) {
+
+	key := aws.String(transformKey(obj.Oid))
+	expiresAt := time.Now().Add(a.expiry)
+
+	if obj.ObjectExists {
+		r, _ := a.s3.GetObjectRequest(&s3.GetObjectInput{Bucket: aws.String(a.bucket), Key: key})
+		href, err := r.Presign(a.expiry)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]*usecase.Action{"download": {Href: href, ExpiresAt: expiresAt}}, nil
+	}
+
+	// The OID is signed as the hash of the payload, so S3 rejects content
+	// of another size or hash. Clients send the header with the upload.
+	r, _ := a.s3.PutObjectRequest(&s3.PutObjectInput{Bucket: aws.String(a.bucket), Key: key, ContentLength: aws.Int64(obj.Size)})
+	r.HTTPRequest.Header.Set(contentSHA256Header, obj.Oid)
+	href, err := r.Presign(a.expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := map[string]*usecase.Action{
+		"upload": {
+			Href:      href,
+			Header:    map[string]string{contentSHA256Header: obj.Oid},
+			ExpiresAt: expiresAt,
+		},
+		"verify": {
+			Href:   req.ObjectsURL + "/verify",
+			Header: map[string]string{"Accept": metaMediaType},
+		},
+	}
+
+	return actions, nil
+}
+
+func contentAction(href string) *usecase.Action {
+	return &usecase.Action{
+		Href:   href,
+		Header: map[string]string{"Accept": contentMediaType},
+	}
+}
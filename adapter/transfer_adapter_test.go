@@ -0,0 +1,120 @@
+package adapter
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+const testObjectsURL = "https://lfs.example.com/user/repo/objects"
+
+func TestNegotiateTransfer(t *testing.T) {
+
+	adapters := usecase.NewTransferAdapters(NewBasicTransferAdapter(), NewMultipartTransferAdapter())
+
+	tests := []struct {
+		transfers []string
+		expected  string
+	}{
+		{nil, "basic"},
+		{[]string{"multipart", "basic"}, "multipart"},
+		{[]string{"basic", "multipart"}, "basic"},
+		{[]string{"lfs-standalone-file"}, "basic"},
+	}
+
+	for _, test := range tests {
+		a, err := adapters.Negotiate(test.transfers)
+		if err != nil {
+			t.Fatalf("expected an adapter for %v, got: %s", test.transfers, err)
+		}
+		if a.Name() != test.expected {
+			t.Errorf("expected %s for %v, got %s", test.expected, test.transfers, a.Name())
+		}
+	}
+
+	_, err := usecase.NewTransferAdapters(NewMultipartTransferAdapter()).Negotiate([]string{"basic"})
+	if err != usecase.ErrTransferNotSupported {
+		t.Errorf("expected no adapter without basic, got: %v", err)
+	}
+}
+
+func TestBasicTransferAdapterActions(t *testing.T) {
+
+	req := &usecase.BatchRequest{ObjectsURL: testObjectsURL}
+	a := NewBasicTransferAdapter()
+
+	actions, err := a.Actions(req, &usecase.ObjectResult{Oid: "oid", ObjectExists: true})
+	if err != nil {
+		t.Fatalf("expected actions, got: %s", err)
+	}
+
+	download := actions["download"]
+	if download == nil || download.Href != testObjectsURL+"/oid" || download.Header["Accept"] != contentMediaType {
+		t.Errorf("expected download through the server, got: %+v", actions)
+	}
+
+	actions, _ = NewMultipartTransferAdapter().Actions(req, &usecase.ObjectResult{Oid: "oid"})
+
+	upload := actions["upload"]
+	if upload == nil || upload.Href != testObjectsURL+"/oid/multipart" || upload.Header["Accept"] != metaMediaType {
+		t.Errorf("expected multipart upload, got: %+v", actions)
+	}
+}
+
+func TestPresignedTransferAdapterActions(t *testing.T) {
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+	})
+	if err != nil {
+		t.Fatalf("expected session, got: %s", err)
+	}
+
+	a := newPresignedTransferAdapter(s3.New(sess), testS3BucketName, time.Minute)
+	if a.Name() != "basic" {
+		t.Errorf("expected presigned links to be offered as basic, got %s", a.Name())
+	}
+
+	req := &usecase.BatchRequest{ObjectsURL: testObjectsURL}
+
+	actions, err := a.Actions(req, &usecase.ObjectResult{Oid: "0123456789abcdef"})
+	if err != nil {
+		t.Fatalf("expected actions, got: %s", err)
+	}
+
+	upload := actions["upload"]
+	if upload == nil || upload.ExpiresAt.IsZero() {
+		t.Fatalf("expected expiring upload action, got: %+v", actions)
+	}
+
+	u, err := url.Parse(upload.Href)
+	if err != nil {
+		t.Fatalf("expected upload URL, got: %s", err)
+	}
+
+	if !strings.HasSuffix(u.Path, transformKey("0123456789abcdef")) || u.Query().Get("X-Amz-Expires") != "60" {
+		t.Errorf("expected presigned URL of the object, got: %s", upload.Href)
+	}
+
+	// S3 only accepts content hashing to the OID
+	if !strings.Contains(u.Query().Get("X-Amz-SignedHeaders"), "x-amz-content-sha256") || upload.Header["X-Amz-Content-Sha256"] != "0123456789abcdef" {
+		t.Errorf("expected the upload to be signed with the content hash, got: %s, %v", upload.Href, upload.Header)
+	}
+
+	if verify := actions["verify"]; verify == nil || verify.Href != testObjectsURL+"/verify" {
+		t.Errorf("expected verify action, got: %+v", actions)
+	}
+
+	actions, err = a.Actions(req, &usecase.ObjectResult{Oid: "0123456789abcdef", ObjectExists: true})
+	if err != nil || actions["download"] == nil || actions["upload"] != nil {
+		t.Errorf("expected download action, got: %+v, %v", actions, err)
+	}
+}
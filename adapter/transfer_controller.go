@@ -1,33 +1,49 @@
 package adapter
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"regexp"
 	"strconv"
+	"time"
 
+	"github.com/ikmski/git-lfs3/entity"
 	"github.com/ikmski/git-lfs3/usecase"
 )
 
 type transferController struct {
 	transferService usecase.TransferService
+	accessService   usecase.AccessService
+	metrics         Metrics
 }
 
 // TransferController is ...
 type TransferController interface {
 	Download(ctx Context)
 	Upload(ctx Context)
+	Verify(ctx Context)
 }
 
 // NewTransferController is ...
-func NewTransferController(s usecase.TransferService) TransferController {
+func NewTransferController(s usecase.TransferService, access usecase.AccessService, metrics Metrics) TransferController {
 	return &transferController{
 		transferService: s,
+		accessService:   access,
+		metrics:         metricsOrNop(metrics),
 	}
 }
 
 func (c *transferController) Download(ctx Context) {
 
+	if !authorize(ctx, c.accessService, entity.RoleRead, entity.ScopeRead, "You must have read access to download objects") {
+		return
+	}
+
 	or := parseObjectRequest(ctx)
+	if !validateOid(ctx, or.Oid) {
+		return
+	}
 
 	exists := c.transferService.Exists(or)
 	if !exists {
@@ -58,36 +74,125 @@ func (c *transferController) Download(ctx Context) {
 		ctx.SetStatus(206)
 	}
 
-	_, err := c.transferService.Download(or, ctx.GetResponseWriter())
+	start := time.Now()
+	n, err := c.transferService.Download(or, ctx.GetResponseWriter())
 	if err != nil {
 		ctx.SetStatus(404)
 		return
 	}
+
+	c.metrics.ObserveTransfer(or.Repo, "download", n, time.Since(start))
 }
 
 func (c *transferController) Upload(ctx Context) {
 
+	if !authorize(ctx, c.accessService, entity.RoleWrite, entity.ScopeWrite, "You must have push access to upload objects") {
+		return
+	}
+
 	o := parseObjectRequest(ctx)
-	exists := c.transferService.Exists(o)
-	if !exists {
-		ctx.SetStatus(404)
+	if !validateOid(ctx, o.Oid) {
+		return
+	}
+
+	o.Size = -1
+	if cl := ctx.GetHeader("Content-Length"); cl != "" {
+		n, err := strconv.ParseInt(cl, 10, 64)
+		if err != nil || n < 0 {
+			writeErrorResponse(ctx, 400, "Invalid Content-Length")
+			return
+		}
+		o.Size = n
+	}
+
+	start := time.Now()
+	r := &countingReader{r: ctx.GetRequestReader()}
+	err := c.transferService.Upload(o, r)
+	switch err {
+	case nil:
+	case usecase.ErrObjectNotFound:
+		writeErrorResponse(ctx, 404, err.Error())
+		return
+	case usecase.ErrContentLengthMismatch:
+		writeErrorResponse(ctx, 400, err.Error())
+		return
+	case usecase.ErrSizeMismatch, usecase.ErrHashMismatch:
+		writeErrorResponse(ctx, 422, err.Error())
+		return
+	default:
+		if !writeObjectError(ctx, err) {
+			writeErrorResponse(ctx, 500, "Failed to store the object")
+		}
 		return
 	}
 
-	err := c.transferService.Upload(o, ctx.GetRequestReader())
+	c.metrics.ObserveTransfer(o.Repo, "upload", r.n, time.Since(start))
+}
+
+// Verify marks an object uploaded directly to storage as complete
+func (c *transferController) Verify(ctx Context) {
+
+	if !authorize(ctx, c.accessService, entity.RoleWrite, entity.ScopeWrite, "You must have push access to upload objects") {
+		return
+	}
+
+	data, err := ctx.GetRawData()
 	if err != nil {
-		ctx.SetStatus(500)
-		//fmt.Fprintf(c.Writer, `{"message":"%s"}`, err)
+		writeRequestError(ctx, err, "Invalid verify request")
 		return
 	}
+
+	var req ObjectRequest
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		writeRequestError(ctx, err, "Invalid verify request")
+		return
+	}
+
+	if !validateOid(ctx, req.Oid) {
+		return
+	}
+
+	o := parseObjectRequest(ctx)
+	o.Oid = req.Oid
+	o.Size = req.Size
+
+	err = c.transferService.Verify(o)
+	switch err {
+	case nil:
+		ctx.SetStatus(200)
+	case usecase.ErrObjectNotFound:
+		writeErrorResponse(ctx, 404, err.Error())
+	case usecase.ErrSizeMismatch:
+		writeErrorResponse(ctx, 422, err.Error())
+	default:
+		if !writeObjectError(ctx, err) {
+			writeErrorResponse(ctx, 500, "Failed to verify the object")
+		}
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
 }
 
 func parseObjectRequest(ctx Context) *usecase.ObjectRequest {
 
+	user := ctx.GetUser()
+	repo := repoPath(ctx)
 	oid := ctx.GetParam("oid")
 
 	or := &usecase.ObjectRequest{
-		Oid: oid,
+		User: user,
+		Repo: repo,
+		Oid:  oid,
 	}
 
 	return or
@@ -6,18 +6,21 @@ import (
 	"github.com/boltdb/bolt"
 	"github.com/ikmski/git-lfs3/entity"
 	"github.com/ikmski/git-lfs3/usecase"
+	"golang.org/x/crypto/bcrypt"
 )
 
 var (
-	usersBucket = []byte("users")
+	usersBucket           = []byte("users")
+	errInvalidCredentials = errors.New("Invalid user name or password")
 )
 
 type userRepository struct {
-	db *bolt.DB
+	db      *bolt.DB
+	metrics Metrics
 }
 
 // NewUserRepository is ...
-func NewUserRepository(db *bolt.DB) usecase.UserRepository {
+func NewUserRepository(db *bolt.DB, metrics Metrics) usecase.UserRepository {
 
 	db.Update(func(tx *bolt.Tx) error {
 
@@ -29,20 +32,30 @@ func NewUserRepository(db *bolt.DB) usecase.UserRepository {
 
 	})
 
-	return &userRepository{db: db}
+	return &userRepository{db: db, metrics: metricsOrNop(metrics)}
 }
 
 // AddUser adds user credentials to the meta store.
 func (r *userRepository) AddUser(user, pass string) error {
 
-	err := r.db.Update(func(tx *bolt.Tx) error {
+	err := boltUpdate(r.db, r.metrics, "user.add", func(tx *bolt.Tx) error {
 
 		bucket := tx.Bucket(usersBucket)
 		if bucket == nil {
 			return errors.New("Bucket not found")
 		}
 
-		err := bucket.Put([]byte(user), []byte(pass))
+		hash, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+
+		data, err := encodeRecord(&userRecord{PasswordHash: string(hash)})
+		if err != nil {
+			return err
+		}
+
+		err = bucket.Put([]byte(user), data)
 		if err != nil {
 			return err
 		}
@@ -52,10 +65,10 @@ func (r *userRepository) AddUser(user, pass string) error {
 	return err
 }
 
-// DeleteUser removes user credentials from the meta store.
+// DeleteUser removes user credentials and access tokens from the meta store.
 func (r *userRepository) DeleteUser(user string) error {
 
-	err := r.db.Update(func(tx *bolt.Tx) error {
+	err := boltUpdate(r.db, r.metrics, "user.delete", func(tx *bolt.Tx) error {
 
 		bucket := tx.Bucket(usersBucket)
 		if bucket == nil {
@@ -63,7 +76,16 @@ func (r *userRepository) DeleteUser(user string) error {
 		}
 
 		err := bucket.Delete([]byte(user))
-		return err
+		if err != nil {
+			return err
+		}
+
+		tokens := tx.Bucket(accessTokensBucket)
+		if tokens == nil || tokens.Bucket([]byte(user)) == nil {
+			return nil
+		}
+
+		return tokens.DeleteBucket([]byte(user))
 	})
 
 	return err
@@ -74,7 +96,7 @@ func (r *userRepository) Users() ([]*entity.User, error) {
 
 	var users []*entity.User
 
-	err := r.db.View(func(tx *bolt.Tx) error {
+	err := boltView(r.db, r.metrics, "user.list", func(tx *bolt.Tx) error {
 
 		bucket := tx.Bucket(usersBucket)
 		if bucket == nil {
@@ -93,3 +115,34 @@ func (r *userRepository) Users() ([]*entity.User, error) {
 
 	return users, err
 }
+
+// Authenticate checks the password of the user.
+func (r *userRepository) Authenticate(user, pass string) (*entity.User, error) {
+
+	var record userRecord
+
+	err := boltView(r.db, r.metrics, "user.get", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(usersBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		value := bucket.Get([]byte(user))
+		if len(value) == 0 {
+			return errInvalidCredentials
+		}
+
+		return decodeRecord(value, &record)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(record.PasswordHash), []byte(pass))
+	if err != nil {
+		return nil, errInvalidCredentials
+	}
+
+	return &entity.User{Name: user}, nil
+}
@@ -0,0 +1,291 @@
+package adapter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+var (
+	// webhookDeliveriesBucket holds the queued deliveries keyed by sequence
+	webhookDeliveriesBucket = []byte("webhook_deliveries")
+	// webhookQueuesBucket holds a bucket of the sequence keys of the
+	// deliveries queued for each URL
+	webhookQueuesBucket = []byte("webhook_queues")
+	// webhookDueBucket indexes the first delivery queued for each URL by the
+	// time of its next attempt followed by its sequence key
+	webhookDueBucket = []byte("webhook_due")
+)
+
+type webhookDeliveryRepository struct {
+	db      *bolt.DB
+	metrics Metrics
+}
+
+// NewWebhookDeliveryRepository is ...
+// The ID of a delivery is the hex of its sequence key, which keeps the
+// deliveries in the order they were enqueued.
+func NewWebhookDeliveryRepository(db *bolt.DB, metrics Metrics) usecase.WebhookDeliveryRepository {
+
+	db.Update(func(tx *bolt.Tx) error {
+
+		for _, name := range [][]byte{webhookDeliveriesBucket, webhookQueuesBucket, webhookDueBucket} {
+			_, err := tx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return &webhookDeliveryRepository{db: db, metrics: metricsOrNop(metrics)}
+}
+
+// Enqueue stores the delivery under the next sequence number
+func (r *webhookDeliveryRepository) Enqueue(delivery *entity.WebhookDelivery) error {
+
+	return boltUpdate(r.db, r.metrics, "webhook.enqueue", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(webhookDeliveriesBucket)
+		if bucket == nil {
+			return errors.New("Bucket not found")
+		}
+
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		delivery.ID = hex.EncodeToString(key)
+
+		data, err := encodeRecord(delivery)
+		if err != nil {
+			return err
+		}
+
+		err = bucket.Put(key, data)
+		if err != nil {
+			return err
+		}
+
+		return queueDelivery(tx, key, delivery)
+	})
+}
+
+// Due walks the index of the first delivery of each URL, so that a URL is
+// blocked until its oldest delivery is delivered or dropped
+func (r *webhookDeliveryRepository) Due(now int64, limit int) ([]*entity.WebhookDelivery, error) {
+
+	var deliveries []*entity.WebhookDelivery
+
+	err := boltView(r.db, r.metrics, "webhook.due", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(webhookDeliveriesBucket)
+		due := tx.Bucket(webhookDueBucket)
+		if bucket == nil || due == nil {
+			return errors.New("Bucket not found")
+		}
+
+		c := due.Cursor()
+		for k, _ := c.First(); k != nil && len(deliveries) < limit; k, _ = c.Next() {
+
+			if int64(binary.BigEndian.Uint64(k[:8])) > now {
+				break
+			}
+
+			v := bucket.Get(k[8:])
+			if v == nil {
+				return fmt.Errorf("Webhook delivery %x not found", k[8:])
+			}
+
+			var delivery entity.WebhookDelivery
+			err := decodeRecord(v, &delivery)
+			if err != nil {
+				return err
+			}
+
+			deliveries = append(deliveries, &delivery)
+		}
+
+		return nil
+	})
+
+	return deliveries, err
+}
+
+// Update stores the delivery if it is still queued
+func (r *webhookDeliveryRepository) Update(delivery *entity.WebhookDelivery) error {
+
+	key, err := hex.DecodeString(delivery.ID)
+	if err != nil {
+		return err
+	}
+
+	return boltUpdate(r.db, r.metrics, "webhook.update", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(webhookDeliveriesBucket)
+		due := tx.Bucket(webhookDueBucket)
+		if bucket == nil || due == nil {
+			return errors.New("Bucket not found")
+		}
+
+		v := bucket.Get(key)
+		if v == nil {
+			return nil
+		}
+
+		var stored entity.WebhookDelivery
+		err := decodeRecord(v, &stored)
+		if err != nil {
+			return err
+		}
+
+		// the URL and the place in its queue cannot change
+		delivery.URL = stored.URL
+
+		data, err := encodeRecord(delivery)
+		if err != nil {
+			return err
+		}
+
+		err = bucket.Put(key, data)
+		if err != nil {
+			return err
+		}
+
+		if !isQueueHead(tx, stored.URL, key) {
+			return nil
+		}
+
+		err = due.Delete(webhookDueKey(stored.NextAttemptAt, key))
+		if err != nil {
+			return err
+		}
+
+		return due.Put(webhookDueKey(delivery.NextAttemptAt, key), []byte{})
+	})
+}
+
+// Delete removes the delivery from the queue, making the next delivery to
+// its URL due at its own next attempt
+func (r *webhookDeliveryRepository) Delete(id string) error {
+
+	key, err := hex.DecodeString(id)
+	if err != nil {
+		return err
+	}
+
+	return boltUpdate(r.db, r.metrics, "webhook.delete", func(tx *bolt.Tx) error {
+
+		bucket := tx.Bucket(webhookDeliveriesBucket)
+		queues := tx.Bucket(webhookQueuesBucket)
+		due := tx.Bucket(webhookDueBucket)
+		if bucket == nil || queues == nil || due == nil {
+			return errors.New("Bucket not found")
+		}
+
+		v := bucket.Get(key)
+		if v == nil {
+			return nil
+		}
+
+		var stored entity.WebhookDelivery
+		err := decodeRecord(v, &stored)
+		if err != nil {
+			return err
+		}
+
+		err = bucket.Delete(key)
+		if err != nil {
+			return err
+		}
+
+		queue := queues.Bucket([]byte(stored.URL))
+		if queue == nil {
+			return nil
+		}
+
+		head := isQueueHead(tx, stored.URL, key)
+
+		err = queue.Delete(key)
+		if err != nil {
+			return err
+		}
+
+		if !head {
+			return nil
+		}
+
+		err = due.Delete(webhookDueKey(stored.NextAttemptAt, key))
+		if err != nil {
+			return err
+		}
+
+		next, _ := queue.Cursor().First()
+		if next == nil {
+			return queues.DeleteBucket([]byte(stored.URL))
+		}
+
+		var delivery entity.WebhookDelivery
+		err = decodeRecord(bucket.Get(next), &delivery)
+		if err != nil {
+			return err
+		}
+
+		return due.Put(webhookDueKey(delivery.NextAttemptAt, next), []byte{})
+	})
+}
+
+// queueDelivery appends the stored delivery to the queue of its URL,
+// indexing it as due when it is the first
+func queueDelivery(tx *bolt.Tx, key []byte, delivery *entity.WebhookDelivery) error {
+
+	queues := tx.Bucket(webhookQueuesBucket)
+	due := tx.Bucket(webhookDueBucket)
+	if queues == nil || due == nil {
+		return errors.New("Bucket not found")
+	}
+
+	queue, err := queues.CreateBucketIfNotExists([]byte(delivery.URL))
+	if err != nil {
+		return err
+	}
+
+	err = queue.Put(key, []byte{})
+	if err != nil {
+		return err
+	}
+
+	if !isQueueHead(tx, delivery.URL, key) {
+		return nil
+	}
+
+	return due.Put(webhookDueKey(delivery.NextAttemptAt, key), []byte{})
+}
+
+func isQueueHead(tx *bolt.Tx, url string, key []byte) bool {
+
+	queue := tx.Bucket(webhookQueuesBucket).Bucket([]byte(url))
+	if queue == nil {
+		return false
+	}
+
+	first, _ := queue.Cursor().First()
+	return bytes.Equal(first, key)
+}
+
+func webhookDueKey(at int64, key []byte) []byte {
+
+	dueKey := make([]byte, 8, 8+len(key))
+	binary.BigEndian.PutUint64(dueKey, uint64(at))
+	return append(dueKey, key...)
+}
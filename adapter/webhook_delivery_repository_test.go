@@ -0,0 +1,105 @@
+package adapter
+
+import (
+	"testing"
+
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+func TestWebhookDeliveries(t *testing.T) {
+
+	d := newTestData()
+	setupRepository(d)
+	defer teardownRepository(d)
+
+	repo := NewWebhookDeliveryRepository(d.database, nil)
+
+	first := &entity.WebhookDelivery{URL: "http://hook/1", Event: entity.WebhookEvent{Event: entity.WebhookLockCreated, Repo: d.repoName}, NextAttemptAt: 10}
+	second := &entity.WebhookDelivery{URL: "http://hook/2", Event: entity.WebhookEvent{Event: entity.WebhookObjectUploaded, Repo: d.repoName}, NextAttemptAt: 20}
+	for _, delivery := range []*entity.WebhookDelivery{first, second} {
+		if err := repo.Enqueue(delivery); err != nil {
+			t.Fatalf("expected enqueue to succeed, got: %s", err)
+		}
+	}
+	if first.ID == "" || first.ID >= second.ID {
+		t.Fatalf("expected ascending ids, got %q and %q", first.ID, second.ID)
+	}
+
+	due, err := repo.Due(10, 10)
+	if err != nil || len(due) != 1 || due[0].ID != first.ID || due[0].Event.Event != entity.WebhookLockCreated {
+		t.Fatalf("expected the first delivery, got: %+v, %v", due, err)
+	}
+
+	if due, _ := repo.Due(20, 1); len(due) != 1 || due[0].ID != first.ID {
+		t.Errorf("expected the limit to apply in order, got: %+v", due)
+	}
+
+	first.Attempts = 1
+	first.NextAttemptAt = 30
+	first.LastError = "timeout"
+	if err := repo.Update(first); err != nil {
+		t.Fatalf("expected update to succeed, got: %s", err)
+	}
+
+	due, err = repo.Due(20, 10)
+	if err != nil || len(due) != 1 || due[0].ID != second.ID {
+		t.Fatalf("expected the second delivery, got: %+v, %v", due, err)
+	}
+
+	if err := repo.Delete(second.ID); err != nil {
+		t.Fatalf("expected delete to succeed, got: %s", err)
+	}
+
+	// updating a deleted delivery does not queue it again
+	if err := repo.Update(second); err != nil {
+		t.Fatalf("expected update to succeed, got: %s", err)
+	}
+
+	due, err = repo.Due(30, 10)
+	if err != nil || len(due) != 1 || due[0].ID != first.ID || due[0].Attempts != 1 || due[0].LastError != "timeout" {
+		t.Errorf("expected the updated first delivery, got: %+v, %v", due, err)
+	}
+}
+
+func TestWebhookDeliveriesPerURL(t *testing.T) {
+
+	d := newTestData()
+	setupRepository(d)
+	defer teardownRepository(d)
+
+	repo := NewWebhookDeliveryRepository(d.database, nil)
+
+	first := &entity.WebhookDelivery{URL: "http://hook/1", NextAttemptAt: 10}
+	second := &entity.WebhookDelivery{URL: "http://hook/1", NextAttemptAt: 10}
+	other := &entity.WebhookDelivery{URL: "http://hook/2", NextAttemptAt: 20}
+	for _, delivery := range []*entity.WebhookDelivery{first, second, other} {
+		if err := repo.Enqueue(delivery); err != nil {
+			t.Fatalf("expected enqueue to succeed, got: %s", err)
+		}
+	}
+
+	// only the first delivery of each URL is due, in the order of the next attempt
+	due, err := repo.Due(20, 10)
+	if err != nil || len(due) != 2 || due[0].ID != first.ID || due[1].ID != other.ID {
+		t.Fatalf("expected the first delivery of each url, got: %+v, %v", due, err)
+	}
+
+	// a failed delivery blocks the later deliveries to its URL
+	first.Attempts = 1
+	first.NextAttemptAt = 30
+	if err := repo.Update(first); err != nil {
+		t.Fatalf("expected update to succeed, got: %s", err)
+	}
+	due, err = repo.Due(20, 10)
+	if err != nil || len(due) != 1 || due[0].ID != other.ID {
+		t.Fatalf("expected the blocked url to be skipped, got: %+v, %v", due, err)
+	}
+
+	if err := repo.Delete(first.ID); err != nil {
+		t.Fatalf("expected delete to succeed, got: %s", err)
+	}
+	due, err = repo.Due(20, 10)
+	if err != nil || len(due) != 2 || due[0].ID != second.ID || due[1].ID != other.ID {
+		t.Errorf("expected the next delivery to be due, got: %+v, %v", due, err)
+	}
+}
@@ -0,0 +1,111 @@
+package adapter
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+// Headers of webhook requests. The signature is the hex of the HMAC-SHA256
+// of the body keyed with the secret of the webhook, prefixed with "sha256=".
+const (
+	WebhookEventHeader     = "X-Git-LFS3-Event"
+	WebhookDeliveryHeader  = "X-Git-LFS3-Delivery"
+	WebhookSignatureHeader = "X-Git-LFS3-Signature"
+)
+
+const defaultWebhookTimeout = 10 * time.Second
+
+type webhookSender struct {
+	client *http.Client
+}
+
+// NewWebhookSender returns a sender posting deliveries as JSON.
+// timeout limits every request and defaults to 10 seconds.
+func NewWebhookSender(timeout time.Duration) usecase.WebhookSender {
+
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+
+	return &webhookSender{client: &http.Client{Timeout: timeout}}
+}
+
+// Send posts the delivery and fails unless the webhook responds with a 2xx status
+func (s *webhookSender) Send(hook *entity.Webhook, delivery *entity.WebhookDelivery) error {
+
+	body, err := json.Marshal(convertWebhookPayload(delivery))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, delivery.Event.Event)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhookPayload(hook.Secret, body))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// drain the body so that the connection can be reused
+	io.Copy(ioutil.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("Webhook responded with status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+// SignWebhookPayload returns the hex of the HMAC-SHA256 of the body keyed with the secret
+func SignWebhookPayload(secret string, body []byte) string {
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func convertWebhookPayload(delivery *entity.WebhookDelivery) *WebhookPayload {
+
+	event := delivery.Event
+
+	payload := &WebhookPayload{
+		ID:    delivery.ID,
+		Event: event.Event,
+		Repo:  event.Repo,
+		Actor: event.Actor,
+		Time:  time.Unix(event.Time, 0).UTC(),
+	}
+
+	if event.Lock != nil {
+		payload.Lock = &Lock{
+			ID:       event.Lock.ID,
+			Path:     event.Lock.Path,
+			Owner:    User{Name: event.Lock.Owner.Name},
+			LockedAt: time.Unix(event.Lock.LockedAt, 0).UTC(),
+		}
+	}
+
+	if event.Object != nil {
+		payload.Object = &WebhookObject{Oid: event.Object.Oid, Size: event.Object.Size}
+	}
+
+	return payload
+}
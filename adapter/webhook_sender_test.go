@@ -0,0 +1,61 @@
+package adapter
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+func TestWebhookSender(t *testing.T) {
+
+	var payload WebhookPayload
+	var signature string
+	status := 204
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &payload)
+		signature = r.Header.Get(WebhookSignatureHeader)
+		if signature != "sha256="+SignWebhookPayload("secret", body) {
+			signature = "invalid"
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sender := NewWebhookSender(0)
+	hook := &entity.Webhook{URL: server.URL, Secret: "secret"}
+	delivery := &entity.WebhookDelivery{
+		ID:  "0000000000000001",
+		URL: server.URL,
+		Event: entity.WebhookEvent{
+			Event:  entity.WebhookObjectUploaded,
+			Repo:   "repo",
+			Actor:  "bilbo",
+			Time:   1500000000,
+			Object: &entity.WebhookObject{Oid: "oid", Size: 3},
+		},
+	}
+
+	if err := sender.Send(hook, delivery); err != nil {
+		t.Fatalf("expected send to succeed, got: %s", err)
+	}
+	if signature == "invalid" {
+		t.Errorf("expected the payload to be signed with the secret")
+	}
+	if payload.ID != delivery.ID || payload.Event != entity.WebhookObjectUploaded || payload.Object == nil || payload.Object.Size != 3 || payload.Lock != nil {
+		t.Errorf("expected object payload, got: %+v", payload)
+	}
+	if payload.Time.Unix() != 1500000000 {
+		t.Errorf("expected event time, got: %s", payload.Time)
+	}
+
+	status = 500
+	if err := sender.Send(hook, delivery); err == nil {
+		t.Errorf("expected send to fail on status 500")
+	}
+}
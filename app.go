@@ -1,55 +1,185 @@
 package main
 
 import (
+	gocontext "context"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/boltdb/bolt"
 	"github.com/gorilla/mux"
 	"github.com/ikmski/git-lfs3/adapter"
+	"github.com/ikmski/git-lfs3/usecase"
 )
 
 const (
 	contentMediaType = "application/vnd.git-lfs"
 	metaMediaType    = "application/vnd.git-lfs+json"
+
+	defaultShutdownTimeout = 30 * time.Second
 )
 
 type app struct {
-	config serverConfig
-	router *mux.Router
+	config       serverConfig
+	logger       adapter.Logger
+	router       *mux.Router
+	db           *bolt.DB
+	webhooks     *usecase.Webhooks
+	stopWebhooks func()
+	shuttingDown int32
 }
 
 func newApp(
 	conf serverConfig,
+	logger adapter.Logger,
 	batchController adapter.BatchController,
 	transferController adapter.TransferController,
-	lockController adapter.LockController) *app {
+	multipartController adapter.MultipartController,
+	importController adapter.ImportController,
+	objectController adapter.ObjectController,
+	lockController adapter.LockController,
+	healthController adapter.HealthController,
+	accessTokenController adapter.AccessTokenController,
+	repositoryController adapter.RepositoryController,
+	deletionController adapter.DeletionController,
+	auditController adapter.AuditController,
+	accessService usecase.AccessService) *app {
 
 	a := &app{
 		config: conf,
+		logger: logger,
 	}
 
+	limits := conf.BodyLimits.withDefaults()
+
 	r := mux.NewRouter()
+	r.Use(func(next http.Handler) http.Handler { return accessLog(logger, next) })
+	r.NotFoundHandler = accessLog(logger, http.NotFoundHandler())
+
+	// Admin API, registered first as its paths also match the Git LFS API prefix
+	admin := r.PathPrefix("/admin").Subrouter()
+	admin.Use(func(next http.Handler) http.Handler { return authenticate(accessService, next) })
+
+	admin.Methods("GET").Path("/users/{name}/tokens").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { accessTokenController.List(newContext(w, r)) })
+	admin.Methods("POST").Path("/users/{name}/tokens").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accessTokenController.Create(newLimitedContext(w, r, limits.Admin))
+		})
+	admin.Methods("DELETE").Path("/users/{name}/tokens/{id}").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { accessTokenController.Revoke(newContext(w, r)) })
+
+	admin.Methods("GET").Path("/repos").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { repositoryController.List(newContext(w, r)) })
+	admin.Methods("POST").Path("/repos").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			repositoryController.Create(newLimitedContext(w, r, limits.Admin))
+		})
+	admin.Methods("GET").Path("/repos/{owner}/{name}").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { repositoryController.Get(newContext(w, r)) })
+	admin.Methods("DELETE").Path("/repos/{owner}/{name}").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { repositoryController.Delete(newContext(w, r)) })
+	admin.Methods("POST").Path("/repos/{owner}/{name}/rename").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			repositoryController.Rename(newLimitedContext(w, r, limits.Admin))
+		})
+	admin.Methods("PUT").Path("/repos/{owner}/{name}/archive").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { repositoryController.Archive(newContext(w, r)) })
+	admin.Methods("DELETE").Path("/repos/{owner}/{name}/archive").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { repositoryController.Unarchive(newContext(w, r)) })
+
+	admin.Methods("DELETE").Path("/objects/{oid}").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { deletionController.Delete(newContext(w, r)) })
+	admin.Methods("GET").Path("/tombstones").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { deletionController.Tombstones(newContext(w, r)) })
+	admin.Methods("DELETE").Path("/tombstones/{oid}").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { deletionController.Clear(newContext(w, r)) })
+	admin.Methods("GET").Path("/deletions").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { deletionController.Deletions(newContext(w, r)) })
+
+	admin.Methods("GET").Path("/audit").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { auditController.Query(newContext(w, r)) })
+
+	// Git LFS API, for authenticated users only
+	lfs := r.PathPrefix("/{user}/{repo}").Subrouter()
+	lfs.Use(func(next http.Handler) http.Handler { return authenticate(accessService, next) })
 
 	// Batch
-	r.Methods("POST").Path("/{user}/{repo}/objects/batch").MatcherFunc(MetaMatcher).
-		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { batchController.Batch(newContext(w, r)) })
+	lfs.Methods("POST").Path("/objects/batch").MatcherFunc(MetaMatcher).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			batchController.Batch(newLimitedContext(w, r, limits.Batch))
+		})
 
 	// Transfer
-	r.Methods("GET").Path("/{user}/{repo}/objects/{oid}").MatcherFunc(ContentMatcher).
+	lfs.Methods("GET").Path("/objects/{oid}").MatcherFunc(ContentMatcher).
 		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { transferController.Download(newContext(w, r)) })
-	r.Methods("PUT").Path("/{user}/{repo}/objects/{oid}").MatcherFunc(ContentMatcher).
+	lfs.Methods("PUT").Path("/objects/{oid}").MatcherFunc(ContentMatcher).
 		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { transferController.Upload(newContext(w, r)) })
 
+	lfs.Methods("POST").Path("/objects/verify").MatcherFunc(MetaMatcher).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			transferController.Verify(newLimitedContext(w, r, limits.Default))
+		})
+
+	// Objects
+	lfs.Methods("GET").Path("/objects").MatcherFunc(MetaMatcher).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { objectController.List(newContext(w, r)) })
+	lfs.Methods("GET").Path("/objects/{oid}/info").MatcherFunc(MetaMatcher).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { objectController.Info(newContext(w, r)) })
+
+	// Import
+	lfs.Methods("POST").Path("/objects/import").MatcherFunc(MetaMatcher).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			importController.Import(newLimitedContext(w, r, limits.Batch))
+		})
+
+	// Multipart transfer
+	lfs.Methods("POST").Path("/objects/{oid}/multipart").MatcherFunc(MetaMatcher).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { multipartController.Start(newContext(w, r)) })
+	lfs.Methods("PUT").Path("/objects/{oid}/multipart/{part:[0-9]+}").MatcherFunc(ContentMatcher).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { multipartController.UploadPart(newContext(w, r)) })
+	lfs.Methods("POST").Path("/objects/{oid}/multipart/complete").MatcherFunc(MetaMatcher).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { multipartController.Complete(newContext(w, r)) })
+	lfs.Methods("DELETE").Path("/objects/{oid}/multipart").MatcherFunc(MetaMatcher).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { multipartController.Abort(newContext(w, r)) })
+
 	// Lock
-	r.Methods("GET").Path("/{user}/{repo}/locks").MatcherFunc(MetaMatcher).
-		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { lockController.List(newContext(w, r)) })
-	r.Methods("POST").Path("/{user}/{repo}/locks/verify").MatcherFunc(MetaMatcher).
-		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { lockController.Verify(newContext(w, r)) })
-	r.Methods("POST").Path("/{user}/{repo}/locks").MatcherFunc(MetaMatcher).
-		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { lockController.Lock(newContext(w, r)) })
-	r.Methods("POST").Path("/{user}/{repo}/locks/{id}/unlock").MatcherFunc(MetaMatcher).
-		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { lockController.Unlock(newContext(w, r)) })
+	lfs.Methods("GET").Path("/locks").MatcherFunc(MetaMatcher).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lockController.List(newLimitedContext(w, r, limits.Locks))
+		})
+	lfs.Methods("POST").Path("/locks/verify").MatcherFunc(MetaMatcher).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lockController.Verify(newLimitedContext(w, r, limits.Locks))
+		})
+	lfs.Methods("POST").Path("/locks").MatcherFunc(MetaMatcher).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lockController.Lock(newLimitedContext(w, r, limits.Locks))
+		})
+	lfs.Methods("POST").Path("/locks/{id}/unlock").MatcherFunc(MetaMatcher).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lockController.Unlock(newLimitedContext(w, r, limits.Locks))
+		})
+
+	// Health
+	r.Methods("GET").Path("/healthz").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) { healthController.Live(newContext(w, r)) })
+	r.Methods("GET").Path("/readyz").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.LoadInt32(&a.shuttingDown) != 0 {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{"status":"shutting down"}`))
+				return
+			}
+			healthController.Ready(newContext(w, r))
+		})
 
 	a.router = r
 
@@ -61,18 +191,90 @@ func (a *app) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	a.router.ServeHTTP(w, r)
 }
 
+// serve listens on the configured port until SIGINT or SIGTERM is received
 func (a *app) serve() error {
 
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", a.config.Port))
+	if err != nil {
+		a.close()
+		return err
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	return a.run(l, stop)
+}
+
+// run serves requests on l until a signal is received from stop.
+// In-flight requests are then given the shutdown timeout to complete
+// before the database is closed.
+func (a *app) run(l net.Listener, stop <-chan os.Signal) error {
+
 	s := &http.Server{
-		Handler: a.router,
-		Addr:    fmt.Sprintf(":%d", a.config.Port),
+		Handler:           a.router,
+		ReadHeaderTimeout: a.config.ReadHeaderTimeout.Duration,
+		IdleTimeout:       a.config.IdleTimeout.Duration,
+	}
+
+	a.stopWebhooks = a.webhooks.Start()
+
+	errc := make(chan error, 1)
+	go func() {
+		if a.config.Tls {
+			errc <- s.ServeTLS(l, a.config.CertFile, a.config.KeyFile)
+		} else {
+			errc <- s.Serve(l)
+		}
+	}()
+
+	select {
+	case err := <-errc:
+		a.close()
+		return err
+	case sig := <-stop:
+		a.logger.Info("shutting down", "signal", sig.String())
+	}
+
+	// Fail readiness first so that load balancers stop sending new requests
+	atomic.StoreInt32(&a.shuttingDown, 1)
+	time.Sleep(a.config.ShutdownDelay.Duration)
+
+	timeout := a.config.ShutdownTimeout.Duration
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), timeout)
+	defer cancel()
+
+	// Handlers still running after the timeout must not outlive the database
+	err := s.Shutdown(ctx)
+	if err != nil {
+		s.Close()
+	}
+	cerr := a.close()
+	if err != nil {
+		return err
 	}
 
-	if a.config.Tls {
-		return s.ListenAndServeTLS(a.config.CertFile, a.config.KeyFile)
-	} else {
-		return s.ListenAndServe()
+	return cerr
+}
+
+// close stops the delivery of webhooks, which use the database, before
+// closing the database
+func (a *app) close() error {
+
+	if a.stopWebhooks != nil {
+		a.stopWebhooks()
+	}
+
+	if a.db == nil {
+		return nil
 	}
+
+	return a.db.Close()
 }
 
 func ContentMatcher(r *http.Request, m *mux.RouteMatch) bool {
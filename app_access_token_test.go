@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ikmski/git-lfs3/adapter"
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+func createTestAccessToken(t *testing.T, user string, ttl time.Duration, scopes ...entity.Scope) *usecase.AccessTokenResult {
+
+	result, err := testAccessTokenSvc.Create(&usecase.AccessTokenRequest{
+		User:   user,
+		Name:   "test",
+		Scopes: scopes,
+		TTL:    ttl,
+	})
+	if err != nil {
+		t.Fatalf("expected token, got: %s", err)
+	}
+
+	return result
+}
+
+func doRequest(t *testing.T, req *http.Request) *http.Response {
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+
+	return res
+}
+
+func TestAccessTokenAsPassword(t *testing.T) {
+
+	token := createTestAccessToken(t, testUser1, 0, entity.ScopeRead)
+
+	req := newBatchRequest(t, "download", testContentOid)
+	req.SetBasicAuth(testUser1, token.Value)
+	if res := doRequest(t, req); res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+
+	req = newBatchRequest(t, "upload", testNonExistingOid)
+	req.SetBasicAuth(testUser1, token.Value)
+	if res := doRequest(t, req); res.StatusCode != 403 {
+		t.Fatalf("expected status 403 without write scope, got %d", res.StatusCode)
+	}
+
+	req = newBatchRequest(t, "download", testContentOid)
+	req.SetBasicAuth(testUser2, token.Value)
+	if res := doRequest(t, req); res.StatusCode != 401 {
+		t.Fatalf("expected status 401 for the token of another user, got %d", res.StatusCode)
+	}
+
+	tokens, err := testAccessTokenSvc.Tokens(testUser1)
+	if err != nil {
+		t.Fatalf("expected tokens, got: %s", err)
+	}
+
+	for _, tok := range tokens {
+		if tok.ID == token.Token.ID && tok.LastUsedAt == 0 {
+			t.Errorf("expected last use to be recorded")
+		}
+	}
+}
+
+func TestAccessTokenWriteScope(t *testing.T) {
+
+	token := createTestAccessToken(t, testUser1, 0, entity.ScopeWrite)
+
+	req := newBatchRequest(t, "upload", testNonExistingOid)
+	req.SetBasicAuth(testUser1, token.Value)
+	if res := doRequest(t, req); res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+
+	path := fmt.Sprintf("%s/%s/%s/locks", lfsServer.URL, testUser1, testRepo)
+	req, err := http.NewRequest("GET", path, nil)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	req.SetBasicAuth(testUser1, token.Value)
+	req.Header.Set("Accept", "application/vnd.git-lfs+json")
+	if res := doRequest(t, req); res.StatusCode != 403 {
+		t.Fatalf("expected status 403 without lock scope, got %d", res.StatusCode)
+	}
+}
+
+func TestAccessTokenExpired(t *testing.T) {
+
+	token := createTestAccessToken(t, testUser1, time.Nanosecond, entity.ScopeRead)
+
+	req := newBatchRequest(t, "download", testContentOid)
+	req.SetBasicAuth(testUser1, token.Value)
+	if res := doRequest(t, req); res.StatusCode != 401 {
+		t.Fatalf("expected status 401, got %d", res.StatusCode)
+	}
+}
+
+func TestAccessTokenAdminAPI(t *testing.T) {
+
+	path := fmt.Sprintf("%s/admin/users/%s/tokens", lfsServer.URL, testUser2)
+	body, _ := json.Marshal(&adapter.AccessTokenRequest{
+		Name:      "laptop",
+		Scopes:    []string{"read"},
+		ExpiresIn: 3600,
+	})
+
+	// testUser1 is an admin
+	req, _ := http.NewRequest("POST", path, bytes.NewReader(body))
+	req.SetBasicAuth(testUser1, testPass1)
+	res := doRequest(t, req)
+	if res.StatusCode != 201 {
+		t.Fatalf("expected status 201, got %d", res.StatusCode)
+	}
+
+	var created adapter.AccessTokenResponse
+	if err := json.NewDecoder(res.Body).Decode(&created); err != nil {
+		t.Fatalf("expected token response, got: %s", err)
+	}
+	if created.Token == "" || created.ExpiresAt == nil {
+		t.Fatalf("expected token value and expiry, got: %+v", created)
+	}
+
+	// tokens cannot be used to manage tokens
+	req, _ = http.NewRequest("GET", path, nil)
+	req.SetBasicAuth(testUser2, created.Token)
+	if res := doRequest(t, req); res.StatusCode != 403 {
+		t.Fatalf("expected status 403 with a token, got %d", res.StatusCode)
+	}
+
+	// users list their own tokens, without values
+	req, _ = http.NewRequest("GET", path, nil)
+	req.SetBasicAuth(testUser2, testPass2)
+	res = doRequest(t, req)
+	if res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+
+	var list adapter.AccessTokenListResponse
+	if err := json.NewDecoder(res.Body).Decode(&list); err != nil {
+		t.Fatalf("expected token list, got: %s", err)
+	}
+	if len(list.Tokens) != 1 || list.Tokens[0].ID != created.ID || list.Tokens[0].Token != "" {
+		t.Fatalf("expected the created token without value, got: %+v", list.Tokens)
+	}
+
+	// but not those of other users
+	req, _ = http.NewRequest("GET", fmt.Sprintf("%s/admin/users/%s/tokens", lfsServer.URL, testUser1), nil)
+	req.SetBasicAuth(testUser2, testPass2)
+	if res := doRequest(t, req); res.StatusCode != 403 {
+		t.Fatalf("expected status 403, got %d", res.StatusCode)
+	}
+
+	req, _ = http.NewRequest("DELETE", path+"/"+created.ID, nil)
+	req.SetBasicAuth(testUser2, testPass2)
+	if res := doRequest(t, req); res.StatusCode != 204 {
+		t.Fatalf("expected status 204, got %d", res.StatusCode)
+	}
+
+	req, _ = http.NewRequest("DELETE", path+"/"+created.ID, nil)
+	req.SetBasicAuth(testUser2, testPass2)
+	if res := doRequest(t, req); res.StatusCode != 404 {
+		t.Fatalf("expected status 404 for a revoked token, got %d", res.StatusCode)
+	}
+
+	req = newBatchRequest(t, "download", testContentOid)
+	req.SetBasicAuth(testUser2, created.Token)
+	if res := doRequest(t, req); res.StatusCode != 401 {
+		t.Fatalf("expected status 401 for a revoked token, got %d", res.StatusCode)
+	}
+
+	// the admin created the token, and its user revoked it
+	for actor, action := range map[string]string{testUser1: entity.AuditTokenCreate, testUser2: entity.AuditTokenRevoke} {
+		var found bool
+		for _, e := range queryAudit(t, url.Values{"actor": {actor}}) {
+			if e.Details["id"] == created.ID {
+				found = e.Action == action && e.Target == testUser2
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected %s of the token by %s", action, actor)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ikmski/git-lfs3/adapter"
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+func queryAudit(t *testing.T, query url.Values) []*adapter.AuditEventResponse {
+
+	res := doAdminRequest(t, testUser1, testPass1, "GET", "/audit?"+query.Encode())
+	if res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+
+	var list adapter.AuditEventListResponse
+	if err := json.NewDecoder(res.Body).Decode(&list); err != nil {
+		t.Fatalf("expected audit events, got: %s", err)
+	}
+
+	return list.Events
+}
+
+func unlock(t *testing.T, id string, force bool) {
+
+	url := fmt.Sprintf("%s/%s/%s/locks/%s/unlock", lfsServer.URL, testUser1, testRepo, id)
+	req, err := http.NewRequest("POST", url, strings.NewReader(fmt.Sprintf(`{"force": %t}`, force)))
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	req.SetBasicAuth(testUser1, testPass1)
+	req.Header.Set("Accept", metaMediaType)
+	req.Header.Set("Content-Type", metaMediaType)
+
+	if res := doRequest(t, req); res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+}
+
+func TestAuditLocks(t *testing.T) {
+
+	since := time.Now().Add(-time.Second).UTC().Format(time.RFC3339)
+
+	lock, err := addLock(testUser1, "TestAuditLocks")
+	if err != nil {
+		t.Fatalf("create lock error: %s", err)
+	}
+	unlock(t, lock.ID, false)
+
+	theirs := entity.Lock{ID: "audit-lock", Path: "TestAuditLocks/theirs", Owner: entity.User{Name: testUser2}, LockedAt: time.Now().Unix()}
+	if err := testLockRepo.Add(ownedRepo(testRepo), theirs); err != nil {
+		t.Fatalf("error seeding lock store: %s", err)
+	}
+	unlock(t, theirs.ID, true)
+
+	var actions []string
+	for _, e := range queryAudit(t, url.Values{"actor": {testUser1}, "repo": {ownedRepo(testRepo)}, "since": {since}}) {
+		if strings.HasPrefix(e.Target, "TestAuditLocks") {
+			actions = append(actions, e.Action)
+		}
+	}
+
+	// the most recent event first
+	expected := []string{entity.AuditLockForceRelease, entity.AuditLockRelease, entity.AuditLockCreate}
+	if strings.Join(actions, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, actions)
+	}
+}
+
+func TestAuditFailedLogin(t *testing.T) {
+
+	intruder := "audit-intruder"
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/%s/%s/locks", lfsServer.URL, testUser1, testRepo), nil)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	req.SetBasicAuth(intruder, "guess")
+	req.Header.Set("Accept", metaMediaType)
+
+	if res := doRequest(t, req); res.StatusCode != 401 {
+		t.Fatalf("expected status 401, got %d", res.StatusCode)
+	}
+
+	events := queryAudit(t, url.Values{"actor": {intruder}})
+	if len(events) != 1 || events[0].Action != entity.AuditLoginFailed || events[0].Details["method"] != "password" {
+		t.Errorf("expected a failed login, got: %+v", events)
+	}
+
+	// events before the time range are not returned
+	until := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	if events := queryAudit(t, url.Values{"actor": {intruder}, "until": {until}}); len(events) != 0 {
+		t.Errorf("expected no events, got: %+v", events)
+	}
+}
+
+func TestAuditQueryForbidden(t *testing.T) {
+
+	if res := doAdminRequest(t, testUser2, testPass2, "GET", "/audit"); res.StatusCode != 403 {
+		t.Errorf("expected status 403, got %d", res.StatusCode)
+	}
+
+	for _, query := range []string{"?since=yesterday", "?until=1500000000", "?limit=-1", "?cursor=invalid"} {
+		if res := doAdminRequest(t, testUser1, testPass1, "GET", "/audit"+query); res.StatusCode != 422 {
+			t.Errorf("expected status 422 for %s, got %d", query, res.StatusCode)
+		}
+	}
+}
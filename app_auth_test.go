@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ikmski/git-lfs3/adapter"
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+func newBatchRequest(t *testing.T, operation string, oid string) *http.Request {
+
+	path := fmt.Sprintf("%s/%s/%s/objects/batch", lfsServer.URL, testUser1, testRepo)
+
+	requestData := &adapter.BatchRequest{
+		Operation: operation,
+		Objects: []*adapter.ObjectRequest{
+			{Oid: oid, Size: testContentSize},
+		},
+	}
+
+	requestBody, _ := json.Marshal(requestData)
+
+	req, err := http.NewRequest("POST", path, bytes.NewReader(requestBody))
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	req.Header.Set("Accept", "application/vnd.git-lfs+json")
+	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+
+	return req
+}
+
+func TestAuthRequired(t *testing.T) {
+
+	req := newBatchRequest(t, "download", testContentOid)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+
+	if res.StatusCode != 401 {
+		t.Fatalf("expected status 401, got %d", res.StatusCode)
+	}
+
+	if res.Header.Get("LFS-Authenticate") == "" {
+		t.Errorf("expected LFS-Authenticate header")
+	}
+}
+
+func TestAuthInvalidPassword(t *testing.T) {
+
+	req := newBatchRequest(t, "download", testContentOid)
+	req.SetBasicAuth(testUser1, testPass2)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+
+	if res.StatusCode != 401 {
+		t.Fatalf("expected status 401, got %d", res.StatusCode)
+	}
+}
+
+func TestReadOnlyUserCanDownload(t *testing.T) {
+
+	req := newBatchRequest(t, "download", testContentOid)
+	req.SetBasicAuth(testUser2, testPass2)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+
+	if res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+}
+
+func TestReadOnlyUserCannotUpload(t *testing.T) {
+
+	req := newBatchRequest(t, "upload", testNonExistingOid)
+	req.SetBasicAuth(testUser2, testPass2)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+
+	if res.StatusCode != 403 {
+		t.Fatalf("expected status 403, got %d", res.StatusCode)
+	}
+
+	var e adapter.ErrorResponse
+	if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
+		t.Fatalf("expected error response, got: %s", err)
+	}
+
+	if e.Message == "" {
+		t.Errorf("expected error message")
+	}
+}
+
+func TestReadOnlyUserCannotLock(t *testing.T) {
+
+	path := fmt.Sprintf("%s/%s/%s/locks", lfsServer.URL, testUser1, testRepo)
+	req, err := http.NewRequest("POST", path, bytes.NewBufferString(`{"path": "read/only/path"}`))
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	req.SetBasicAuth(testUser2, testPass2)
+	req.Header.Set("Accept", "application/vnd.git-lfs+json")
+	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+
+	if res.StatusCode != 403 {
+		t.Fatalf("expected status 403, got %d", res.StatusCode)
+	}
+}
+
+func TestUnknownRepositoryForbidden(t *testing.T) {
+
+	path := fmt.Sprintf("%s/%s/%s/locks", lfsServer.URL, testUser2, testRepo)
+	req, err := http.NewRequest("GET", path, nil)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	req.SetBasicAuth(testUser1, testPass1)
+	req.Header.Set("Accept", "application/vnd.git-lfs+json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+
+	if res.StatusCode != 403 {
+		t.Fatalf("expected status 403, got %d", res.StatusCode)
+	}
+}
+
+func TestSameRepoNameOfAnotherOwner(t *testing.T) {
+
+	// testUser2 administers a repo named like testUser1's
+	if err := testPermRepo.Grant(testUser2, testUser2+"/"+testRepo, entity.RoleAdmin); err != nil {
+		t.Fatalf("error granting permission: %s", err)
+	}
+
+	get := func(path string) *http.Response {
+		req, err := http.NewRequest("GET", fmt.Sprintf("%s/%s/%s%s", lfsServer.URL, testUser2, testRepo, path), nil)
+		if err != nil {
+			t.Fatalf("request error: %s", err)
+		}
+		req.SetBasicAuth(testUser2, testPass2)
+		req.Header.Set("Accept", metaMediaType)
+		return doRequest(t, req)
+	}
+
+	res := get("/locks")
+	var locks adapter.LockListResponse
+	if err := json.NewDecoder(res.Body).Decode(&locks); err != nil || len(locks.Locks) != 0 {
+		t.Errorf("expected no locks of %s, got: %+v, %v", ownedRepo(testRepo), locks, err)
+	}
+
+	res = get("/objects")
+	var objs adapter.ObjectListResponse
+	if err := json.NewDecoder(res.Body).Decode(&objs); err != nil || len(objs.Objects) != 0 {
+		t.Errorf("expected no objects of %s, got: %+v, %v", ownedRepo(testRepo), objs, err)
+	}
+
+	res = get("/objects/" + testContentOid + "/info")
+	if res.StatusCode != 404 {
+		t.Errorf("expected status 404 for an object of %s, got %d", ownedRepo(testRepo), res.StatusCode)
+	}
+}
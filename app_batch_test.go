@@ -6,9 +6,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ikmski/git-lfs3/adapter"
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
 )
 
 func TestBatchDownload(t *testing.T) {
@@ -33,6 +37,7 @@ func TestBatchDownload(t *testing.T) {
 	if err != nil {
 		t.Fatalf("request error: %s", err)
 	}
+	req.SetBasicAuth(testUser1, testPass1)
 	req.Header.Set("Accept", "application/vnd.git-lfs+json")
 	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
 
@@ -74,6 +79,58 @@ func TestBatchDownload(t *testing.T) {
 
 }
 
+func TestBatchDownloadUnknown(t *testing.T) {
+
+	oid := multipartOid("never uploaded")
+	body := fmt.Sprintf(`{"operation": "download", "objects": [{"oid": "%s", "size": 14}]}`, oid)
+
+	path := fmt.Sprintf("%s/%s/%s/objects/batch", lfsServer.URL, testUser1, testRepo)
+	req, err := http.NewRequest("POST", path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	req.SetBasicAuth(testUser1, testPass1)
+	req.Header.Set("Accept", metaMediaType)
+	req.Header.Set("Content-Type", metaMediaType)
+
+	var responseData adapter.BatchResponse
+	if err := json.NewDecoder(doRequest(t, req).Body).Decode(&responseData); err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	obj := responseData.Objects[0]
+	if obj.Error == nil || obj.Error.Code != 404 || len(obj.Actions) != 0 {
+		t.Errorf("expected 404 for an unknown object, got: %+v", obj)
+	}
+
+	if _, err := testMetaDataRepo.Get(oid); err == nil {
+		t.Errorf("expected a download not to register the object")
+	}
+}
+
+func TestBatchUnverifiedContent(t *testing.T) {
+
+	// uploaded directly to storage, but never verified
+	content := "unverified content"
+	meta := &entity.MetaData{
+		Oid:      multipartOid(content),
+		Size:     int64(len(content)),
+		Uploader: entity.User{Name: testUser1},
+		Repos:    []string{ownedRepo(testRepo)},
+	}
+	if _, err := testMetaDataRepo.Put(meta); err != nil {
+		t.Fatalf("error seeding meta store: %s", err)
+	}
+	if err := testContentRepo.Put(meta, strings.NewReader(content)); err != nil {
+		t.Fatalf("error seeding content store: %s", err)
+	}
+
+	res := doBatchUpload(t, testRepo, &adapter.ObjectRequest{Oid: meta.Oid, Size: meta.Size})
+	if obj := res.Objects[0]; obj.Error != nil || obj.Actions["upload"] == nil {
+		t.Errorf("expected unverified content to be uploaded again, got: %+v", obj)
+	}
+}
+
 func TestBatchUpload(t *testing.T) {
 
 	path := fmt.Sprintf("%s/%s/%s/objects/batch", lfsServer.URL, testUser1, testRepo)
@@ -96,6 +153,7 @@ func TestBatchUpload(t *testing.T) {
 	if err != nil {
 		t.Fatalf("request error: %s", err)
 	}
+	req.SetBasicAuth(testUser1, testPass1)
 	req.Header.Set("Accept", "application/vnd.git-lfs+json")
 	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
 
@@ -129,9 +187,202 @@ func TestBatchUpload(t *testing.T) {
 	if responseData.Objects[0].Size != testContentSize {
 		t.Errorf("got %v\nwant %v", responseData.Objects[0].Size, testContentSize)
 	}
-	_, ok := responseData.Objects[0].Actions["upload"]
+	upload, ok := responseData.Objects[0].Actions["upload"]
 	if !ok {
-		t.Errorf("got %v\nwant %v", ok, true)
+		t.Fatalf("got %v\nwant %v", ok, true)
 	}
 
+	href := fmt.Sprintf("%s/%s/%s/objects/%s", lfsServer.URL, testUser1, testRepo, testNonExistingOid)
+	if upload.Href != href {
+		t.Errorf("got %v\nwant %v", upload.Href, href)
+	}
+
+}
+
+func TestBatchInvalidOid(t *testing.T) {
+
+	res := doBatchUpload(t, testRepo,
+		&adapter.ObjectRequest{Oid: testContentOid, Size: testContentSize},
+		&adapter.ObjectRequest{Oid: strings.ToUpper(testNonExistingOid), Size: testContentSize},
+		&adapter.ObjectRequest{Oid: "../../etc/passwd", Size: testContentSize},
+		&adapter.ObjectRequest{Oid: testContentOid[:63], Size: testContentSize},
+	)
+
+	if res.HashAlgo != "sha256" {
+		t.Errorf("expected hash_algo sha256, got %q", res.HashAlgo)
+	}
+
+	if obj := res.Objects[0]; obj.Error != nil {
+		t.Errorf("expected no error for a valid OID, got: %+v", obj.Error)
+	}
+
+	for _, obj := range res.Objects[1:] {
+		if obj.Error == nil || obj.Error.Code != 422 || len(obj.Actions) != 0 {
+			t.Errorf("expected 422 for OID %q, got: %+v", obj.Oid, obj.Error)
+		}
+	}
+}
+
+func TestBatchHashAlgo(t *testing.T) {
+
+	path := fmt.Sprintf("%s/%s/%s/objects/batch", lfsServer.URL, testUser1, testRepo)
+
+	for algo, expected := range map[string]int{"sha256": 200, "sha512": 409} {
+
+		requestBody, _ := json.Marshal(&adapter.BatchRequest{
+			Operation: "download",
+			HashAlgo:  algo,
+			Objects:   []*adapter.ObjectRequest{{Oid: testContentOid, Size: testContentSize}},
+		})
+
+		req, err := http.NewRequest("POST", path, bytes.NewReader(requestBody))
+		if err != nil {
+			t.Fatalf("request error: %s", err)
+		}
+		req.SetBasicAuth(testUser1, testPass1)
+		req.Header.Set("Accept", "application/vnd.git-lfs+json")
+		req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request error: %s", err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode != expected {
+			t.Errorf("expected status %d for %s, got %d", expected, algo, res.StatusCode)
+		}
+	}
+}
+
+func TestBatchOrder(t *testing.T) {
+
+	var objs []*adapter.ObjectRequest
+	for i := 0; i < 50; i++ {
+		switch i % 3 {
+		case 0:
+			objs = append(objs, &adapter.ObjectRequest{Oid: testContentOid, Size: testContentSize})
+		case 1:
+			objs = append(objs, &adapter.ObjectRequest{Oid: fmt.Sprintf("%064x", 0xb000+i), Size: testContentSize})
+		default:
+			objs = append(objs, &adapter.ObjectRequest{Oid: fmt.Sprintf("invalid-%d", i), Size: testContentSize})
+		}
+	}
+
+	res := doBatchUpload(t, testRepo, objs...)
+
+	for i, obj := range res.Objects {
+		if obj.Oid != objs[i].Oid {
+			t.Fatalf("expected object %d to be %s, got %s", i, objs[i].Oid, obj.Oid)
+		}
+
+		switch i % 3 {
+		case 0:
+			if obj.Actions["download"] == nil {
+				t.Errorf("expected download action for stored object %d", i)
+			}
+		case 1:
+			if obj.Actions["upload"] == nil {
+				t.Errorf("expected upload action for new object %d", i)
+			}
+		default:
+			if obj.Error == nil || obj.Error.Code != 422 {
+				t.Errorf("expected 422 for invalid object %d, got: %+v", i, obj.Error)
+			}
+		}
+	}
+}
+
+// latencyContentRepository delays existence checks like requests to S3
+type latencyContentRepository struct {
+	usecase.ContentRepository
+	latency time.Duration
+}
+
+func (r *latencyContentRepository) Exists(meta *entity.MetaData) bool {
+	time.Sleep(r.latency)
+	return r.ContentRepository.Exists(meta)
+}
+
+func BenchmarkBatch(b *testing.B) {
+
+	contentRepo := &latencyContentRepository{ContentRepository: testContentRepo, latency: 5 * time.Millisecond}
+	transfers := usecase.NewTransferAdapters(adapter.NewBasicTransferAdapter())
+
+	var objs []*usecase.ObjectRequest
+	for i := 0; i < 200; i++ {
+		objs = append(objs, &usecase.ObjectRequest{Oid: testContentOid, Size: testContentSize})
+	}
+
+	req := &usecase.BatchRequest{
+		Operation: "download",
+		User:      testUser1,
+		Repo:      ownedRepo(testRepo),
+		Objects:   objs,
+	}
+
+	for _, concurrency := range []int{1, 16, 64} {
+		b.Run(fmt.Sprintf("concurrency-%d", concurrency), func(b *testing.B) {
+
+			s := usecase.NewBatchService(testMetaDataRepo, contentRepo, nil, nil, nil, nil, transfers, concurrency, nil)
+
+			for i := 0; i < b.N; i++ {
+				_, err := s.Batch(req)
+				if err != nil {
+					b.Fatalf("batch error: %s", err)
+				}
+			}
+		})
+	}
+}
+
+func postBatch(t *testing.T, body string) int {
+
+	path := fmt.Sprintf("%s/%s/%s/objects/batch", lfsServer.URL, testUser1, testRepo)
+
+	req, err := http.NewRequest("POST", path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	req.SetBasicAuth(testUser1, testPass1)
+	req.Header.Set("Accept", "application/vnd.git-lfs+json")
+	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	res.Body.Close()
+
+	return res.StatusCode
+}
+
+func TestBatchTooManyObjects(t *testing.T) {
+
+	obj := fmt.Sprintf(`{"oid":%q,"size":%d}`, testContentOid, testContentSize)
+
+	objs := strings.Repeat(obj+",", testBatchMaxObjects-1) + obj
+	if status := postBatch(t, `{"operation":"download","objects":[`+objs+`]}`); status != 200 {
+		t.Errorf("expected status 200 for %d objects, got %d", testBatchMaxObjects, status)
+	}
+
+	// the objects are decoded one at a time, so a request that is cut off
+	// after the maximum fails as too large instead of malformed
+	objs = strings.Repeat(obj+",", testBatchMaxObjects+1)
+	if status := postBatch(t, `{"operation":"download","objects":[`+objs); status != 413 {
+		t.Errorf("expected status 413 for %d objects, got %d", testBatchMaxObjects+1, status)
+	}
+}
+
+func TestBatchMalformed(t *testing.T) {
+
+	for _, body := range []string{
+		`{"operation":"download","objects":[{"oid":`,
+		`{"operation":"download","objects":{}}`,
+		`["download"]`,
+	} {
+		if status := postBatch(t, body); status != 422 {
+			t.Errorf("expected status 422 for %s, got %d", body, status)
+		}
+	}
 }
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ikmski/git-lfs3/adapter"
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+func doAdminRequest(t *testing.T, user string, pass string, method string, path string) *http.Response {
+
+	req, err := http.NewRequest(method, fmt.Sprintf("%s/admin%s", lfsServer.URL, path), nil)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	req.SetBasicAuth(user, pass)
+
+	return doRequest(t, req)
+}
+
+func TestDeleteObject(t *testing.T) {
+
+	content := "leaked secret"
+	meta := &entity.MetaData{
+		Oid:      multipartOid(content),
+		Size:     int64(len(content)),
+		Uploader: entity.User{Name: testUser1},
+		Repos:    []string{ownedRepo(testRepo), ownedRepo(testPolicyRepo)},
+		Complete: true,
+	}
+	if _, err := testMetaDataRepo.Put(meta); err != nil {
+		t.Fatalf("error seeding meta store: %s", err)
+	}
+	if err := testContentRepo.Put(meta, strings.NewReader(content)); err != nil {
+		t.Fatalf("error seeding content store: %s", err)
+	}
+
+	path := "/objects/" + meta.Oid + "?reason=" + url.QueryEscape("pushed by mistake")
+
+	// testUser2 is not an admin
+	if res := doAdminRequest(t, testUser2, testPass2, "DELETE", path); res.StatusCode != 403 {
+		t.Fatalf("expected status 403, got %d", res.StatusCode)
+	}
+
+	if res := doAdminRequest(t, testUser1, testPass1, "DELETE", "/objects/"+meta.Oid); res.StatusCode != 422 {
+		t.Fatalf("expected status 422 without a reason, got %d", res.StatusCode)
+	}
+
+	if res := doAdminRequest(t, testUser1, testPass1, "DELETE", path); res.StatusCode != 204 {
+		t.Fatalf("expected status 204, got %d", res.StatusCode)
+	}
+
+	if testContentRepo.Exists(meta) {
+		t.Errorf("expected content to be deleted")
+	}
+	if _, err := testMetaDataRepo.Get(meta.Oid); err == nil {
+		t.Errorf("expected meta to be deleted")
+	}
+
+	if res := doAdminRequest(t, testUser1, testPass1, "DELETE", path); res.StatusCode != 404 {
+		t.Fatalf("expected status 404 for a deleted object, got %d", res.StatusCode)
+	}
+
+	// the deleted object cannot be uploaded again
+	batch := doBatchUpload(t, testRepo, &adapter.ObjectRequest{Oid: meta.Oid, Size: meta.Size})
+	if obj := batch.Objects[0]; obj.Error == nil || obj.Error.Code != 410 {
+		t.Fatalf("expected upload of a tombstoned object to fail with 410, got: %+v", obj)
+	}
+
+	res := doAdminRequest(t, testUser1, testPass1, "GET", "/tombstones")
+	var tombstones adapter.TombstoneListResponse
+	if err := json.NewDecoder(res.Body).Decode(&tombstones); err != nil {
+		t.Fatalf("expected tombstone list, got: %s", err)
+	}
+	if len(tombstones.Tombstones) != 1 || tombstones.Tombstones[0].DeletedBy != testUser1 || tombstones.Tombstones[0].Reason != "pushed by mistake" {
+		t.Fatalf("expected the tombstone of the deleted object, got: %+v", tombstones.Tombstones)
+	}
+
+	clearPath := "/tombstones/" + meta.Oid + "?reason=cleared"
+	if res := doAdminRequest(t, testUser1, testPass1, "DELETE", clearPath); res.StatusCode != 204 {
+		t.Fatalf("expected status 204, got %d", res.StatusCode)
+	}
+	if res := doAdminRequest(t, testUser1, testPass1, "DELETE", clearPath); res.StatusCode != 404 {
+		t.Fatalf("expected status 404 for a cleared tombstone, got %d", res.StatusCode)
+	}
+
+	batch = doBatchUpload(t, testRepo, &adapter.ObjectRequest{Oid: meta.Oid, Size: meta.Size})
+	if obj := batch.Objects[0]; obj.Error != nil || obj.Actions["upload"] == nil {
+		t.Fatalf("expected upload once the tombstone is cleared, got: %+v", obj)
+	}
+
+	res = doAdminRequest(t, testUser1, testPass1, "GET", "/deletions")
+	var deletions adapter.ObjectDeletionListResponse
+	if err := json.NewDecoder(res.Body).Decode(&deletions); err != nil {
+		t.Fatalf("expected deletion list, got: %s", err)
+	}
+
+	var actions []string
+	for _, d := range deletions.Deletions {
+		if d.Oid == meta.Oid {
+			actions = append(actions, d.Action)
+		}
+	}
+	if strings.Join(actions, ",") != "delete,clear" {
+		t.Fatalf("expected the deletion and the clearing to be recorded, got: %v", actions)
+	}
+}
+
+func TestUploadDeletedObject(t *testing.T) {
+
+	// registered in a batch before an admin deleted the object
+	content := "deleted while uploading"
+	meta := &entity.MetaData{
+		Oid:      multipartOid(content),
+		Size:     int64(len(content)),
+		Uploader: entity.User{Name: testUser1},
+		Repos:    []string{ownedRepo(testRepo)},
+	}
+	if _, err := testMetaDataRepo.Put(meta); err != nil {
+		t.Fatalf("error seeding meta store: %s", err)
+	}
+	deletion := &entity.ObjectDeletion{Action: entity.DeletionActionDelete, Oid: meta.Oid, User: testUser1, Reason: "test"}
+	if err := testTombstoneRepo.Add(deletion); err != nil {
+		t.Fatalf("error seeding tombstones: %s", err)
+	}
+
+	res := doRequest(t, newUploadRequest(t, meta.Oid, strings.NewReader(content)))
+	if res.StatusCode != 410 {
+		t.Errorf("expected status 410 for a deleted object, got %d", res.StatusCode)
+	}
+
+	if testContentRepo.Exists(meta) {
+		t.Errorf("expected no content stored for a deleted object")
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ikmski/git-lfs3/adapter"
+)
+
+func TestHealthz(t *testing.T) {
+
+	res, err := http.Get(fmt.Sprintf("%s/healthz", lfsServer.URL))
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+
+	res, err := http.Get(fmt.Sprintf("%s/readyz", lfsServer.URL))
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+
+	var health adapter.HealthResponse
+	if err := json.NewDecoder(res.Body).Decode(&health); err != nil {
+		t.Fatalf("expected health response, got error: %s", err)
+	}
+
+	if health.Status != "ok" {
+		t.Errorf("expected status ok, got: %s", health.Status)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ikmski/git-lfs3/adapter"
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+func postImport(t *testing.T, user string, pass string, repo string, body *adapter.ImportRequest) *http.Response {
+
+	data, _ := json.Marshal(body)
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/%s/%s/objects/import", lfsServer.URL, testUser1, repo), bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	req.SetBasicAuth(user, pass)
+	req.Header.Set("Accept", metaMediaType)
+	req.Header.Set("Content-Type", metaMediaType)
+
+	return doRequest(t, req)
+}
+
+func decodeImportResponse(t *testing.T, res *http.Response) map[string]*adapter.ImportObject {
+
+	if res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+
+	var body adapter.ImportResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("expected import response, got: %s", err)
+	}
+
+	objs := make(map[string]*adapter.ImportObject)
+	for _, o := range body.Objects {
+		objs[o.Oid] = o
+	}
+
+	return objs
+}
+
+func TestImport(t *testing.T) {
+
+	source := "import-source"
+	target := "import-target"
+
+	if err := testPermRepo.Grant(testUser1, testUser1+"/"+source, entity.RoleRead); err != nil {
+		t.Fatalf("error granting permission: %s", err)
+	}
+	if err := testPermRepo.Grant(testUser1, testUser1+"/"+target, entity.RoleAdmin); err != nil {
+		t.Fatalf("error granting permission: %s", err)
+	}
+
+	stored := "imported content"
+	storedMeta := &entity.MetaData{
+		Oid:      multipartOid(stored),
+		Size:     int64(len(stored)),
+		Uploader: entity.User{Name: testUser1},
+		Repos:    []string{ownedRepo(source)},
+		Complete: true,
+	}
+	missingMeta := &entity.MetaData{
+		Oid:      multipartOid("never uploaded"),
+		Size:     14,
+		Uploader: entity.User{Name: testUser1},
+		Repos:    []string{ownedRepo(source)},
+	}
+
+	for _, meta := range []*entity.MetaData{storedMeta, missingMeta} {
+		if _, err := testMetaDataRepo.Put(meta); err != nil {
+			t.Fatalf("error seeding meta store: %s", err)
+		}
+	}
+	if err := testContentRepo.Put(storedMeta, strings.NewReader(stored)); err != nil {
+		t.Fatalf("error seeding content store: %s", err)
+	}
+
+	objs := decodeImportResponse(t, postImport(t, testUser1, testPass1, target, &adapter.ImportRequest{
+		Source: testUser1 + "/" + source,
+		All:    true,
+	}))
+
+	if o := objs[storedMeta.Oid]; o == nil || !o.Imported || o.Error != nil || o.Size != storedMeta.Size {
+		t.Errorf("expected stored object to be imported, got: %+v", o)
+	}
+	if o := objs[missingMeta.Oid]; o == nil || o.Imported || o.Error == nil || o.Error.Code != 404 {
+		t.Errorf("expected object without content to fail with 404, got: %+v", o)
+	}
+
+	meta, err := testMetaDataRepo.Get(storedMeta.Oid)
+	if err != nil || !containsString(meta.Repos, ownedRepo(target)) {
+		t.Errorf("expected object to be referenced by the target repo, got: %+v, %v", meta, err)
+	}
+
+	objs = decodeImportResponse(t, postImport(t, testUser1, testPass1, target, &adapter.ImportRequest{
+		Source: testUser1 + "/" + source,
+		Oids:   []string{storedMeta.Oid, testContentOid, "invalid"},
+	}))
+
+	if o := objs[storedMeta.Oid]; o == nil || o.Imported || o.Error != nil {
+		t.Errorf("expected object in the repo already to be left as it is, got: %+v", o)
+	}
+	if o := objs[testContentOid]; o == nil || o.Error == nil || o.Error.Code != 404 {
+		t.Errorf("expected object not in the source repo to fail with 404, got: %+v", o)
+	}
+	if o := objs["invalid"]; o == nil || o.Error == nil || o.Error.Code != 422 {
+		t.Errorf("expected invalid object ID to fail with 422, got: %+v", o)
+	}
+}
+
+func TestImportRequest(t *testing.T) {
+
+	// testUser2 has read access to repo only
+	res := postImport(t, testUser2, testPass2, testRepo, &adapter.ImportRequest{Source: testUser1 + "/" + testRepo, All: true})
+	if res.StatusCode != 403 {
+		t.Errorf("expected status 403 without admin access, got %d", res.StatusCode)
+	}
+
+	res = postImport(t, testUser1, testPass1, testRepo, &adapter.ImportRequest{Source: testUser2 + "/other", All: true})
+	if res.StatusCode != 403 {
+		t.Errorf("expected status 403 without read access to the source, got %d", res.StatusCode)
+	}
+
+	for _, body := range []*adapter.ImportRequest{
+		{Source: testRepo, All: true},
+		{Source: testUser1 + "/" + testRepo},
+		{Source: testUser1 + "/" + testRepo, All: true, Oids: []string{testContentOid}},
+	} {
+		res = postImport(t, testUser1, testPass1, testRepo, body)
+		if res.StatusCode != 422 {
+			t.Errorf("expected status 422 for %+v, got %d", body, res.StatusCode)
+		}
+	}
+}
+
+func containsString(values []string, value string) bool {
+
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
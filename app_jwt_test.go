@@ -0,0 +1,144 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ikmski/git-lfs3/usecase"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+const (
+	testJWKSFile = "lfs-test-jwks.json"
+	testIssuer   = "https://idp.example.com"
+	testAudience = "git-lfs3"
+	testGroup    = "designers"
+	testOIDCUser = "carol"
+)
+
+var testSigner jose.Signer
+
+// setupBearerVerifier creates a signing key for test tokens and a verifier
+// trusting it. Members of testGroup get write access to the test repo.
+func setupBearerVerifier() (usecase.BearerTokenVerifier, error) {
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	testSigner, err = jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test"))
+	if err != nil {
+		return nil, err
+	}
+
+	jwks, err := json.Marshal(&jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "test", Algorithm: string(jose.RS256), Use: "sig"},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = ioutil.WriteFile(testJWKSFile, jwks, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(testJWKSFile)
+
+	return newBearerTokenVerifier(oidcConfig{
+		Issuer:   testIssuer,
+		Audience: testAudience,
+		JWKSFile: testJWKSFile,
+	})
+}
+
+func signTestJWT(t *testing.T, user string, groups []string) string {
+
+	claims := struct {
+		jwt.Claims
+		Groups []string `json:"groups"`
+	}{
+		Claims: jwt.Claims{
+			Subject:  user,
+			Issuer:   testIssuer,
+			Audience: jwt.Audience{testAudience},
+			Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Groups: groups,
+	}
+
+	token, err := jwt.Signed(testSigner).Claims(claims).CompactSerialize()
+	if err != nil {
+		t.Fatalf("error signing token: %s", err)
+	}
+
+	return token
+}
+
+func TestBearerAuth(t *testing.T) {
+
+	req := newBatchRequest(t, "download", testContentOid)
+	req.Header.Set("Authorization", "Bearer "+signTestJWT(t, testUser1, nil))
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+
+	if res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+}
+
+func TestBearerGroupPermission(t *testing.T) {
+
+	req := newBatchRequest(t, "upload", testNonExistingOid)
+	req.Header.Set("Authorization", "Bearer "+signTestJWT(t, testOIDCUser, []string{testGroup}))
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+
+	if res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+
+	req = newBatchRequest(t, "upload", testNonExistingOid)
+	req.Header.Set("Authorization", "Bearer "+signTestJWT(t, testOIDCUser, nil))
+
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+
+	if res.StatusCode != 403 {
+		t.Fatalf("expected status 403 without group, got %d", res.StatusCode)
+	}
+}
+
+func TestBearerInvalidToken(t *testing.T) {
+
+	req := newBatchRequest(t, "download", testContentOid)
+	req.Header.Set("Authorization", "Bearer not-a-jwt")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+
+	if res.StatusCode != 401 {
+		t.Fatalf("expected status 401, got %d", res.StatusCode)
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/ikmski/git-lfs3/adapter"
+	"github.com/ikmski/git-lfs3/entity"
 )
 
 func TestLocksList(t *testing.T) {
@@ -18,6 +20,7 @@ func TestLocksList(t *testing.T) {
 	if err != nil {
 		t.Fatalf("request error: %s", err)
 	}
+	req.SetBasicAuth(testUser1, testPass1)
 	req.Header.Set("Accept", "application/vnd.git-lfs+json")
 	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
 
@@ -54,6 +57,7 @@ func TestLocksVerify(t *testing.T) {
 	if err != nil {
 		t.Fatalf("request error: %s", err)
 	}
+	req.SetBasicAuth(testUser1, testPass1)
 	req.Header.Set("Accept", "application/vnd.git-lfs+json")
 	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
 
@@ -111,6 +115,7 @@ func TestUnlock(t *testing.T) {
 	if err != nil {
 		t.Fatalf("request error: %s", err)
 	}
+	req.SetBasicAuth(testUser1, testPass1)
 	req.Header.Set("Accept", "application/vnd.git-lfs+json")
 	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
 
@@ -142,6 +147,29 @@ func TestUnlock(t *testing.T) {
 	}
 }
 
+func TestUnlockErrors(t *testing.T) {
+
+	theirs := entity.Lock{ID: "theirs-lock", Path: "TestUnlockErrors/theirs", Owner: entity.User{Name: testUser2}}
+	if err := testLockRepo.Add(ownedRepo(testRepo), theirs); err != nil {
+		t.Fatalf("error seeding lock store: %s", err)
+	}
+
+	for id, status := range map[string]int{theirs.ID: 403, testNonExistingLockId: 404} {
+		url := fmt.Sprintf("%s/%s/%s/locks/%s/unlock", lfsServer.URL, testUser1, testRepo, id)
+		req, err := http.NewRequest("POST", url, strings.NewReader(`{"force": false}`))
+		if err != nil {
+			t.Fatalf("request error: %s", err)
+		}
+		req.SetBasicAuth(testUser1, testPass1)
+		req.Header.Set("Accept", metaMediaType)
+		req.Header.Set("Content-Type", metaMediaType)
+
+		if res := doRequest(t, req); res.StatusCode != status {
+			t.Errorf("expected status %d unlocking %s, got %d", status, id, res.StatusCode)
+		}
+	}
+}
+
 func addLock(username string, path string) (*adapter.Lock, error) {
 
 	url := fmt.Sprintf("%s/%s/%s/locks", lfsServer.URL, username, testRepo)
@@ -149,6 +177,7 @@ func addLock(username string, path string) (*adapter.Lock, error) {
 	if err != nil {
 		return nil, err
 	}
+	req.SetBasicAuth(testUser1, testPass1)
 
 	req.Header.Set("Accept", "application/vnd.git-lfs+json")
 	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
@@ -177,3 +206,27 @@ func addLock(username string, path string) (*adapter.Lock, error) {
 
 	return lockResponse.Lock, nil
 }
+
+func TestLockBodyTooLarge(t *testing.T) {
+
+	path := fmt.Sprintf("%s/%s/%s/locks", lfsServer.URL, testUser1, testRepo)
+
+	body := fmt.Sprintf(`{"path":%q}`, strings.Repeat("a", testLockBodyLimit))
+	req, err := http.NewRequest("POST", path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	req.SetBasicAuth(testUser1, testPass1)
+	req.Header.Set("Accept", "application/vnd.git-lfs+json")
+	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != 413 {
+		t.Errorf("expected status 413, got %d", res.StatusCode)
+	}
+}
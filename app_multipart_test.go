@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ikmski/git-lfs3/adapter"
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+const testMultipartContent = "this content is uploaded in parts"
+
+func multipartOid(content string) string {
+
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func doMultipartBatch(t *testing.T, oid string, size int64) *adapter.BatchResponse {
+
+	path := fmt.Sprintf("%s/%s/%s/objects/batch", lfsServer.URL, testUser1, testRepo)
+
+	requestBody, _ := json.Marshal(&adapter.BatchRequest{
+		Operation: "upload",
+		Transfers: []string{"multipart", "basic"},
+		Objects:   []*adapter.ObjectRequest{{Oid: oid, Size: size}},
+	})
+
+	req, err := http.NewRequest("POST", path, bytes.NewReader(requestBody))
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	req.SetBasicAuth(testUser1, testPass1)
+	req.Header.Set("Accept", "application/vnd.git-lfs+json")
+	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+
+	if res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+
+	var responseData adapter.BatchResponse
+	err = json.NewDecoder(res.Body).Decode(&responseData)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	return &responseData
+}
+
+func doMultipart(t *testing.T, method string, href string, body io.Reader) (int, *adapter.MultipartUploadResponse) {
+
+	req, err := http.NewRequest(method, href, body)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	req.SetBasicAuth(testUser1, testPass1)
+	if body == nil {
+		req.Header.Set("Accept", "application/vnd.git-lfs+json")
+	} else {
+		req.Header.Set("Accept", "application/vnd.git-lfs")
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	defer res.Body.Close()
+
+	data, _ := ioutil.ReadAll(res.Body)
+
+	var upload adapter.MultipartUploadResponse
+	if res.StatusCode == 200 && len(data) > 0 {
+		err = json.Unmarshal(data, &upload)
+		if err != nil {
+			t.Fatalf("got error: %s", err)
+		}
+	}
+
+	return res.StatusCode, &upload
+}
+
+func TestMultipartUpload(t *testing.T) {
+
+	content := testMultipartContent
+	oid := multipartOid(content)
+	size := int64(len(content))
+
+	batch := doMultipartBatch(t, oid, size)
+	if batch.Transfer != "multipart" {
+		t.Fatalf("expected multipart transfer, got %q", batch.Transfer)
+	}
+
+	upload := batch.Objects[0].Actions["upload"]
+	if upload == nil || !strings.HasSuffix(upload.Href, "/multipart") {
+		t.Fatalf("expected multipart upload action, got: %+v", upload)
+	}
+	href := upload.Href
+
+	status, started := doMultipart(t, "POST", href, nil)
+	if status != 200 {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+
+	if started.PartSize != testPartSize || started.PartCount != 5 || started.NextPart != 1 {
+		t.Fatalf("expected 5 parts of %d bytes starting at 1, got: %+v", testPartSize, started)
+	}
+
+	status, _ = doMultipart(t, "PUT", href+"/2", strings.NewReader(content[8:16]))
+	if status != 409 {
+		t.Errorf("expected status 409 for a part out of order, got %d", status)
+	}
+
+	for n := 1; n <= 2; n++ {
+		part := content[(n-1)*testPartSize : n*testPartSize]
+		status, _ = doMultipart(t, "PUT", fmt.Sprintf("%s/%d", href, n), strings.NewReader(part))
+		if status != 200 {
+			t.Fatalf("expected status 200 for part %d, got %d", n, status)
+		}
+	}
+
+	status, _ = doMultipart(t, "POST", href+"/complete", nil)
+	if status != 422 {
+		t.Errorf("expected status 422 for an incomplete upload, got %d", status)
+	}
+
+	// the upload is resumed after the last stored part
+	status, resumed := doMultipart(t, "POST", href, nil)
+	if status != 200 || resumed.NextPart != 3 || resumed.Uploaded != 2*testPartSize {
+		t.Fatalf("expected upload to resume at part 3, got %d: %+v", status, resumed)
+	}
+
+	for n := 3; n <= 5; n++ {
+		end := n * testPartSize
+		if end > len(content) {
+			end = len(content)
+		}
+		status, _ = doMultipart(t, "PUT", fmt.Sprintf("%s/%d", href, n), strings.NewReader(content[(n-1)*testPartSize:end]))
+		if status != 200 {
+			t.Fatalf("expected status 200 for part %d, got %d", n, status)
+		}
+	}
+
+	status, _ = doMultipart(t, "POST", href+"/complete", nil)
+	if status != 200 {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+
+	meta, err := testMetaDataRepo.Get(oid)
+	if err != nil || !meta.Complete {
+		t.Fatalf("expected object to be complete, got: %+v, %v", meta, err)
+	}
+
+	if !testContentRepo.Exists(meta) {
+		t.Errorf("expected content to be stored")
+	}
+}
+
+func TestMultipartUploadHashMismatch(t *testing.T) {
+
+	content := "content that does not match the oid"
+	oid := multipartOid(testMultipartContent + " but different")
+	size := int64(len(content))
+
+	batch := doMultipartBatch(t, oid, size)
+	href := batch.Objects[0].Actions["upload"].Href
+
+	status, started := doMultipart(t, "POST", href, nil)
+	if status != 200 {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+
+	for n := 1; n <= started.PartCount; n++ {
+		end := n * testPartSize
+		if end > len(content) {
+			end = len(content)
+		}
+		status, _ = doMultipart(t, "PUT", fmt.Sprintf("%s/%d", href, n), strings.NewReader(content[(n-1)*testPartSize:end]))
+		if status != 200 {
+			t.Fatalf("expected status 200 for part %d, got %d", n, status)
+		}
+	}
+
+	status, _ = doMultipart(t, "POST", href+"/complete", nil)
+	if status != 422 {
+		t.Fatalf("expected status 422, got %d", status)
+	}
+
+	// the failed upload is discarded
+	status, _ = doMultipart(t, "DELETE", href, nil)
+	if status != 404 {
+		t.Errorf("expected status 404 after the upload was aborted, got %d", status)
+	}
+}
+
+func TestMultipartUploadNotStarted(t *testing.T) {
+
+	path := fmt.Sprintf("%s/%s/%s/objects/%s/multipart/1", lfsServer.URL, testUser1, testRepo, testContentOid)
+
+	status, _ := doMultipart(t, "PUT", path, strings.NewReader("content"))
+	if status != 404 {
+		t.Errorf("expected status 404, got %d", status)
+	}
+}
+
+func TestMultipartUploadArchivedMidUpload(t *testing.T) {
+
+	name := "multipart-archived"
+	if err := testRepositoryRepo.Create(&entity.Repository{Name: ownedRepo(name)}); err != nil {
+		t.Fatalf("error registering repository: %s", err)
+	}
+	if err := testPermRepo.Grant(testUser1, ownedRepo(name), entity.RoleWrite); err != nil {
+		t.Fatalf("error granting permission: %s", err)
+	}
+
+	// registered in a batch before the repo was archived
+	content := testMultipartContent + " archived"
+	meta := &entity.MetaData{
+		Oid:      multipartOid(content),
+		Size:     int64(len(content)),
+		Uploader: entity.User{Name: testUser1},
+		Repos:    []string{ownedRepo(name)},
+	}
+	if _, err := testMetaDataRepo.Put(meta); err != nil {
+		t.Fatalf("error seeding meta store: %s", err)
+	}
+	href := fmt.Sprintf("%s/%s/%s/objects/%s/multipart", lfsServer.URL, testUser1, name, meta.Oid)
+
+	status, started := doMultipart(t, "POST", href, nil)
+	if status != 200 {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+
+	for n := 1; n <= started.PartCount; n++ {
+		end := n * testPartSize
+		if end > len(content) {
+			end = len(content)
+		}
+		status, _ = doMultipart(t, "PUT", fmt.Sprintf("%s/%d", href, n), strings.NewReader(content[(n-1)*testPartSize:end]))
+		if status != 200 {
+			t.Fatalf("expected status 200 for part %d, got %d", n, status)
+		}
+	}
+
+	if err := testRepositoryRepo.SetArchived(ownedRepo(name), true); err != nil {
+		t.Fatalf("error archiving repository: %s", err)
+	}
+
+	status, _ = doMultipart(t, "POST", href+"/complete", nil)
+	if status != 403 {
+		t.Fatalf("expected status 403 for an archived repo, got %d", status)
+	}
+
+	if testContentRepo.Exists(meta) {
+		t.Errorf("expected no content stored for an archived repo")
+	}
+
+	// the rejected upload is discarded
+	status, _ = doMultipart(t, "DELETE", href, nil)
+	if status != 404 {
+		t.Errorf("expected status 404 after the upload was aborted, got %d", status)
+	}
+}
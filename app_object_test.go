@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ikmski/git-lfs3/adapter"
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+const testListRepo = "list-repo"
+
+func getObjects(t *testing.T, path string) *http.Response {
+
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/%s/%s/objects%s", lfsServer.URL, testUser1, testListRepo, path), nil)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	req.SetBasicAuth(testUser1, testPass1)
+	req.Header.Set("Accept", metaMediaType)
+
+	return doRequest(t, req)
+}
+
+func seedListRepo(t *testing.T) []*entity.MetaData {
+
+	if err := testPermRepo.Grant(testUser1, testUser1+"/"+testListRepo, entity.RoleRead); err != nil {
+		t.Fatalf("error granting permission: %s", err)
+	}
+
+	// sizes ascend while creation dates descend
+	var metas []*entity.MetaData
+	for i, content := range []string{"a listed", "bb listed", "ccc listed"} {
+		meta := &entity.MetaData{
+			Oid:       multipartOid(content),
+			Size:      int64(len(content)),
+			CreatedAt: int64(1500000000 - i),
+			Uploader:  entity.User{Name: testUser1},
+			Repos:     []string{ownedRepo(testListRepo)},
+		}
+		if _, err := testMetaDataRepo.Put(meta); err != nil {
+			t.Fatalf("error seeding meta store: %s", err)
+		}
+		metas = append(metas, meta)
+	}
+
+	if err := testContentRepo.Put(metas[0], strings.NewReader("a listed")); err != nil {
+		t.Fatalf("error seeding content store: %s", err)
+	}
+
+	return metas
+}
+
+func TestListObjects(t *testing.T) {
+
+	metas := seedListRepo(t)
+
+	res := getObjects(t, "?sort=size&order=desc&limit=2")
+	if res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+
+	var page adapter.ObjectListResponse
+	if err := json.NewDecoder(res.Body).Decode(&page); err != nil {
+		t.Fatalf("expected object list, got: %s", err)
+	}
+	if len(page.Objects) != 2 || page.Objects[0].Oid != metas[2].Oid || page.Objects[1].Oid != metas[1].Oid {
+		t.Fatalf("expected the two largest objects, got: %+v", page.Objects)
+	}
+	if page.NextCursor != fmt.Sprintf("%d:%s", metas[0].Size, metas[0].Oid) {
+		t.Fatalf("expected the smallest object as next cursor, got: %q", page.NextCursor)
+	}
+
+	res = getObjects(t, "?sort=size&order=desc&limit=2&cursor="+page.NextCursor)
+	page = adapter.ObjectListResponse{}
+	if err := json.NewDecoder(res.Body).Decode(&page); err != nil {
+		t.Fatalf("expected object list, got: %s", err)
+	}
+	if len(page.Objects) != 1 || page.Objects[0].Oid != metas[0].Oid || page.NextCursor != "" {
+		t.Fatalf("expected the last page, got: %+v", page)
+	}
+
+	// a cursor lists from its position when its object is gone
+	res = getObjects(t, fmt.Sprintf("?sort=size&order=desc&cursor=%d:%s", metas[1].Size, strings.Repeat("0", 64)))
+	page = adapter.ObjectListResponse{}
+	if err := json.NewDecoder(res.Body).Decode(&page); err != nil {
+		t.Fatalf("expected object list, got: %s", err)
+	}
+	if len(page.Objects) != 1 || page.Objects[0].Oid != metas[0].Oid {
+		t.Fatalf("expected the objects after the cursor, got: %+v", page)
+	}
+
+	res = getObjects(t, "?sort=date")
+	page = adapter.ObjectListResponse{}
+	if err := json.NewDecoder(res.Body).Decode(&page); err != nil {
+		t.Fatalf("expected object list, got: %s", err)
+	}
+	if len(page.Objects) != 3 || page.Objects[0].Oid != metas[2].Oid || page.Objects[0].Uploader != testUser1 {
+		t.Fatalf("expected the oldest object first, got: %+v", page.Objects)
+	}
+
+	for _, query := range []string{"?sort=name", "?order=up", "?limit=0", "?cursor=invalid", "?sort=size&cursor=" + testNonExistingOid} {
+		if res := getObjects(t, query); res.StatusCode != 422 {
+			t.Errorf("expected status 422 for %s, got %d", query, res.StatusCode)
+		}
+	}
+}
+
+func TestObjectInfo(t *testing.T) {
+
+	metas := seedListRepo(t)
+
+	res := getObjects(t, "/"+metas[0].Oid+"/info")
+	if res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+
+	var info adapter.ObjectInfoResponse
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		t.Fatalf("expected object info, got: %s", err)
+	}
+	if info.Oid != metas[0].Oid || info.Size != metas[0].Size || info.ETag == "" || info.StorageClass != "STANDARD" || info.LastModified == nil {
+		t.Fatalf("expected meta data and content details, got: %+v", info)
+	}
+
+	// objects registered but never uploaded have no content details
+	res = getObjects(t, "/"+metas[1].Oid+"/info")
+	info = adapter.ObjectInfoResponse{}
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		t.Fatalf("expected object info, got: %s", err)
+	}
+	if info.Oid != metas[1].Oid || info.ETag != "" || info.LastModified != nil {
+		t.Fatalf("expected meta data only, got: %+v", info)
+	}
+
+	// testContentOid is stored, but not in the listed repo
+	if res := getObjects(t, "/"+testContentOid+"/info"); res.StatusCode != 404 {
+		t.Errorf("expected status 404, got %d", res.StatusCode)
+	}
+
+	if res := getObjects(t, "/invalid/info"); res.StatusCode != 422 {
+		t.Errorf("expected status 422, got %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ikmski/git-lfs3/adapter"
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+func doBatchUpload(t *testing.T, repo string, objs ...*adapter.ObjectRequest) *adapter.BatchResponse {
+
+	path := fmt.Sprintf("%s/%s/%s/objects/batch", lfsServer.URL, testUser1, repo)
+
+	requestBody, _ := json.Marshal(&adapter.BatchRequest{
+		Operation: "upload",
+		Objects:   objs,
+	})
+
+	req, err := http.NewRequest("POST", path, bytes.NewReader(requestBody))
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	req.SetBasicAuth(testUser1, testPass1)
+	req.Header.Set("Accept", "application/vnd.git-lfs+json")
+	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+
+	if res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+
+	var responseData adapter.BatchResponse
+	err = json.NewDecoder(res.Body).Decode(&responseData)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	if len(responseData.Objects) != len(objs) {
+		t.Fatalf("expected %d objects, got %d", len(objs), len(responseData.Objects))
+	}
+
+	return &responseData
+}
+
+func TestBatchUploadPolicy(t *testing.T) {
+
+	res := doBatchUpload(t, testPolicyRepo,
+		&adapter.ObjectRequest{Oid: "2222222222222222222222222222222222222222222222222222222222222222", Size: testContentSize, Path: "assets/logo.png"},
+		&adapter.ObjectRequest{Oid: "3333333333333333333333333333333333333333333333333333333333333333", Size: testContentSize + 1},
+		&adapter.ObjectRequest{Oid: "4444444444444444444444444444444444444444444444444444444444444444", Size: testContentSize, Path: "bin/setup.EXE"},
+	)
+
+	if obj := res.Objects[0]; obj.Error != nil || obj.Actions["upload"] == nil {
+		t.Errorf("expected allowed object to get an upload action, got error: %+v", obj.Error)
+	}
+
+	if obj := res.Objects[1]; obj.Error == nil || obj.Error.Code != 422 {
+		t.Errorf("expected 422 for an object over the maximum size, got: %+v", obj.Error)
+	}
+
+	if obj := res.Objects[2]; obj.Error == nil || obj.Error.Code != 422 {
+		t.Errorf("expected 422 for a denied extension, got: %+v", obj.Error)
+	}
+}
+
+func TestBatchUploadArchivedRepo(t *testing.T) {
+
+	res := doBatchUpload(t, testArchivedRepo,
+		&adapter.ObjectRequest{Oid: "5555555555555555555555555555555555555555555555555555555555555555", Size: testContentSize},
+	)
+
+	obj := res.Objects[0]
+	if obj.Error == nil || obj.Error.Code != 403 || obj.Error.Message != "Repository is archived" {
+		t.Fatalf("expected 403 for an archived repo, got: %+v", obj.Error)
+	}
+
+	if len(obj.Actions) != 0 {
+		t.Errorf("expected no actions, got: %v", obj.Actions)
+	}
+}
+
+func TestUploadArchivedRepo(t *testing.T) {
+
+	// registered in a batch before the repo was archived
+	content := "archived content"
+	meta := &entity.MetaData{
+		Oid:      multipartOid(content),
+		Size:     int64(len(content)),
+		Uploader: entity.User{Name: testUser1},
+		Repos:    []string{ownedRepo(testArchivedRepo)},
+	}
+	if _, err := testMetaDataRepo.Put(meta); err != nil {
+		t.Fatalf("error seeding meta store: %s", err)
+	}
+
+	requests := []struct {
+		method string
+		path   string
+		accept string
+	}{
+		{"PUT", "/objects/" + meta.Oid, contentMediaType},
+		{"POST", "/objects/" + meta.Oid + "/multipart", metaMediaType},
+	}
+
+	for _, r := range requests {
+		path := fmt.Sprintf("%s/%s/%s%s", lfsServer.URL, testUser1, testArchivedRepo, r.path)
+		req, err := http.NewRequest(r.method, path, strings.NewReader(content))
+		if err != nil {
+			t.Fatalf("request error: %s", err)
+		}
+		req.SetBasicAuth(testUser1, testPass1)
+		req.Header.Set("Accept", r.accept)
+
+		if res := doRequest(t, req); res.StatusCode != 403 {
+			t.Errorf("expected status 403 for %s %s, got %d", r.method, r.path, res.StatusCode)
+		}
+	}
+
+	if testContentRepo.Exists(meta) {
+		t.Errorf("expected no content stored for an archived repo")
+	}
+}
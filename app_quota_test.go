@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ikmski/git-lfs3/adapter"
+)
+
+func TestBatchUploadOverQuota(t *testing.T) {
+
+	path := fmt.Sprintf("%s/%s/%s/objects/batch", lfsServer.URL, testUser1, testQuotaRepo)
+
+	requestData := &adapter.BatchRequest{
+		Operation: "upload",
+		Objects: []*adapter.ObjectRequest{
+			{Oid: testNonExistingOid, Size: testContentSize},
+			{Oid: testContentOid, Size: testContentSize},
+		},
+	}
+
+	requestBody, _ := json.Marshal(requestData)
+
+	req, err := http.NewRequest("POST", path, bytes.NewReader(requestBody))
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	req.SetBasicAuth(testUser1, testPass1)
+	req.Header.Set("Accept", "application/vnd.git-lfs+json")
+	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+
+	if res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+
+	var responseData adapter.BatchResponse
+	err = json.NewDecoder(res.Body).Decode(&responseData)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	if len(responseData.Objects) != 2 {
+		t.Fatalf("expected two objects, got %d", len(responseData.Objects))
+	}
+
+	// the repo allows one object, and referencing an object stored for
+	// another repo counts like uploading it
+	if obj := responseData.Objects[0]; obj.Error != nil {
+		t.Errorf("expected %s within quota, got error: %+v", obj.Oid, obj.Error)
+	}
+
+	if obj := responseData.Objects[1]; obj.Error == nil || obj.Error.Code != 507 {
+		t.Errorf("expected 507 for a reference to %s over quota, got: %+v", obj.Oid, obj.Error)
+	}
+
+	usage, err := testMetaDataRepo.RepoUsage(ownedRepo(testQuotaRepo))
+	if err != nil {
+		t.Fatalf("expected usage, got: %s", err)
+	}
+
+	if usage.Objects != 1 || usage.Size != testContentSize {
+		t.Errorf("expected usage of one object, got: %+v", usage)
+	}
+
+	requestData.Objects = []*adapter.ObjectRequest{
+		{Oid: "1111111111111111111111111111111111111111111111111111111111111111", Size: testContentSize},
+	}
+	requestBody, _ = json.Marshal(requestData)
+
+	req, _ = http.NewRequest("POST", path, bytes.NewReader(requestBody))
+	req.SetBasicAuth(testUser1, testPass1)
+	req.Header.Set("Accept", "application/vnd.git-lfs+json")
+	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+
+	responseData = adapter.BatchResponse{}
+	err = json.NewDecoder(res.Body).Decode(&responseData)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	obj := responseData.Objects[0]
+	if obj.Error == nil || obj.Error.Code != 507 {
+		t.Fatalf("expected 507 object error, got: %+v", obj.Error)
+	}
+
+	if len(obj.Actions) != 0 {
+		t.Errorf("expected no actions for an object over quota, got: %v", obj.Actions)
+	}
+}
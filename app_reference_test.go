@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ikmski/git-lfs3/adapter"
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+func TestBatchUploadReferencesStoredContent(t *testing.T) {
+
+	content := "shared content"
+	meta := &entity.MetaData{
+		Oid:      multipartOid(content),
+		Size:     int64(len(content)),
+		Uploader: entity.User{Name: testUser1},
+		Repos:    []string{ownedRepo(testRepo)},
+		Complete: true,
+	}
+
+	if _, err := testMetaDataRepo.Put(meta); err != nil {
+		t.Fatalf("error seeding meta store: %s", err)
+	}
+	if err := testContentRepo.Put(meta, strings.NewReader(content)); err != nil {
+		t.Fatalf("error seeding content store: %s", err)
+	}
+
+	res := doBatchUpload(t, testPolicyRepo, &adapter.ObjectRequest{Oid: meta.Oid, Size: meta.Size})
+
+	obj := res.Objects[0]
+	if obj.Error != nil || obj.Actions["upload"] != nil {
+		t.Fatalf("expected stored content to need no upload, got: %+v", obj)
+	}
+
+	stored, err := testMetaDataRepo.Get(meta.Oid)
+	if err != nil || len(stored.Repos) != 2 {
+		t.Fatalf("expected the object to be referenced by two repos, got: %+v, %v", stored, err)
+	}
+
+	refs := usecase.NewReferenceService(testMetaDataRepo, testContentRepo)
+
+	if err := refs.Release(ownedRepo(testRepo), meta.Oid); err != nil {
+		t.Fatalf("expected release to succeed, got: %s", err)
+	}
+
+	if !testContentRepo.Exists(meta) {
+		t.Errorf("expected content to be kept while a repo references it")
+	}
+
+	if err := refs.Release(ownedRepo(testPolicyRepo), meta.Oid); err != nil {
+		t.Fatalf("expected release to succeed, got: %s", err)
+	}
+
+	if testContentRepo.Exists(meta) {
+		t.Errorf("expected content to be deleted with the last reference")
+	}
+
+	if _, err := testMetaDataRepo.Get(meta.Oid); err == nil {
+		t.Errorf("expected meta to be deleted with the last reference")
+	}
+}
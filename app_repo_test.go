@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ikmski/git-lfs3/adapter"
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+func doRepositoryRequest(t *testing.T, user string, pass string, method string, path string, body interface{}) *http.Response {
+
+	var r io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		r = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("%s/admin/repos%s", lfsServer.URL, path), r)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	req.SetBasicAuth(user, pass)
+
+	return doRequest(t, req)
+}
+
+func decodeRepositoryResponse(t *testing.T, res *http.Response) *adapter.RepositoryResponse {
+
+	var repo adapter.RepositoryResponse
+	if err := json.NewDecoder(res.Body).Decode(&repo); err != nil {
+		t.Fatalf("expected repository response, got: %s", err)
+	}
+
+	return &repo
+}
+
+func TestRepositoryAdminAPI(t *testing.T) {
+
+	name := ownedRepo("lifecycle-repo")
+	newName := ownedRepo("lifecycle-renamed")
+
+	// testUser2 is not an admin
+	res := doRepositoryRequest(t, testUser2, testPass2, "POST", "", &adapter.RepositoryRequest{Name: name})
+	if res.StatusCode != 403 {
+		t.Fatalf("expected status 403, got %d", res.StatusCode)
+	}
+
+	res = doRepositoryRequest(t, testUser1, testPass1, "POST", "", &adapter.RepositoryRequest{Name: name})
+	if res.StatusCode != 201 {
+		t.Fatalf("expected status 201, got %d", res.StatusCode)
+	}
+	if repo := decodeRepositoryResponse(t, res); repo.Name != name || repo.Archived {
+		t.Fatalf("expected created repository, got: %+v", repo)
+	}
+
+	res = doRepositoryRequest(t, testUser1, testPass1, "POST", "", &adapter.RepositoryRequest{Name: name})
+	if res.StatusCode != 409 {
+		t.Fatalf("expected status 409 for an existing repository, got %d", res.StatusCode)
+	}
+
+	res = doRepositoryRequest(t, testUser1, testPass1, "POST", "", &adapter.RepositoryRequest{Name: "lifecycle-repo"})
+	if res.StatusCode != 422 {
+		t.Fatalf("expected status 422 for an invalid name, got %d", res.StatusCode)
+	}
+
+	res = doRepositoryRequest(t, testUser1, testPass1, "GET", "", nil)
+	var list adapter.RepositoryListResponse
+	if err := json.NewDecoder(res.Body).Decode(&list); err != nil || len(list.Repositories) == 0 {
+		t.Fatalf("expected repository list, got: %+v, %v", list, err)
+	}
+
+	res = doRepositoryRequest(t, testUser1, testPass1, "PUT", "/"+name+"/archive", nil)
+	if res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+	if repo := decodeRepositoryResponse(t, res); !repo.Archived {
+		t.Fatalf("expected archived repository, got: %+v", repo)
+	}
+
+	// uploads to archived repositories are rejected
+	if err := testPermRepo.Grant(testUser1, name, entity.RoleWrite); err != nil {
+		t.Fatalf("error granting permission: %s", err)
+	}
+
+	batch := doBatchUpload(t, "lifecycle-repo", &adapter.ObjectRequest{Oid: testNonExistingOid, Size: 1})
+	if obj := batch.Objects[0]; obj.Error == nil || obj.Error.Code != 403 {
+		t.Fatalf("expected upload to an archived repository to fail, got: %+v", obj)
+	}
+
+	res = doRepositoryRequest(t, testUser1, testPass1, "DELETE", "/"+name+"/archive", nil)
+	if res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+
+	res = doRepositoryRequest(t, testUser1, testPass1, "POST", "/"+name+"/rename", &adapter.RepositoryRequest{Name: newName})
+	if res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+
+	res = doRepositoryRequest(t, testUser1, testPass1, "GET", "/"+name, nil)
+	if res.StatusCode != 404 {
+		t.Fatalf("expected status 404 for the old name, got %d", res.StatusCode)
+	}
+
+	if perm, err := testPermRepo.Get(testUser1, newName); err != nil || perm.Role != entity.RoleWrite {
+		t.Errorf("expected the permission to move with the repository, got: %+v, %v", perm, err)
+	}
+
+	content := "lifecycle content"
+	meta := &entity.MetaData{
+		Oid:      multipartOid(content),
+		Size:     int64(len(content)),
+		Uploader: entity.User{Name: testUser1},
+		Repos:    []string{newName},
+		Complete: true,
+	}
+	if _, err := testMetaDataRepo.Put(meta); err != nil {
+		t.Fatalf("error seeding meta store: %s", err)
+	}
+	if err := testContentRepo.Put(meta, strings.NewReader(content)); err != nil {
+		t.Fatalf("error seeding content store: %s", err)
+	}
+
+	res = doRepositoryRequest(t, testUser1, testPass1, "DELETE", "/"+newName, nil)
+	if res.StatusCode != 204 {
+		t.Fatalf("expected status 204, got %d", res.StatusCode)
+	}
+
+	if testContentRepo.Exists(meta) {
+		t.Errorf("expected content of the deleted repository to be deleted")
+	}
+
+	res = doRepositoryRequest(t, testUser1, testPass1, "GET", "/"+newName, nil)
+	if res.StatusCode != 404 {
+		t.Fatalf("expected status 404 for the deleted repository, got %d", res.StatusCode)
+	}
+
+	if _, err := testPermRepo.Get(testUser1, newName); err == nil {
+		t.Errorf("expected the permission to be deleted with the repository")
+	}
+
+	var actions []string
+	for _, repo := range []string{newName, name} {
+		for _, e := range queryAudit(t, url.Values{"actor": {testUser1}, "repo": {repo}}) {
+			if strings.HasPrefix(e.Action, "repo.") {
+				actions = append(actions, e.Action)
+			}
+		}
+	}
+	expected := []string{entity.AuditRepoDelete, entity.AuditRepoRename, entity.AuditRepoUnarchive, entity.AuditRepoArchive, entity.AuditRepoCreate}
+	if strings.Join(actions, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, actions)
+	}
+}
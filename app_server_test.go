@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/ikmski/git-lfs3/adapter"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+
+	os.Remove("lfs-shutdown-test.db")
+	defer os.Remove("lfs-shutdown-test.db")
+
+	db, err := bolt.Open("lfs-shutdown-test.db", 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		t.Fatalf("error creating test db: %s", err)
+	}
+
+	conf := serverConfig{}
+	conf.ShutdownTimeout.Duration = 5 * time.Second
+	conf.ShutdownDelay.Duration = 300 * time.Millisecond
+
+	logger, _ := adapter.NewLogger(ioutil.Discard, "", "")
+
+	healthController := adapter.NewHealthController(usecase.NewHealthService(testMetaDataRepo, testContentRepo))
+
+	a := newApp(conf, logger, nil, nil, nil, nil, nil, nil, healthController, nil, nil, nil, nil, nil)
+	a.db = db
+
+	started := make(chan struct{})
+	a.router.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("done"))
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %s", err)
+	}
+
+	stop := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- a.run(l, stop)
+	}()
+
+	resc := make(chan *http.Response, 1)
+	go func() {
+		res, err := http.Get(fmt.Sprintf("http://%s/slow", l.Addr()))
+		if err != nil {
+			t.Errorf("request error: %s", err)
+			close(resc)
+			return
+		}
+		resc <- res
+	}()
+
+	readyz := fmt.Sprintf("http://%s/readyz", l.Addr())
+	if res, err := http.Get(readyz); err != nil || res.StatusCode != 200 {
+		t.Fatalf("expected instance to be ready before shutdown, got: %v %v", res, err)
+	}
+
+	<-started
+	stop <- syscall.SIGTERM
+
+	time.Sleep(100 * time.Millisecond)
+	if res, err := http.Get(readyz); err != nil || res.StatusCode != 503 {
+		t.Errorf("expected instance to be unready while draining, got: %v %v", res, err)
+	}
+
+	res, ok := <-resc
+	if !ok {
+		t.FailNow()
+	}
+	if res.StatusCode != 200 {
+		t.Errorf("expected in-flight request to complete with status 200, got %d", res.StatusCode)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected shutdown to succeed, got: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected server to shut down")
+	}
+
+	if err := db.View(func(tx *bolt.Tx) error { return nil }); err != bolt.ErrDatabaseNotOpen {
+		t.Errorf("expected database to be closed, got: %v", err)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+
+	os.Remove("lfs-shutdown-test.db")
+	defer os.Remove("lfs-shutdown-test.db")
+
+	db, err := bolt.Open("lfs-shutdown-test.db", 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		t.Fatalf("error creating test db: %s", err)
+	}
+
+	conf := serverConfig{}
+	conf.ShutdownTimeout.Duration = 100 * time.Millisecond
+
+	logger, _ := adapter.NewLogger(ioutil.Discard, "", "")
+
+	a := newApp(conf, logger, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	a.db = db
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	a.router.HandleFunc("/hung", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %s", err)
+	}
+
+	stop := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- a.run(l, stop)
+	}()
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := http.Get(fmt.Sprintf("http://%s/hung", l.Addr()))
+		errc <- err
+	}()
+
+	<-started
+	stop <- syscall.SIGTERM
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("expected shutdown to time out")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected server to shut down")
+	}
+
+	// the connection of the hung request is closed along with the database
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Errorf("expected hung request to fail")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected hung request to be closed")
+	}
+
+	if err := db.View(func(tx *bolt.Tx) error { return nil }); err != bolt.ErrDatabaseNotOpen {
+		t.Errorf("expected database to be closed, got: %v", err)
+	}
+}
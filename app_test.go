@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"net/http/httptest"
 	"os"
 	"testing"
@@ -15,10 +16,21 @@ import (
 )
 
 var (
-	lfsServer        *httptest.Server
-	testMetaDataRepo usecase.MetaDataRepository
-	testContentRepo  usecase.ContentRepository
-	testLockRepo     usecase.LockRepository
+	lfsServer          *httptest.Server
+	testMetaDataRepo   usecase.MetaDataRepository
+	testContentRepo    usecase.ContentRepository
+	testLockRepo       usecase.LockRepository
+	testUserRepo       usecase.UserRepository
+	testPermRepo       usecase.PermissionRepository
+	testRepositoryRepo usecase.RepositoryRepository
+	testTombstoneRepo  usecase.TombstoneRepository
+	testAccessTokenSvc usecase.AccessTokenService
+	testTokenService   usecase.TokenService
+	testAccessSvc      usecase.AccessService
+	testBearerVerifier usecase.BearerTokenVerifier
+	testAuditLog       *usecase.AuditLog
+	testWebhooks       *usecase.Webhooks
+	testWebhookTarget  *webhookReceiver
 )
 
 const (
@@ -34,6 +46,15 @@ const (
 	testLockId            = "3cfec93346f7ff337c60f2da50cd86740715e2f6"
 	testNonExistingLockId = "f310c1555a2485e2e5229ea015a94c9d590763d3"
 	testLockPath          = "this/is/lock/path"
+	testTokenSecret       = "this is my token secret"
+	testQuotaRepo         = "quota-repo"
+	testPolicyRepo        = "policy-repo"
+	testArchivedRepo      = "archived-repo"
+	testPartSize          = 8
+	testBatchConcurrency  = 4
+	testBatchMaxObjects   = 100
+	testLockBodyLimit     = 1024
+	testWebhookSecret     = "this is my webhook secret"
 )
 
 func TestMain(m *testing.M) {
@@ -48,6 +69,12 @@ func TestMain(m *testing.M) {
 		os.Exit(1)
 	}
 
+	err = adapter.Migrate(db)
+	if err != nil {
+		fmt.Printf("Error migrating test db: %s", err)
+		os.Exit(1)
+	}
+
 	usersBucket := []byte("users")
 	objectsBucket := []byte("objects")
 	locksBucket := []byte("locks")
@@ -65,17 +92,36 @@ func TestMain(m *testing.M) {
 		return nil
 	})
 
-	testMetaDataRepo = adapter.NewMetaDataRepository(db)
+	testMetaDataRepo = adapter.NewMetaDataRepository(db, nil)
 	if err != nil {
 		fmt.Printf("Error creating meta store: %s", err)
 		os.Exit(1)
 	}
-	testLockRepo = adapter.NewLockRepository(db)
+	testLockRepo = adapter.NewLockRepository(db, nil)
 	if err != nil {
 		fmt.Printf("Error creating lock store: %s", err)
 		os.Exit(1)
 	}
 
+	logger, err := adapter.NewLogger(ioutil.Discard, "", "")
+	if err != nil {
+		fmt.Printf("Error creating logger: %s", err)
+		os.Exit(1)
+	}
+
+	testBearerVerifier, err = setupBearerVerifier()
+	if err != nil {
+		fmt.Printf("Error creating bearer token verifier: %s", err)
+		os.Exit(1)
+	}
+
+	testTokenService = usecase.NewTokenService([]byte(testTokenSecret), time.Minute)
+	testUserRepo = adapter.NewUserRepository(db, nil)
+	testPermRepo = adapter.NewPermissionRepository(db, nil)
+	accessTokenRepo := adapter.NewAccessTokenRepository(db, nil)
+	testRepositoryRepo = adapter.NewRepositoryRepository(db, nil)
+	testTombstoneRepo = adapter.NewTombstoneRepository(db, nil)
+
 	testContentRepo, err = adapter.NewMockedContentRepository("lfs-test-bucket")
 	if err != nil {
 		fmt.Printf("Error creating content store: %s", err)
@@ -94,27 +140,68 @@ func TestMain(m *testing.M) {
 		os.Exit(1)
 	}
 
+	err = seedUserRepository()
+	if err != nil {
+		fmt.Printf("Error seeding user store: %s", err)
+		os.Exit(1)
+	}
+
 	err = seedLockRepository()
 	if err != nil {
 		fmt.Printf("Error seeding lock store: %s", err)
 		os.Exit(1)
 	}
 
-	conf := serverConfig{}
-	batchService := usecase.NewBatchService(testMetaDataRepo, testContentRepo)
-	transferService := usecase.NewTransferService(testMetaDataRepo, testContentRepo)
-	lockService := usecase.NewLockService(testLockRepo)
-
-	batchController := adapter.NewBatchController(batchService)
-	transferController := adapter.NewTransferController(transferService)
-	lockController := adapter.NewLockController(lockService)
-
-	app := newApp(conf, batchController, transferController, lockController)
+	conf := serverConfig{BodyLimits: bodyLimitConfig{Locks: testLockBodyLimit}}
+	quotas := &usecase.Quotas{
+		Repos: map[string]entity.Quota{ownedRepo(testQuotaRepo): {Objects: 1}},
+	}
+	policies := &usecase.UploadPolicies{
+		Repos: map[string]entity.UploadPolicy{
+			ownedRepo(testPolicyRepo):   {MaxSize: testContentSize, DeniedExtensions: []string{"exe"}},
+			ownedRepo(testArchivedRepo): {Archived: true},
+		},
+	}
+	testAuditLog = usecase.NewAuditLog(nil, adapter.NewBoltAuditSink(db, nil))
+
+	// deliveries are made by the tests calling testWebhooks.Deliver
+	testWebhookTarget = newWebhookReceiver()
+	testWebhooks = usecase.NewWebhooks([]entity.Webhook{
+		{URL: testWebhookTarget.server.URL + "/repo", Secret: testWebhookSecret, Repos: []string{ownedRepo(testRepo)}},
+		{URL: testWebhookTarget.server.URL + "/locks", Secret: testWebhookSecret, Events: []string{entity.WebhookLockCreated}},
+	}, adapter.NewWebhookDeliveryRepository(db, nil), adapter.NewWebhookSender(0), nil)
+
+	batchService := usecase.NewBatchService(testMetaDataRepo, testContentRepo, testRepositoryRepo, testTombstoneRepo, quotas, policies, usecase.NewTransferAdapters(adapter.NewBasicTransferAdapter(), adapter.NewMultipartTransferAdapter()), testBatchConcurrency, testAuditLog)
+	transferService := usecase.NewTransferService(testMetaDataRepo, testContentRepo, testRepositoryRepo, testTombstoneRepo, policies, testAuditLog, testWebhooks)
+	lockService := usecase.NewLockService(testLockRepo, testAuditLog, testWebhooks)
+	healthService := usecase.NewHealthService(testMetaDataRepo, testContentRepo)
+	accessService := usecase.NewAccessService(testUserRepo, testPermRepo, accessTokenRepo, testTokenService, testBearerVerifier, []string{testUser1}, testAuditLog)
+	testAccessSvc = accessService
+	testAccessTokenSvc = usecase.NewAccessTokenService(accessTokenRepo, testAuditLog)
+
+	importService := usecase.NewImportService(testMetaDataRepo, testContentRepo, testRepositoryRepo, quotas, policies)
+	repositoryService := usecase.NewRepositoryService(testRepositoryRepo, usecase.NewReferenceService(testMetaDataRepo, testContentRepo), testAuditLog)
+	multipartService := usecase.NewMultipartService(testMetaDataRepo, testContentRepo, adapter.NewMultipartUploadRepository(db, nil), testRepositoryRepo, testTombstoneRepo, policies, testPartSize, testAuditLog, testWebhooks)
+
+	batchController := adapter.NewBatchController(batchService, accessService, nil, testBatchMaxObjects)
+	transferController := adapter.NewTransferController(transferService, accessService, nil)
+	lockController := adapter.NewLockController(lockService, accessService, nil)
+	healthController := adapter.NewHealthController(healthService)
+	multipartController := adapter.NewMultipartController(multipartService, accessService, nil)
+	importController := adapter.NewImportController(importService, accessService)
+	objectController := adapter.NewObjectController(usecase.NewObjectService(testMetaDataRepo, testContentRepo), accessService)
+	accessTokenController := adapter.NewAccessTokenController(testAccessTokenSvc, accessService)
+	repositoryController := adapter.NewRepositoryController(repositoryService, accessService)
+	deletionController := adapter.NewDeletionController(usecase.NewDeletionService(testMetaDataRepo, testContentRepo, testTombstoneRepo, testAuditLog), accessService)
+	auditController := adapter.NewAuditController(testAuditLog, accessService)
+
+	app := newApp(conf, logger, batchController, transferController, multipartController, importController, objectController, lockController, healthController, accessTokenController, repositoryController, deletionController, auditController, accessService)
 	lfsServer = httptest.NewServer(app)
 
 	ret := m.Run()
 
 	lfsServer.Close()
+	testWebhookTarget.server.Close()
 	db.Close()
 
 	os.Remove("lfs-test.db")
@@ -122,18 +209,23 @@ func TestMain(m *testing.M) {
 	os.Exit(ret)
 }
 
+// ownedRepo returns the <owner>/<repo> path of a repo of testUser1, which
+// keys everything of the repo
+func ownedRepo(name string) string {
+	return testUser1 + "/" + name
+}
+
 func seedMetaDataRepository() error {
 
-	/*
-		if err := testMetaDataRepo.AddUser(testUser1, testPass1); err != nil {
-			return err
-		}
-		if err := testMetaDataRepo.AddUser(testUser2, testPass2); err != nil {
-			return err
-		}
-	*/
+	meta := &entity.MetaData{
+		Oid:      testContentOid,
+		Size:     testContentSize,
+		Uploader: entity.User{Name: testUser1},
+		Repos:    []string{ownedRepo(testRepo)},
+		Complete: true,
+	}
 
-	_, err := testMetaDataRepo.Put(testContentOid, testContentSize)
+	_, err := testMetaDataRepo.Put(meta)
 	if err != nil {
 		return err
 	}
@@ -141,6 +233,34 @@ func seedMetaDataRepository() error {
 	return nil
 }
 
+func seedUserRepository() error {
+
+	if err := testUserRepo.AddUser(testUser1, testPass1); err != nil {
+		return err
+	}
+	if err := testUserRepo.AddUser(testUser2, testPass2); err != nil {
+		return err
+	}
+
+	repo := testUser1 + "/" + testRepo
+	if err := testPermRepo.Grant(testUser1, repo, entity.RoleAdmin); err != nil {
+		return err
+	}
+	if err := testPermRepo.Grant(testUser2, repo, entity.RoleRead); err != nil {
+		return err
+	}
+	if err := testPermRepo.Grant(usecase.GroupPrefix+testGroup, repo, entity.RoleWrite); err != nil {
+		return err
+	}
+	for _, r := range []string{testQuotaRepo, testPolicyRepo, testArchivedRepo} {
+		if err := testPermRepo.Grant(testUser1, testUser1+"/"+r, entity.RoleWrite); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func seedLockRepository() error {
 
 	lock := entity.Lock{
@@ -151,7 +271,7 @@ func seedLockRepository() error {
 		},
 		LockedAt: time.Now().Unix(),
 	}
-	if err := testLockRepo.Add(testRepo, lock); err != nil {
+	if err := testLockRepo.Add(ownedRepo(testRepo), lock); err != nil {
 		return err
 	}
 
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+func TestTokenAuth(t *testing.T) {
+
+	token, err := testAccessSvc.IssueToken(testUser1, testUser1+"/"+testRepo, "download")
+	if err != nil {
+		t.Fatalf("expected token, got: %s", err)
+	}
+
+	req := newBatchRequest(t, "download", testContentOid)
+	req.Header.Set("Authorization", "RemoteAuth "+token.Value)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+
+	if res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+}
+
+func TestTokenScopedToOperation(t *testing.T) {
+
+	token, err := testAccessSvc.IssueToken(testUser1, testUser1+"/"+testRepo, "download")
+	if err != nil {
+		t.Fatalf("expected token, got: %s", err)
+	}
+
+	req := newBatchRequest(t, "upload", testNonExistingOid)
+	req.Header.Set("Authorization", "RemoteAuth "+token.Value)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+
+	if res.StatusCode != 403 {
+		t.Fatalf("expected status 403, got %d", res.StatusCode)
+	}
+}
+
+func TestTokenScopedToRepository(t *testing.T) {
+
+	token, err := testTokenService.Issue(testUser1, testUser1+"/other-repo", "download")
+	if err != nil {
+		t.Fatalf("expected token, got: %s", err)
+	}
+
+	req := newBatchRequest(t, "download", testContentOid)
+	req.Header.Set("Authorization", "RemoteAuth "+token.Value)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+
+	if res.StatusCode != 403 {
+		t.Fatalf("expected status 403, got %d", res.StatusCode)
+	}
+}
+
+func TestTokenTampered(t *testing.T) {
+
+	token, err := testAccessSvc.IssueToken(testUser2, testUser1+"/"+testRepo, "download")
+	if err != nil {
+		t.Fatalf("expected token, got: %s", err)
+	}
+
+	// swap the payload for one claiming to be another user
+	other, _ := testTokenService.Issue(testUser1, testUser1+"/"+testRepo, "upload")
+	forged := strings.Split(other.Value, ".")[0] + "." + strings.Split(token.Value, ".")[1]
+
+	req := newBatchRequest(t, "upload", testNonExistingOid)
+	req.Header.Set("Authorization", "RemoteAuth "+forged)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+
+	if res.StatusCode != 401 {
+		t.Fatalf("expected status 401, got %d", res.StatusCode)
+	}
+}
+
+func TestIssueTokenRequiresPermission(t *testing.T) {
+
+	if _, err := testAccessSvc.IssueToken(testUser2, testUser1+"/"+testRepo, "upload"); err == nil {
+		t.Errorf("expected token for upload without write access to fail")
+	}
+}
+
+func TestGitLFSAuthenticateCommand(t *testing.T) {
+
+	os.Remove("lfs-command-test.db")
+	defer os.Remove("lfs-command-test.db")
+
+	config := globalConfig{
+		Server:   serverConfig{Tls: true, Host: "lfs.example.com"},
+		Database: databaseConfig{MetaDB: "lfs-command-test.db"},
+		Auth:     authConfig{TokenSecret: testTokenSecret},
+	}
+
+	var out bytes.Buffer
+	for _, args := range [][]string{
+		{"user", "add", testUser1, testPass1},
+		{"perm", "grant", testUser1, testUser1 + "/" + testRepo, string(entity.RoleWrite)},
+	} {
+		if err := runCommand(config, args, &out); err != nil {
+			t.Fatalf("expected %v to succeed, got: %s", args, err)
+		}
+	}
+
+	// the running server holds the lock on the database
+	db, err := openDatabase(config.Database)
+	if err != nil {
+		t.Fatalf("error opening database: %s", err)
+	}
+	defer db.Close()
+
+	os.Setenv(sshUserEnv, testUser1)
+	os.Setenv("SSH_ORIGINAL_COMMAND", "git-lfs-authenticate "+testUser1+"/"+testRepo+".git upload")
+	defer os.Unsetenv(sshUserEnv)
+	defer os.Unsetenv("SSH_ORIGINAL_COMMAND")
+
+	if err := runCommand(config, []string{"git-lfs-authenticate"}, &out); err != nil {
+		t.Fatalf("expected git-lfs-authenticate to succeed, got: %s", err)
+	}
+
+	var res authenticateResponse
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+		t.Fatalf("expected json response, got: %s", err)
+	}
+
+	if res.Href != "https://lfs.example.com/"+testUser1+"/"+testRepo {
+		t.Errorf("expected href to point at the repo, got: %s", res.Href)
+	}
+
+	auth := res.Header["Authorization"]
+	if !strings.HasPrefix(auth, "RemoteAuth ") {
+		t.Fatalf("expected RemoteAuth header, got: %s", auth)
+	}
+
+	if res.ExpiresIn <= 0 || res.ExpiresAt.Before(time.Now()) {
+		t.Errorf("expected token to expire in the future, got: %v", res.ExpiresAt)
+	}
+
+	claims, err := testTokenService.Verify(strings.TrimPrefix(auth, "RemoteAuth "))
+	if err != nil {
+		t.Fatalf("expected token to verify, got: %s", err)
+	}
+
+	if claims.User != testUser1 || claims.Operation != "upload" {
+		t.Errorf("expected claims to match, got: %v", claims)
+	}
+}
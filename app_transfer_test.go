@@ -3,9 +3,11 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/ikmski/git-lfs3/entity"
@@ -18,6 +20,7 @@ func TestDownload(t *testing.T) {
 	if err != nil {
 		t.Fatalf("request error: %s", err)
 	}
+	req.SetBasicAuth(testUser1, testPass1)
 	req.Header.Set("Accept", "application/vnd.git-lfs")
 
 	res, err := http.DefaultClient.Do(req)
@@ -46,6 +49,7 @@ func TestDownloadWithRange(t *testing.T) {
 	if err != nil {
 		t.Fatalf("request error: %s", err)
 	}
+	req.SetBasicAuth(testUser1, testPass1)
 	req.Header.Set("Accept", "application/vnd.git-lfs")
 
 	fromByte := 5
@@ -81,6 +85,39 @@ func TestDownloadWithRange(t *testing.T) {
 
 }
 
+func TestTransferOtherRepo(t *testing.T) {
+
+	// testUser2 administers a repo named like the one of the object
+	if err := testPermRepo.Grant(testUser2, testUser2+"/"+testRepo, entity.RoleAdmin); err != nil {
+		t.Fatalf("error granting permission: %s", err)
+	}
+
+	requests := []struct {
+		method string
+		path   string
+		accept string
+	}{
+		{"GET", "/objects/" + testContentOid, contentMediaType},
+		{"POST", "/objects/" + testContentOid + "/multipart", metaMediaType},
+		{"PUT", "/objects/" + testContentOid + "/multipart/1", contentMediaType},
+	}
+
+	for _, r := range requests {
+		path := fmt.Sprintf("%s/%s/%s%s", lfsServer.URL, testUser2, testRepo, r.path)
+		req, err := http.NewRequest(r.method, path, strings.NewReader(testContent))
+		if err != nil {
+			t.Fatalf("request error: %s", err)
+		}
+		req.SetBasicAuth(testUser2, testPass2)
+		req.Header.Set("Accept", r.accept)
+
+		res := doRequest(t, req)
+		if res.StatusCode != 404 {
+			t.Errorf("expected status 404 for %s %s, got %d", r.method, r.path, res.StatusCode)
+		}
+	}
+}
+
 func TestUpload(t *testing.T) {
 
 	path := fmt.Sprintf("%s/%s/%s/objects/%s", lfsServer.URL, testUser1, testRepo, testContentOid)
@@ -88,6 +125,7 @@ func TestUpload(t *testing.T) {
 	if err != nil {
 		t.Fatalf("request error: %s", err)
 	}
+	req.SetBasicAuth(testUser1, testPass1)
 	req.Header.Set("Accept", "application/vnd.git-lfs")
 	req.Header.Set("Content-Type", "application/octet-stream")
 	req.Body = ioutil.NopCloser(bytes.NewBuffer([]byte(testContent)))
@@ -128,4 +166,171 @@ func TestUpload(t *testing.T) {
 		t.Logf(string(c))
 		t.Fatalf("expected content, got `%s`", string(c))
 	}
+
+	meta, err := testMetaDataRepo.Get(testContentOid)
+	if err != nil {
+		t.Fatalf("error retreiving from meta store: %s", err)
+	}
+
+	if !meta.Complete {
+		t.Errorf("expected meta to be complete after upload")
+	}
+
+	if meta.StorageClass != "STANDARD" {
+		t.Errorf("expected storage class to be set, got: %s", meta.StorageClass)
+	}
+}
+
+func newUploadRequest(t *testing.T, oid string, body io.Reader) *http.Request {
+
+	path := fmt.Sprintf("%s/%s/%s/objects/%s", lfsServer.URL, testUser1, testRepo, oid)
+	req, err := http.NewRequest("PUT", path, body)
+	if err != nil {
+		t.Fatalf("request error: %s", err)
+	}
+	req.SetBasicAuth(testUser1, testPass1)
+	req.Header.Set("Accept", "application/vnd.git-lfs")
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	return req
+}
+
+func TestUploadSizeMismatch(t *testing.T) {
+
+	oid := "6666666666666666666666666666666666666666666666666666666666666666"
+	meta := &entity.MetaData{Oid: oid, Size: 5, Repos: []string{ownedRepo(testRepo)}}
+	if _, err := testMetaDataRepo.Put(meta); err != nil {
+		t.Fatalf("error seeding meta store: %s", err)
+	}
+
+	// the declared Content-Length disagrees with the batch
+	req := newUploadRequest(t, oid, bytes.NewReader([]byte(testContent)))
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("response error: %s", err)
+	}
+	if res.StatusCode != 400 {
+		t.Fatalf("expected status 400, got %d", res.StatusCode)
+	}
+
+	// without Content-Length, the stream is cut at the registered size
+	req = newUploadRequest(t, oid, io.MultiReader(strings.NewReader(testContent)))
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("response error: %s", err)
+	}
+	if res.StatusCode != 422 {
+		t.Fatalf("expected status 422 for content too large, got %d", res.StatusCode)
+	}
+
+	if testContentRepo.Exists(meta) {
+		t.Errorf("expected content to not be stored")
+	}
+}
+
+func TestUploadTooShort(t *testing.T) {
+
+	oid := "7777777777777777777777777777777777777777777777777777777777777777"
+	meta := &entity.MetaData{Oid: oid, Size: 100, Repos: []string{ownedRepo(testRepo)}}
+	if _, err := testMetaDataRepo.Put(meta); err != nil {
+		t.Fatalf("error seeding meta store: %s", err)
+	}
+
+	req := newUploadRequest(t, oid, io.MultiReader(strings.NewReader(testContent)))
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("response error: %s", err)
+	}
+	if res.StatusCode != 422 {
+		t.Fatalf("expected status 422 for content too short, got %d", res.StatusCode)
+	}
+
+	if testContentRepo.Exists(meta) {
+		t.Errorf("expected content to not be stored")
+	}
+}
+
+func TestVerify(t *testing.T) {
+
+	content := "this content was uploaded directly to storage"
+	oid := multipartOid(content)
+	meta := &entity.MetaData{Oid: oid, Size: int64(len(content)), Repos: []string{ownedRepo(testRepo)}}
+	if _, err := testMetaDataRepo.Put(meta); err != nil {
+		t.Fatalf("error seeding meta store: %s", err)
+	}
+
+	path := fmt.Sprintf("%s/%s/%s/objects/verify", lfsServer.URL, testUser1, testRepo)
+	doVerify := func(size int64) int {
+		body := fmt.Sprintf(`{"oid":%q,"size":%d}`, oid, size)
+		req, err := http.NewRequest("POST", path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("request error: %s", err)
+		}
+		req.SetBasicAuth(testUser1, testPass1)
+		req.Header.Set("Accept", "application/vnd.git-lfs+json")
+		req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("response error: %s", err)
+		}
+		res.Body.Close()
+
+		return res.StatusCode
+	}
+
+	if status := doVerify(meta.Size); status != 404 {
+		t.Errorf("expected status 404 before the upload, got %d", status)
+	}
+
+	if err := testContentRepo.Put(meta, strings.NewReader(content)); err != nil {
+		t.Fatalf("error seeding content store: %s", err)
+	}
+
+	if status := doVerify(meta.Size + 1); status != 422 {
+		t.Errorf("expected status 422 for a wrong size, got %d", status)
+	}
+
+	if status := doVerify(meta.Size); status != 200 {
+		t.Fatalf("expected status 200, got %d", status)
+	}
+
+	meta, err := testMetaDataRepo.Get(oid)
+	if err != nil || !meta.Complete {
+		t.Errorf("expected meta to be complete after verify, got: %+v, %v", meta, err)
+	}
+}
+
+func TestTransferInvalidOid(t *testing.T) {
+
+	for _, oid := range []string{strings.ToUpper(testContentOid), "..secret"} {
+
+		path := fmt.Sprintf("%s/%s/%s/objects/%s", lfsServer.URL, testUser1, testRepo, oid)
+		req, err := http.NewRequest("GET", path, nil)
+		if err != nil {
+			t.Fatalf("request error: %s", err)
+		}
+		req.SetBasicAuth(testUser1, testPass1)
+		req.Header.Set("Accept", "application/vnd.git-lfs")
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("response error: %s", err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode != 422 {
+			t.Errorf("expected status 422 for download of %q, got %d", oid, res.StatusCode)
+		}
+
+		res, err = http.DefaultClient.Do(newUploadRequest(t, oid, strings.NewReader(testContent)))
+		if err != nil {
+			t.Fatalf("response error: %s", err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode != 422 {
+			t.Errorf("expected status 422 for upload of %q, got %d", oid, res.StatusCode)
+		}
+	}
 }
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ikmski/git-lfs3/adapter"
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+// webhookReceiver records the webhook requests it receives
+type webhookReceiver struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	received []*receivedWebhook
+	failures int // number of the next requests answered with status 500
+}
+
+type receivedWebhook struct {
+	path    string
+	header  http.Header
+	body    []byte
+	payload adapter.WebhookPayload
+}
+
+func newWebhookReceiver() *webhookReceiver {
+
+	r := &webhookReceiver{}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+
+		body, _ := ioutil.ReadAll(req.Body)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if r.failures > 0 {
+			r.failures--
+			w.WriteHeader(500)
+			return
+		}
+
+		hook := &receivedWebhook{path: req.URL.Path, header: req.Header, body: body}
+		json.Unmarshal(body, &hook.payload)
+		r.received = append(r.received, hook)
+	}))
+
+	return r
+}
+
+// take returns the received webhooks of the lock or object and forgets them
+func (r *webhookReceiver) take(target string) []*receivedWebhook {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var taken, rest []*receivedWebhook
+	for _, hook := range r.received {
+		p := hook.payload
+		if (p.Lock != nil && p.Lock.Path == target) || (p.Object != nil && p.Object.Oid == target) {
+			taken = append(taken, hook)
+		} else {
+			rest = append(rest, hook)
+		}
+	}
+	r.received = rest
+
+	return taken
+}
+
+func (r *webhookReceiver) fail(n int) {
+
+	r.mu.Lock()
+	r.failures = n
+	r.mu.Unlock()
+}
+
+func deliverWebhooks(t *testing.T, now time.Time) {
+
+	if err := testWebhooks.Deliver(now.Unix()); err != nil {
+		t.Fatalf("expected delivery to succeed, got: %s", err)
+	}
+}
+
+func TestWebhookLocks(t *testing.T) {
+
+	path := "TestWebhookLocks"
+	lock, err := addLock(testUser1, path)
+	if err != nil {
+		t.Fatalf("create lock error: %s", err)
+	}
+	unlock(t, lock.ID, false)
+
+	deliverWebhooks(t, time.Now())
+
+	var received []string
+	for _, hook := range testWebhookTarget.take(path) {
+		received = append(received, hook.path+" "+hook.payload.Event)
+
+		if hook.header.Get(adapter.WebhookEventHeader) != hook.payload.Event || hook.header.Get(adapter.WebhookDeliveryHeader) != hook.payload.ID {
+			t.Errorf("expected event and delivery headers, got: %v", hook.header)
+		}
+		if hook.header.Get(adapter.WebhookSignatureHeader) != "sha256="+adapter.SignWebhookPayload(testWebhookSecret, hook.body) {
+			t.Errorf("expected signed payload, got signature %q", hook.header.Get(adapter.WebhookSignatureHeader))
+		}
+		if hook.payload.Repo != ownedRepo(testRepo) || hook.payload.Actor != testUser1 || hook.payload.Lock.ID != lock.ID || hook.payload.Lock.Owner.Name != testUser1 {
+			t.Errorf("expected lock payload, got: %+v", hook.payload)
+		}
+	}
+
+	// the global webhook is only fired on created locks, and each webhook
+	// receives its events in order
+	sort.SliceStable(received, func(i, j int) bool {
+		return strings.Fields(received[i])[0] < strings.Fields(received[j])[0]
+	})
+	expected := []string{
+		"/locks " + entity.WebhookLockCreated,
+		"/repo " + entity.WebhookLockCreated,
+		"/repo " + entity.WebhookLockReleased,
+	}
+	if strings.Join(received, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, received)
+	}
+}
+
+func TestWebhookObjectUploaded(t *testing.T) {
+
+	content := "webhook content"
+	oid := multipartOid(content)
+	doBatchUpload(t, testRepo, &adapter.ObjectRequest{Oid: oid, Size: int64(len(content))})
+
+	if res := doRequest(t, newUploadRequest(t, oid, strings.NewReader(content))); res.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+
+	deliverWebhooks(t, time.Now())
+
+	received := testWebhookTarget.take(oid)
+	if len(received) != 1 || received[0].payload.Event != entity.WebhookObjectUploaded || received[0].payload.Object.Size != int64(len(content)) {
+		t.Fatalf("expected an upload event, got: %+v", received)
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+
+	// deliver the events of other tests first, so that they do not take the failures
+	for {
+		due, err := testWebhooks.Queue.Due(time.Now().Unix(), 1)
+		if err != nil {
+			t.Fatalf("expected queue, got: %s", err)
+		}
+		if len(due) == 0 {
+			break
+		}
+		deliverWebhooks(t, time.Now())
+	}
+
+	path := "TestWebhookRetry"
+	if _, err := addLock(testUser1, path); err != nil {
+		t.Fatalf("create lock error: %s", err)
+	}
+
+	// both webhooks fail, and their other deliveries wait for the next attempt
+	now := time.Now()
+	testWebhookTarget.fail(2)
+	deliverWebhooks(t, now)
+	if received := testWebhookTarget.take(path); len(received) != 0 {
+		t.Fatalf("expected failed deliveries, got: %d", len(received))
+	}
+
+	// the first retry is due after the backoff
+	deliverWebhooks(t, now.Add(testWebhooks.Backoff/2))
+	if received := testWebhookTarget.take(path); len(received) != 0 {
+		t.Fatalf("expected no retry before the backoff, got: %d", len(received))
+	}
+
+	testWebhookTarget.fail(1)
+	deliverWebhooks(t, now.Add(testWebhooks.Backoff))
+	first := testWebhookTarget.take(path)
+	if len(first) != 1 {
+		t.Fatalf("expected one retry to succeed, got: %d", len(first))
+	}
+
+	// the backoff doubles after the second failure
+	deliverWebhooks(t, now.Add(testWebhooks.Backoff*2))
+	if received := testWebhookTarget.take(path); len(received) != 0 {
+		t.Fatalf("expected no retry before the doubled backoff, got: %d", len(received))
+	}
+
+	deliverWebhooks(t, now.Add(testWebhooks.Backoff*3))
+	second := testWebhookTarget.take(path)
+	if len(second) != 1 || second[0].path == first[0].path {
+		t.Fatalf("expected the other webhook to be retried, got: %d", len(second))
+	}
+
+	// a delivered event is not sent again
+	deliverWebhooks(t, now.Add(time.Hour*24))
+	if received := testWebhookTarget.take(path); len(received) != 0 {
+		t.Errorf("expected no more deliveries, got: %d", len(received))
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	gocontext "context"
+	"net/http"
+	"strings"
+
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+const (
+	remoteAuthScheme = "RemoteAuth"
+	bearerScheme     = "Bearer"
+)
+
+type contextKey int
+
+const (
+	identityContextKey contextKey = iota
+	requestInfoContextKey
+)
+
+// authenticate requires valid Basic credentials, a token issued by
+// git-lfs-authenticate or a bearer JWT for every request handled by next, and
+// makes the authenticated identity available to the handlers.
+func authenticate(access usecase.AccessService, next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		var id *usecase.Identity
+		var err error
+
+		header := r.Header.Get("Authorization")
+		if strings.HasPrefix(header, remoteAuthScheme+" ") {
+			id, err = access.AuthenticateToken(strings.TrimPrefix(header, remoteAuthScheme+" "))
+		} else if strings.HasPrefix(header, bearerScheme+" ") {
+			id, err = access.AuthenticateBearer(strings.TrimPrefix(header, bearerScheme+" "))
+		} else {
+			user, pass, ok := r.BasicAuth()
+			if !ok {
+				requireAuth(w, r)
+				return
+			}
+			id, err = access.Authenticate(user, pass)
+		}
+
+		if err != nil {
+			requireAuth(w, r)
+			return
+		}
+
+		if info, ok := r.Context().Value(requestInfoContextKey).(*requestInfo); ok {
+			info.user = id.User
+		}
+
+		ctx := gocontext.WithValue(r.Context(), identityContextKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func requireAuth(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("WWW-Authenticate", `Basic realm="git-lfs3"`)
+	w.Header().Set("LFS-Authenticate", `Basic realm="git-lfs3"`)
+	w.Header().Set("Content-Type", metaMediaType)
+	w.WriteHeader(http.StatusUnauthorized)
+	w.Write([]byte(`{"message":"Credentials needed"}`))
+}
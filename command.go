@@ -0,0 +1,422 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ikmski/git-lfs3/adapter"
+	"github.com/ikmski/git-lfs3/entity"
+	"github.com/ikmski/git-lfs3/usecase"
+)
+
+const commandUsage = `usage:
+  git-lfs3                                   run the server
+  git-lfs3 user add <name> <password>        add a user or change its password
+  git-lfs3 user delete <name>                delete a user
+  git-lfs3 user list                         list users
+  git-lfs3 perm grant <user> <repo> <role>   grant read, write or admin on <owner>/<repo>,
+                                             use @<group> to grant to an OIDC group
+  git-lfs3 perm revoke <user> <repo>         revoke the role of a user on <owner>/<repo>
+  git-lfs3 perm list <repo>                  list the permissions on <owner>/<repo>
+  git-lfs3 token create [-repo <repo>] [-expires <duration>] <user> <name> <scope>...
+                                             create a personal access token with the read,
+                                             write and/or lock scopes
+  git-lfs3 token list <user>                 list the personal access tokens of a user
+  git-lfs3 token revoke <user> <id>          revoke a personal access token
+  git-lfs3 repo create <repo>                register the repository <owner>/<repo>
+  git-lfs3 repo list                         list registered repositories
+  git-lfs3 repo rename <repo> <new name>     rename a repository with its locks and objects
+  git-lfs3 repo archive <repo>               make a repository read-only
+  git-lfs3 repo unarchive <repo>             make a repository writable again
+  git-lfs3 repo delete <repo>                delete a repository with its locks and objects
+  git-lfs3 git-lfs-authenticate <repo> <operation>
+                                             issue a token for the SSH user in $GIT_LFS3_USER;
+                                             without arguments, $SSH_ORIGINAL_COMMAND is used`
+
+// sshUserEnv names the environment variable holding the user of an SSH key,
+// as set by the forced command in authorized_keys
+const sshUserEnv = "GIT_LFS3_USER"
+
+var errUsage = errors.New(commandUsage)
+
+type authenticateResponse struct {
+	Href      string            `json:"href"`
+	Header    map[string]string `json:"header"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	ExpiresIn int64             `json:"expires_in"`
+}
+
+// runCommand runs an administrative command against the database.
+// The server must not be running, as it holds the lock on the database,
+// except for git-lfs-authenticate, which does not use the database.
+func runCommand(config globalConfig, args []string, w io.Writer) error {
+
+	if args[0] == "git-lfs-authenticate" {
+		if len(args) == 1 {
+			// run as forced command from authorized_keys
+			args = strings.Fields(os.Getenv("SSH_ORIGINAL_COMMAND"))
+			if len(args) == 0 || args[0] != "git-lfs-authenticate" {
+				return errUsage
+			}
+		}
+		if len(args) != 3 {
+			return errUsage
+		}
+		return gitLFSAuthenticate(config, os.Getenv(sshUserEnv), args[1], args[2], w)
+	}
+
+	if len(args) < 2 {
+		return errUsage
+	}
+
+	db, err := openDatabase(config.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	userRepo := adapter.NewUserRepository(db, nil)
+	permissionRepo := adapter.NewPermissionRepository(db, nil)
+	accessTokenService := usecase.NewAccessTokenService(adapter.NewAccessTokenRepository(db, nil), nil)
+	repositoryRepo := adapter.NewRepositoryRepository(db, nil)
+
+	audit, err := newAuditLog(config.Audit, db, nil, func(err error) {
+		log.Printf("failed to record audit event: %s", err)
+	})
+	if err != nil {
+		return err
+	}
+
+	switch args[0] + " " + args[1] {
+
+	case "user add":
+		if len(args) != 4 {
+			return errUsage
+		}
+		if strings.HasPrefix(args[2], usecase.GroupPrefix) {
+			return fmt.Errorf("User names must not start with %s", usecase.GroupPrefix)
+		}
+		err = userRepo.AddUser(args[2], args[3])
+		if err != nil {
+			return err
+		}
+		audit.Record(commandEvent(entity.AuditUserAdd, "", args[2], nil))
+		return nil
+
+	case "user delete":
+		if len(args) != 3 {
+			return errUsage
+		}
+		err = userRepo.DeleteUser(args[2])
+		if err != nil {
+			return err
+		}
+		audit.Record(commandEvent(entity.AuditUserDelete, "", args[2], nil))
+		return nil
+
+	case "user list":
+		users, err := userRepo.Users()
+		if err != nil {
+			return err
+		}
+		for _, u := range users {
+			fmt.Fprintln(w, u.Name)
+		}
+		return nil
+
+	case "perm grant":
+		if len(args) != 5 {
+			return errUsage
+		}
+		err = permissionRepo.Grant(args[2], args[3], entity.Role(args[4]))
+		if err != nil {
+			return err
+		}
+		audit.Record(commandEvent(entity.AuditPermissionGrant, args[3], args[2], map[string]string{"role": args[4]}))
+		return nil
+
+	case "perm revoke":
+		if len(args) != 4 {
+			return errUsage
+		}
+		err = permissionRepo.Revoke(args[2], args[3])
+		if err != nil {
+			return err
+		}
+		audit.Record(commandEvent(entity.AuditPermissionRevoke, args[3], args[2], nil))
+		return nil
+
+	case "perm list":
+		if len(args) != 3 {
+			return errUsage
+		}
+		perms, err := permissionRepo.Permissions(args[2])
+		if err != nil {
+			return err
+		}
+		for _, p := range perms {
+			fmt.Fprintf(w, "%s\t%s\n", p.User, p.Role)
+		}
+		return nil
+
+	case "token create":
+		req, err := parseTokenCreateArgs(args[2:])
+		if err != nil {
+			return err
+		}
+		req.Actor = commandActor()
+		result, err := accessTokenService.Create(req)
+		if err != nil {
+			return err
+		}
+		token := result.Token
+		audit.Record(commandEvent(entity.AuditTokenCreate, token.Repo, token.User, map[string]string{"id": token.ID, "name": token.Name, "scopes": joinScopes(token.Scopes)}))
+		fmt.Fprintln(w, result.Value)
+		return nil
+
+	case "token list":
+		if len(args) != 3 {
+			return errUsage
+		}
+		tokens, err := accessTokenService.Tokens(args[2])
+		if err != nil {
+			return err
+		}
+		for _, t := range tokens {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				t.ID, t.Name, joinScopes(t.Scopes), orDash(t.Repo), formatUnix(t.ExpiresAt), formatUnix(t.LastUsedAt))
+		}
+		return nil
+
+	case "token revoke":
+		if len(args) != 4 {
+			return errUsage
+		}
+		err = accessTokenService.Revoke(args[2], args[3], commandActor())
+		if err != nil {
+			return err
+		}
+		audit.Record(commandEvent(entity.AuditTokenRevoke, "", args[2], map[string]string{"id": args[3]}))
+		return nil
+
+	case "repo create":
+		if len(args) != 3 {
+			return errUsage
+		}
+		_, err := usecase.NewRepositoryService(repositoryRepo, nil, nil).Create(args[2], commandActor())
+		if err != nil {
+			return err
+		}
+		audit.Record(commandEvent(entity.AuditRepoCreate, args[2], "", nil))
+		return nil
+
+	case "repo list":
+		repos, err := repositoryRepo.Repositories()
+		if err != nil {
+			return err
+		}
+		for _, r := range repos {
+			state := "active"
+			if r.Archived {
+				state = "archived"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r.Name, state, formatUnix(r.CreatedAt))
+		}
+		return nil
+
+	case "repo rename":
+		if len(args) != 4 {
+			return errUsage
+		}
+		_, err := usecase.NewRepositoryService(repositoryRepo, nil, nil).Rename(args[2], args[3], commandActor())
+		if err != nil {
+			return err
+		}
+		audit.Record(commandEvent(entity.AuditRepoRename, args[2], "", map[string]string{"new_name": args[3]}))
+		return nil
+
+	case "repo archive", "repo unarchive":
+		if len(args) != 3 {
+			return errUsage
+		}
+		archived := args[1] == "archive"
+		err = repositoryRepo.SetArchived(args[2], archived)
+		if err != nil {
+			return err
+		}
+		action := entity.AuditRepoArchive
+		if !archived {
+			action = entity.AuditRepoUnarchive
+		}
+		audit.Record(commandEvent(action, args[2], "", nil))
+		return nil
+
+	case "repo delete":
+		if len(args) != 3 {
+			return errUsage
+		}
+		// deleting the objects of the repo needs the content store too
+		logger, err := newLogger(config.Log)
+		if err != nil {
+			return err
+		}
+		contentRepo, err := adapter.NewContentRepository(config.S3.Bucket, logger, nil)
+		if err != nil {
+			return err
+		}
+		refs := usecase.NewReferenceService(adapter.NewMetaDataRepository(db, nil), contentRepo)
+		err = usecase.NewRepositoryService(repositoryRepo, refs, nil).Delete(args[2], commandActor())
+		if err != nil {
+			return err
+		}
+		audit.Record(commandEvent(entity.AuditRepoDelete, args[2], "", nil))
+		return nil
+	}
+
+	return errUsage
+}
+
+func parseTokenCreateArgs(args []string) (*usecase.AccessTokenRequest, error) {
+
+	flags := flag.NewFlagSet("token create", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	repo := flags.String("repo", "", "")
+	expires := flags.Duration("expires", 0, "")
+
+	err := flags.Parse(args)
+	if err != nil || flags.NArg() < 3 {
+		return nil, errUsage
+	}
+
+	req := &usecase.AccessTokenRequest{
+		User: flags.Arg(0),
+		Name: flags.Arg(1),
+		Repo: *repo,
+		TTL:  *expires,
+	}
+	for _, s := range flags.Args()[2:] {
+		req.Scopes = append(req.Scopes, entity.Scope(s))
+	}
+
+	return req, nil
+}
+
+func joinScopes(scopes []entity.Scope) string {
+
+	var s []string
+	for _, scope := range scopes {
+		s = append(s, string(scope))
+	}
+
+	return strings.Join(s, ",")
+}
+
+func formatUnix(t int64) string {
+
+	if t == 0 {
+		return "-"
+	}
+
+	return time.Unix(t, 0).UTC().Format(time.RFC3339)
+}
+
+func orDash(s string) string {
+
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+// commandEvent returns the audit event of a command. Commands are run by
+// system users rather than users of the server, so the actor is the
+// system user in $USER.
+func commandEvent(action string, repo string, target string, details map[string]string) *entity.AuditEvent {
+
+	actor := commandActor()
+
+	if details == nil {
+		details = make(map[string]string)
+	}
+	details["via"] = "cli"
+
+	return &entity.AuditEvent{
+		Actor:   actor,
+		Action:  action,
+		Repo:    repo,
+		Target:  target,
+		Details: details,
+	}
+}
+
+// commandActor returns the system user running the command
+func commandActor() string {
+
+	actor := os.Getenv("USER")
+	if actor == "" {
+		return "cli"
+	}
+
+	return actor
+}
+
+// gitLFSAuthenticate writes the response git-lfs expects from the
+// git-lfs-authenticate command run over SSH.
+// The token is signed without opening the database, which the running server
+// holds the lock on. It only narrows the access of the user to the repo and
+// operation, so the server checks the permissions of the user on every request.
+func gitLFSAuthenticate(config globalConfig, user string, repo string, operation string, w io.Writer) error {
+
+	if user == "" {
+		return fmt.Errorf("%s is not set", sshUserEnv)
+	}
+
+	if operation != "upload" && operation != "download" {
+		return fmt.Errorf("Unknown operation: %s", operation)
+	}
+
+	repo = strings.Trim(strings.TrimSuffix(repo, ".git"), "/")
+	if !entity.ValidRepositoryPath(repo) {
+		return fmt.Errorf("Invalid repository: %s", repo)
+	}
+
+	token, err := newTokenService(config.Auth).Issue(user, repo, operation)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Unix(token.ExpiresAt, 0)
+	res := &authenticateResponse{
+		Href: fmt.Sprintf("%s/%s", baseURL(config.Server), repo),
+		Header: map[string]string{
+			"Authorization": remoteAuthScheme + " " + token.Value,
+		},
+		ExpiresAt: expiresAt.UTC(),
+		ExpiresIn: int64(time.Until(expiresAt) / time.Second),
+	}
+
+	return json.NewEncoder(w).Encode(res)
+}
+
+func baseURL(conf serverConfig) string {
+
+	host := conf.Host
+	if host == "" {
+		host = fmt.Sprintf("localhost:%d", conf.Port)
+	}
+
+	if conf.Tls {
+		return "https://" + host
+	}
+
+	return "http://" + host
+}
@@ -1,17 +1,65 @@
 package main
 
+import (
+	"time"
+
+	"github.com/ikmski/git-lfs3/entity"
+)
+
 type globalConfig struct {
 	Server   serverConfig
 	Database databaseConfig
 	S3       s3Config
+	Log      logConfig
+	Metrics  metricsConfig
+	Auth     authConfig
+	OIDC     oidcConfig
+	Quota    quotaConfig
+	Policy   policyConfig
+	Transfer transferConfig
+	Batch    batchConfig
+	Audit    auditConfig
+	Webhook  webhookConfig
 }
 
 type serverConfig struct {
-	Tls      bool   `toml:"tls"`
-	Port     int    `toml:"port"`
-	Host     string `toml:"host"`
-	CertFile string `toml:"cert_file"`
-	KeyFile  string `toml:"key_file"`
+	Tls               bool            `toml:"tls"`
+	Port              int             `toml:"port"`
+	Host              string          `toml:"host"`
+	CertFile          string          `toml:"cert_file"`
+	KeyFile           string          `toml:"key_file"`
+	ReadHeaderTimeout duration        `toml:"read_header_timeout"`
+	IdleTimeout       duration        `toml:"idle_timeout"`
+	ShutdownTimeout   duration        `toml:"shutdown_timeout"`
+	ShutdownDelay     duration        `toml:"shutdown_delay"` // time between failing readiness and closing the listener
+	BodyLimits        bodyLimitConfig `toml:"body_limits"`
+}
+
+// bodyLimitConfig sets the maximum size of JSON request bodies per
+// endpoint, in bytes. Uploaded content is limited by the batch instead.
+type bodyLimitConfig struct {
+	Batch   int64 `toml:"batch"`   // defaults to 16 MiB
+	Locks   int64 `toml:"locks"`   // defaults to 64 KiB
+	Admin   int64 `toml:"admin"`   // defaults to 64 KiB
+	Default int64 `toml:"default"` // other endpoints, defaults to 1 MiB
+}
+
+func (c bodyLimitConfig) withDefaults() bodyLimitConfig {
+
+	if c.Batch <= 0 {
+		c.Batch = 16 << 20
+	}
+	if c.Locks <= 0 {
+		c.Locks = 64 << 10
+	}
+	if c.Admin <= 0 {
+		c.Admin = 64 << 10
+	}
+	if c.Default <= 0 {
+		c.Default = 1 << 20
+	}
+
+	return c
 }
 
 type databaseConfig struct {
@@ -24,3 +72,119 @@ type s3Config struct {
 	Region             string `toml:"region"`
 	Bucket             string `toml:"bucket"`
 }
+
+type logConfig struct {
+	Level  string `toml:"level"`  // debug, info, warn or error
+	Format string `toml:"format"` // json or logfmt
+	Output string `toml:"output"` // stdout, stderr or a file path
+}
+
+type authConfig struct {
+	TokenSecret string   `toml:"token_secret"` // key for signing git-lfs-authenticate tokens
+	TokenTTL    duration `toml:"token_ttl"`    // defaults to 5 minutes
+	Admins      []string `toml:"admins"`       // users allowed to manage the tokens of other users
+}
+
+// oidcConfig enables bearer JWTs when either jwks_file or jwks_url is set,
+// which requires the issuer and the audience as well
+type oidcConfig struct {
+	Issuer      string   `toml:"issuer"`
+	Audience    string   `toml:"audience"`
+	UserClaim   string   `toml:"user_claim"`   // defaults to sub
+	GroupsClaim string   `toml:"groups_claim"` // defaults to groups
+	JWKSFile    string   `toml:"jwks_file"`
+	JWKSURL     string   `toml:"jwks_url"`
+	JWKSRefresh duration `toml:"jwks_refresh"` // defaults to 1 hour
+}
+
+// quotaConfig sets storage quotas, in bytes and objects, for all repos and
+// users and overrides them for single ones. Zero values are unlimited.
+type quotaConfig struct {
+	RepoSize    int64                 `toml:"repo_size"`
+	RepoObjects int64                 `toml:"repo_objects"`
+	UserSize    int64                 `toml:"user_size"`
+	UserObjects int64                 `toml:"user_objects"`
+	Repos       map[string]quotaLimit `toml:"repos"` // keyed by <owner>/<repo>
+	Users       map[string]quotaLimit `toml:"users"`
+}
+
+type quotaLimit struct {
+	Size    int64 `toml:"size"`
+	Objects int64 `toml:"objects"`
+}
+
+// policyConfig restricts uploads to all repos, repos add to it
+type policyConfig struct {
+	uploadPolicy
+	Repos map[string]uploadPolicy `toml:"repos"` // keyed by <owner>/<repo>
+}
+
+type uploadPolicy struct {
+	MaxObjectSize     int64    `toml:"max_object_size"` // bytes, 0 if unlimited
+	AllowedExtensions []string `toml:"allowed_extensions"`
+	DeniedExtensions  []string `toml:"denied_extensions"`
+	ReadOnly          bool     `toml:"read_only"`
+	Archived          bool     `toml:"archived"`
+}
+
+type batchConfig struct {
+	Concurrency int `toml:"concurrency"` // existence checks run at a time per batch, defaults to 16
+	MaxObjects  int `toml:"max_objects"` // objects per batch request, defaults to 10000
+}
+
+type transferConfig struct {
+	Multipart         bool     `toml:"multipart"`           // offer the resumable multipart transfer adapter
+	MultipartPartSize int64    `toml:"multipart_part_size"` // bytes, defaults to 16 MiB, at least 5 MiB
+	Presigned         bool     `toml:"presigned"`           // link basic transfers to S3 instead of the server
+	PresignExpiry     duration `toml:"presign_expiry"`      // defaults to 15 minutes
+}
+
+// auditConfig enables the sinks of the audit log. The admin API queries
+// the database when it is enabled, the file otherwise.
+type auditConfig struct {
+	Database bool   `toml:"database"` // store events in the meta data database
+	File     string `toml:"file"`     // append events as JSON lines to the file
+}
+
+// webhookConfig sets the endpoints notified of lock and object events.
+// Failed deliveries are retried with exponential backoff.
+type webhookConfig struct {
+	MaxAttempts int               `toml:"max_attempts"` // defaults to 10
+	Backoff     duration          `toml:"backoff"`      // delay of the first retry, defaults to 10 seconds
+	MaxBackoff  duration          `toml:"max_backoff"`  // defaults to 1 hour
+	Timeout     duration          `toml:"timeout"`      // per request, defaults to 10 seconds
+	Endpoints   []webhookEndpoint `toml:"endpoints"`
+}
+
+type webhookEndpoint struct {
+	URL    string   `toml:"url"`
+	Secret string   `toml:"secret"` // key for the HMAC-SHA256 signature of the payloads
+	Repos  []string `toml:"repos"`  // <owner>/<repo> paths, all repos if empty
+	Events []string `toml:"events"` // e.g. lock.created or object.uploaded, all events if empty
+}
+
+type metricsConfig struct {
+	Enabled bool   `toml:"enabled"`
+	Path    string `toml:"path"` // defaults to /metrics
+}
+
+// duration is a time.Duration written as a string such as "30s" in the config
+type duration struct {
+	time.Duration
+}
+
+func (d *duration) UnmarshalText(text []byte) error {
+	var err error
+	d.Duration, err = time.ParseDuration(string(text))
+	return err
+}
+
+func (p uploadPolicy) entity() entity.UploadPolicy {
+	return entity.UploadPolicy{
+		MaxSize:           p.MaxObjectSize,
+		AllowedExtensions: p.AllowedExtensions,
+		DeniedExtensions:  p.DeniedExtensions,
+		ReadOnly:          p.ReadOnly,
+		Archived:          p.Archived,
+	}
+}
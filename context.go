@@ -7,11 +7,14 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/ikmski/git-lfs3/adapter"
+	"github.com/ikmski/git-lfs3/usecase"
 )
 
 type context struct {
-	w http.ResponseWriter
-	r *http.Request
+	w     http.ResponseWriter
+	r     *http.Request
+	limit int64 // the maximum size of the request body, unlimited if zero
+	body  io.Reader
 }
 
 func newContext(w http.ResponseWriter, r *http.Request) adapter.Context {
@@ -21,6 +24,16 @@ func newContext(w http.ResponseWriter, r *http.Request) adapter.Context {
 	return ctx
 }
 
+// newLimitedContext returns a context whose request body fails with
+// adapter.ErrBodyTooLarge when it is larger than limit
+func newLimitedContext(w http.ResponseWriter, r *http.Request, limit int64) adapter.Context {
+	ctx := new(context)
+	ctx.w = w
+	ctx.r = r
+	ctx.limit = limit
+	return ctx
+}
+
 func (ctx *context) GetHeader(s string) string {
 	return ctx.r.Header.Get(s)
 }
@@ -38,10 +51,26 @@ func (ctx *context) GetParam(s string) string {
 
 func (ctx *context) GetRawData() ([]byte, error) {
 	buf := new(bytes.Buffer)
-	io.Copy(buf, ctx.r.Body)
+	_, err := io.Copy(buf, ctx.GetRequestReader())
+	if err != nil {
+		return nil, err
+	}
 	return buf.Bytes(), nil
 }
 
+func (ctx *context) GetUser() string {
+	id := ctx.GetIdentity()
+	if id == nil {
+		return ""
+	}
+	return id.User
+}
+
+func (ctx *context) GetIdentity() *usecase.Identity {
+	id, _ := ctx.r.Context().Value(identityContextKey).(*usecase.Identity)
+	return id
+}
+
 func (ctx *context) SetStatus(s int) {
 	ctx.w.WriteHeader(s)
 }
@@ -50,10 +79,46 @@ func (ctx *context) SetHeader(key string, val string) {
 	ctx.w.Header().Set(key, val)
 }
 
+func (ctx *context) GetBaseURL() string {
+
+	scheme := "http"
+	if ctx.r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := ctx.r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+
+	return scheme + "://" + ctx.r.Host
+}
+
 func (ctx *context) GetResponseWriter() io.Writer {
 	return ctx.w
 }
 
 func (ctx *context) GetRequestReader() io.Reader {
-	return ctx.r.Body
+	if ctx.body == nil {
+		ctx.body = ctx.r.Body
+		if ctx.limit > 0 {
+			ctx.body = &bodyLimitReader{r: http.MaxBytesReader(ctx.w, ctx.r.Body, ctx.limit), limit: ctx.limit}
+		}
+	}
+	return ctx.body
+}
+
+// bodyLimitReader reports the failure of http.MaxBytesReader to read beyond
+// its limit as adapter.ErrBodyTooLarge
+type bodyLimitReader struct {
+	r     io.Reader
+	n     int64
+	limit int64
+}
+
+func (b *bodyLimitReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.n += int64(n)
+	if err != nil && err != io.EOF && b.n >= b.limit {
+		return n, adapter.ErrBodyTooLarge
+	}
+	return n, err
 }
@@ -0,0 +1,44 @@
+package entity
+
+// Scope is ...
+type Scope string
+
+// Scopes of personal access tokens
+const (
+	ScopeRead  Scope = "read"
+	ScopeWrite Scope = "write"
+	ScopeLock  Scope = "lock"
+)
+
+// AccessToken is a personal access token of a user.
+// Only a hash of the secret part of the token is ever stored.
+type AccessToken struct {
+	ID         string
+	User       string
+	Name       string
+	Scopes     []Scope
+	Repo       string // the only repository the token is valid for, if not empty
+	CreatedAt  int64  // UnixTime
+	ExpiresAt  int64  // UnixTime, 0 if the token does not expire
+	LastUsedAt int64  // UnixTime, 0 if the token was never used
+}
+
+// Valid reports whether the scope is one of the known scopes
+func (s Scope) Valid() bool {
+	switch s {
+	case ScopeRead, ScopeWrite, ScopeLock:
+		return true
+	}
+	return false
+}
+
+// Allows reports whether the scope grants the access of required.
+// The write scope includes read, so that a token can push and fetch.
+func (s Scope) Allows(required Scope) bool {
+	return s == required || (s == ScopeWrite && required == ScopeRead)
+}
+
+// Expired reports whether the token has expired at the given time
+func (t *AccessToken) Expired(now int64) bool {
+	return t.ExpiresAt != 0 && now >= t.ExpiresAt
+}
@@ -0,0 +1,34 @@
+package entity
+
+// AuditEvent records an action taken on the server
+type AuditEvent struct {
+	Time    int64  // UnixTime
+	Actor   string // the user who took the action, empty if unknown
+	Action  string
+	Repo    string // empty for actions outside repos
+	Target  string // the oid, lock path or user the action was taken on
+	Details map[string]string
+}
+
+// Actions of audit events
+const (
+	AuditLockCreate       = "lock.create"
+	AuditLockRelease      = "lock.release"
+	AuditLockForceRelease = "lock.force_release"
+	AuditObjectUpload     = "object.upload"
+	AuditObjectLink       = "object.link" // stored content added to another repo
+	AuditObjectDelete     = "object.delete"
+	AuditTombstoneClear   = "object.tombstone_clear"
+	AuditUserAdd          = "user.add"
+	AuditUserDelete       = "user.delete"
+	AuditPermissionGrant  = "permission.grant"
+	AuditPermissionRevoke = "permission.revoke"
+	AuditTokenCreate      = "token.create"
+	AuditTokenRevoke      = "token.revoke"
+	AuditRepoCreate       = "repo.create"
+	AuditRepoRename       = "repo.rename"
+	AuditRepoArchive      = "repo.archive"
+	AuditRepoUnarchive    = "repo.unarchive"
+	AuditRepoDelete       = "repo.delete"
+	AuditLoginFailed      = "login.failed"
+)
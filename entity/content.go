@@ -1,5 +1,9 @@
 package entity
 
-// Content is ...
+// Content is the stored content of an object as reported by the storage
 type Content struct {
+	Size         int64
+	ETag         string
+	StorageClass string
+	LastModified int64 // UnixTime
 }
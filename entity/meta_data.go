@@ -2,6 +2,39 @@ package entity
 
 // MetaData is ...
 type MetaData struct {
-	Oid  string
-	Size int64
+	Oid            string
+	Size           int64
+	CreatedAt      int64 // UnixTime
+	Uploader       User
+	Repos          []string
+	LastAccessedAt int64 // UnixTime
+	StorageClass   string
+	Complete       bool
+	Shared         bool // stored before objects were tracked by repo
+}
+
+// InRepo reports whether the object can be used in the repo. Shared objects
+// belong to every repo.
+func (m *MetaData) InRepo(repo string) bool {
+
+	return m.Shared || containsString(m.Repos, repo)
+}
+
+// HashAlgoSHA256 is the hash algorithm OIDs are computed with
+const HashAlgoSHA256 = "sha256"
+
+// ValidOid reports whether oid is a SHA-256 hash in 64 lowercase hex characters
+func ValidOid(oid string) bool {
+
+	if len(oid) != 64 {
+		return false
+	}
+
+	for _, c := range oid {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+
+	return true
 }
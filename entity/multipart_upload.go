@@ -0,0 +1,59 @@
+package entity
+
+// MultipartUpload is the state of a resumable upload of an object in parts
+type MultipartUpload struct {
+	Oid       string
+	Size      int64
+	UploadID  string // the id of the upload in the content store
+	PartSize  int64
+	Parts     []UploadPart
+	HashState []byte // the state of the hash of the parts uploaded so far
+	CreatedAt int64  // UnixTime
+}
+
+// UploadPart is ...
+type UploadPart struct {
+	Number int
+	Size   int64
+	ETag   string
+}
+
+// PartCount returns the number of parts the object is uploaded in
+func (u *MultipartUpload) PartCount() int {
+
+	if u.Size == 0 || u.PartSize <= 0 {
+		return 1
+	}
+
+	return int((u.Size + u.PartSize - 1) / u.PartSize)
+}
+
+// PartLength returns the size of the part with the number, starting at 1
+func (u *MultipartUpload) PartLength(number int) int64 {
+
+	if number < 1 || number > u.PartCount() {
+		return 0
+	}
+
+	if number < u.PartCount() {
+		return u.PartSize
+	}
+
+	return u.Size - int64(number-1)*u.PartSize
+}
+
+// NextPart returns the number of the next part to upload
+func (u *MultipartUpload) NextPart() int {
+	return len(u.Parts) + 1
+}
+
+// Uploaded returns the number of bytes uploaded so far
+func (u *MultipartUpload) Uploaded() int64 {
+
+	var n int64
+	for _, p := range u.Parts {
+		n += p.Size
+	}
+
+	return n
+}
@@ -0,0 +1,40 @@
+package entity
+
+// Role is ...
+type Role string
+
+// Roles in ascending order of access
+const (
+	RoleRead  Role = "read"
+	RoleWrite Role = "write"
+	RoleAdmin Role = "admin"
+)
+
+// Permission is ...
+type Permission struct {
+	User string
+	Repo string
+	Role Role
+}
+
+// Allows reports whether the role grants the access of required
+func (r Role) Allows(required Role) bool {
+	return r.level() > 0 && r.level() >= required.level()
+}
+
+// Valid reports whether the role is one of the known roles
+func (r Role) Valid() bool {
+	return r.level() > 0
+}
+
+func (r Role) level() int {
+	switch r {
+	case RoleRead:
+		return 1
+	case RoleWrite:
+		return 2
+	case RoleAdmin:
+		return 3
+	}
+	return 0
+}
@@ -0,0 +1,32 @@
+package entity
+
+// Usage is the storage used by a repo or user
+type Usage struct {
+	Size    int64
+	Objects int64
+}
+
+// Quota limits the storage of a repo or user. Zero values are unlimited.
+type Quota struct {
+	Size    int64
+	Objects int64
+}
+
+// Allows reports whether the usage stays within the quota
+func (q Quota) Allows(u Usage) bool {
+
+	if q.Size > 0 && u.Size > q.Size {
+		return false
+	}
+
+	if q.Objects > 0 && u.Objects > q.Objects {
+		return false
+	}
+
+	return true
+}
+
+// Unlimited reports whether the quota sets no limit
+func (q Quota) Unlimited() bool {
+	return q.Size <= 0 && q.Objects <= 0
+}
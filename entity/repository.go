@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"strings"
+)
+
+// Repository is a repository registered with the server.
+// Objects cannot be uploaded to archived repositories.
+type Repository struct {
+	Name      string // the <owner>/<repo> path
+	Archived  bool
+	CreatedAt int64 // UnixTime
+}
+
+// ValidRepositoryName reports whether name can name a repository, i.e. it
+// is not empty, not a relative path element and contains no slash.
+func ValidRepositoryName(name string) bool {
+
+	if name == "" || name == "." || name == ".." {
+		return false
+	}
+
+	return !strings.Contains(name, "/")
+}
+
+// ValidRepositoryPath reports whether path is an <owner>/<repo> path of
+// valid names. Repositories are named and keyed by their path.
+func ValidRepositoryPath(path string) bool {
+
+	parts := strings.Split(path, "/")
+
+	return len(parts) == 2 && ValidRepositoryName(parts[0]) && ValidRepositoryName(parts[1])
+}
@@ -0,0 +1,29 @@
+package entity
+
+// Tombstone marks an object deleted by an admin, so that its OID cannot be
+// uploaded again until the tombstone is cleared
+type Tombstone struct {
+	Oid       string
+	Size      int64
+	DeletedBy string
+	DeletedAt int64 // UnixTime
+	Reason    string
+}
+
+// Actions of object deletion records
+const (
+	DeletionActionDelete = "delete"
+	DeletionActionClear  = "clear"
+)
+
+// ObjectDeletion records the deletion of an object, or the clearing of its
+// tombstone, by an admin
+type ObjectDeletion struct {
+	Action string
+	Oid    string
+	Size   int64
+	Repos  []string // the repos that referenced the deleted object
+	User   string
+	Reason string
+	Time   int64 // UnixTime
+}
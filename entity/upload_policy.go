@@ -0,0 +1,47 @@
+package entity
+
+import (
+	"path"
+	"strings"
+)
+
+// UploadPolicy restricts the objects that can be uploaded to a repo
+type UploadPolicy struct {
+	MaxSize           int64    // 0 if unlimited
+	AllowedExtensions []string // any extension is allowed if empty
+	DeniedExtensions  []string
+	ReadOnly          bool
+	Archived          bool
+}
+
+// AllowsPath reports whether an object with the path may be uploaded.
+// Extensions are compared case insensitively, with or without leading dot.
+func (p *UploadPolicy) AllowsPath(filePath string) bool {
+
+	ext := normalizeExtension(path.Ext(filePath))
+
+	if containsExtension(p.DeniedExtensions, ext) {
+		return false
+	}
+
+	if len(p.AllowedExtensions) > 0 && !containsExtension(p.AllowedExtensions, ext) {
+		return false
+	}
+
+	return true
+}
+
+func containsExtension(exts []string, ext string) bool {
+
+	for _, e := range exts {
+		if normalizeExtension(e) == ext {
+			return true
+		}
+	}
+
+	return false
+}
+
+func normalizeExtension(ext string) string {
+	return strings.ToLower(strings.TrimPrefix(ext, "."))
+}
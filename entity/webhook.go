@@ -0,0 +1,69 @@
+package entity
+
+// Events webhooks are fired on
+const (
+	WebhookLockCreated       = "lock.created"
+	WebhookLockReleased      = "lock.released"
+	WebhookLockForceReleased = "lock.force_released"
+	WebhookObjectUploaded    = "object.uploaded"
+	WebhookObjectVerified    = "object.verified" // uploaded directly to storage by the client
+)
+
+// Webhook is an endpoint notified of events, identified by its URL
+type Webhook struct {
+	URL    string
+	Secret string   // key for signing the payloads
+	Repos  []string // all repos if empty
+	Events []string // all events if empty
+}
+
+// Matches reports whether the webhook is fired on the event in the repo
+func (w *Webhook) Matches(event string, repo string) bool {
+
+	if len(w.Repos) > 0 && !containsString(w.Repos, repo) {
+		return false
+	}
+
+	if len(w.Events) > 0 && !containsString(w.Events, event) {
+		return false
+	}
+
+	return true
+}
+
+// WebhookEvent is ...
+type WebhookEvent struct {
+	Event  string
+	Repo   string
+	Actor  string
+	Time   int64 // UnixTime
+	Lock   *Lock
+	Object *WebhookObject
+}
+
+// WebhookObject is the object of an object event
+type WebhookObject struct {
+	Oid  string
+	Size int64
+}
+
+// WebhookDelivery is an event queued for delivery to a webhook
+type WebhookDelivery struct {
+	ID            string
+	URL           string
+	Event         WebhookEvent
+	Attempts      int
+	NextAttemptAt int64 // UnixTime
+	LastError     string
+}
+
+func containsString(values []string, value string) bool {
+
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
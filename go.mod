@@ -7,5 +7,8 @@ require (
 	github.com/aws/aws-sdk-go v1.30.14
 	github.com/boltdb/bolt v1.3.1
 	github.com/gorilla/mux v1.7.4
+	github.com/prometheus/client_golang v1.5.1
+	golang.org/x/crypto v0.0.0-20200420201142-3c4aac89819a
 	golang.org/x/sys v0.0.0-20200420163511-1957bb5e6d1f // indirect
+	gopkg.in/square/go-jose.v2 v2.5.0
 )
@@ -0,0 +1,137 @@
+package main
+
+import (
+	gocontext "context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ikmski/git-lfs3/adapter"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// requestInfo carries details found by inner handlers back to the access log
+type requestInfo struct {
+	user string
+}
+
+func newLogger(conf logConfig) (adapter.Logger, error) {
+
+	var w io.Writer
+	switch conf.Output {
+	case "", "stdout":
+		w = os.Stdout
+	case "stderr":
+		w = os.Stderr
+	default:
+		f, err := os.OpenFile(conf.Output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+		w = f
+	}
+
+	return adapter.NewLogger(w, conf.Level, conf.Format)
+}
+
+type loggingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *loggingResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *loggingResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+type loggingReadCloser struct {
+	io.ReadCloser
+	bytes int64
+}
+
+func (r *loggingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// accessLog writes an access log entry for each request handled by next.
+func accessLog(logger adapter.Logger, next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		start := time.Now()
+
+		requestID := r.Header.Get(requestIDHeader)
+		if requestID == "" {
+			requestID = randomRequestID()
+			r.Header.Set(requestIDHeader, requestID)
+		}
+		w.Header().Set(requestIDHeader, requestID)
+
+		info := &requestInfo{}
+		r = r.WithContext(gocontext.WithValue(r.Context(), requestInfoContextKey, info))
+
+		lw := &loggingResponseWriter{ResponseWriter: w}
+		body := &loggingReadCloser{ReadCloser: r.Body}
+		if r.Body != nil {
+			r.Body = body
+		}
+
+		next.ServeHTTP(lw, r)
+
+		status := lw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		route := ""
+		if cr := mux.CurrentRoute(r); cr != nil {
+			route, _ = cr.GetPathTemplate()
+		}
+		// the user path variable is the owner of the repo, the user
+		// logged is the identity set by authenticate
+		vars := mux.Vars(r)
+		repo := ""
+		if vars["repo"] != "" {
+			repo = vars["user"] + "/" + vars["repo"]
+		}
+
+		logger.Info("request",
+			"request_id", requestID,
+			"method", r.Method,
+			"route", route,
+			"path", r.URL.Path,
+			"user", info.user,
+			"repo", repo,
+			"oid", vars["oid"],
+			"status", status,
+			"bytes_in", body.bytes,
+			"bytes_out", lw.bytes,
+			"duration_ms", float64(time.Since(start))/float64(time.Millisecond),
+		)
+	})
+}
+
+func randomRequestID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return fmt.Sprintf("%x", id[:])
+}
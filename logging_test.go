@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/ikmski/git-lfs3/adapter"
+)
+
+func TestAccessLog(t *testing.T) {
+
+	var buf bytes.Buffer
+	logger, err := adapter.NewLogger(&buf, "info", "json")
+	if err != nil {
+		t.Fatalf("expected logger, got: %s", err)
+	}
+
+	r := mux.NewRouter()
+	r.Use(func(next http.Handler) http.Handler { return accessLog(logger, next) })
+	r.Use(func(next http.Handler) http.Handler { return authenticate(testAccessSvc, next) })
+	r.Methods("PUT").Path("/{user}/{repo}/objects/{oid}").
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			buf := new(bytes.Buffer)
+			buf.ReadFrom(r.Body)
+			w.WriteHeader(201)
+			w.Write([]byte("ok"))
+		})
+
+	req := httptest.NewRequest("PUT", "/"+testUser1+"/"+testRepo+"/objects/"+testContentOid, strings.NewReader(testContent))
+	req.Header.Set(requestIDHeader, "test-request")
+	// the path names the owner of the repo, the user is the one authenticated
+	req.SetBasicAuth(testUser2, testPass2)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Header().Get(requestIDHeader) != "test-request" {
+		t.Errorf("expected request id to be echoed, got: %s", rec.Header().Get(requestIDHeader))
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected json log entry, got: %s", err)
+	}
+
+	expected := map[string]interface{}{
+		"request_id": "test-request",
+		"method":     "PUT",
+		"route":      "/{user}/{repo}/objects/{oid}",
+		"user":       testUser2,
+		"repo":       testUser1 + "/" + testRepo,
+		"oid":        testContentOid,
+		"status":     float64(201),
+		"bytes_in":   float64(testContentSize),
+		"bytes_out":  float64(2),
+	}
+
+	for k, v := range expected {
+		if entry[k] != v {
+			t.Errorf("expected %s to be %v, got: %v", k, v, entry[k])
+		}
+	}
+
+	if _, ok := entry["duration_ms"]; !ok {
+		t.Errorf("expected duration in log entry")
+	}
+}
@@ -1,17 +1,32 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"os"
 	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/boltdb/bolt"
 	"github.com/ikmski/git-lfs3/adapter"
+	"github.com/ikmski/git-lfs3/entity"
 	"github.com/ikmski/git-lfs3/usecase"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 const (
-	configFileName = "config.toml"
+	configFileName     = "config.toml"
+	defaultMetaDB      = "meta.db"
+	defaultMetricsPath = "/metrics"
+	defaultTokenTTL    = 5 * time.Minute
+
+	defaultMultipartPartSize = 16 << 20
+	minMultipartPartSize     = 5 << 20 // the minimum size of all but the last part in S3
+	defaultPresignExpiry     = 15 * time.Minute
+	defaultBatchConcurrency  = 16
+	defaultBatchMaxObjects   = 10000
 )
 
 func main() {
@@ -22,37 +37,335 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if len(os.Args) > 1 {
+		err = runCommand(config, os.Args[1:], os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	app, err := initializeApp(config)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	app.serve()
+	err = app.serve()
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
 func initializeApp(config globalConfig) (*app, error) {
 
-	db, err := bolt.Open("meta.db", 0600, &bolt.Options{Timeout: 1 * time.Second})
+	logger, err := newLogger(config.Log)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := openDatabase(config.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics adapter.Metrics
+	registry := prometheus.NewRegistry()
+	if config.Metrics.Enabled {
+		metrics, err = adapter.NewPrometheusMetrics(registry)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	metaDataRepo := adapter.NewMetaDataRepository(db, metrics)
+	lockRepo := adapter.NewLockRepository(db, metrics)
+	userRepo := adapter.NewUserRepository(db, metrics)
+	permissionRepo := adapter.NewPermissionRepository(db, metrics)
+	accessTokenRepo := adapter.NewAccessTokenRepository(db, metrics)
+	multipartUploadRepo := adapter.NewMultipartUploadRepository(db, metrics)
+	repositoryRepo := adapter.NewRepositoryRepository(db, metrics)
+	tombstoneRepo := adapter.NewTombstoneRepository(db, metrics)
+	contentRepo, err := adapter.NewContentRepository(config.S3.Bucket, logger, metrics)
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
-	metaDataRepo := adapter.NewMetaDataRepository(db)
-	lockRepo := adapter.NewLockRepository(db)
-	contentRepo, err := adapter.NewContentRepository("test")
+	transferAdapters, err := newTransferAdapters(config.Transfer, config.S3)
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
-	batchService := usecase.NewBatchService(metaDataRepo, contentRepo)
-	transferService := usecase.NewTransferService(metaDataRepo, contentRepo)
-	lockService := usecase.NewLockService(lockRepo)
+	auditLog, err := newAuditLog(config.Audit, db, metrics, func(err error) {
+		logger.Error("failed to record audit event", "error", err)
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
 
-	batchController := adapter.NewBatchController(batchService)
-	transferController := adapter.NewTransferController(transferService)
-	lockController := adapter.NewLockController(lockService)
+	webhooks, err := newWebhooks(config.Webhook, db, metrics, func(err error) {
+		logger.Error("webhook delivery failed", "error", err)
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
 
-	app := newApp(config.Server, batchController, transferController, lockController)
+	quotas := newQuotas(config.Quota)
+	policies := newUploadPolicies(config.Policy)
+
+	batchService := usecase.NewBatchService(metaDataRepo, contentRepo, repositoryRepo, tombstoneRepo, quotas, policies, transferAdapters, batchConcurrency(config.Batch), auditLog)
+	transferService := usecase.NewTransferService(metaDataRepo, contentRepo, repositoryRepo, tombstoneRepo, policies, auditLog, webhooks)
+	lockService := usecase.NewLockService(lockRepo, auditLog, webhooks)
+	healthService := usecase.NewHealthService(metaDataRepo, contentRepo)
+	multipartService := usecase.NewMultipartService(metaDataRepo, contentRepo, multipartUploadRepo, repositoryRepo, tombstoneRepo, policies, multipartPartSize(config.Transfer), auditLog, webhooks)
+	importService := usecase.NewImportService(metaDataRepo, contentRepo, repositoryRepo, quotas, policies)
+	repositoryService := usecase.NewRepositoryService(repositoryRepo, usecase.NewReferenceService(metaDataRepo, contentRepo), auditLog)
+	bearerVerifier, err := newBearerTokenVerifier(config.OIDC)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	accessService := usecase.NewAccessService(userRepo, permissionRepo, accessTokenRepo, newTokenService(config.Auth), bearerVerifier, config.Auth.Admins, auditLog)
+	accessTokenService := usecase.NewAccessTokenService(accessTokenRepo, auditLog)
+
+	batchController := adapter.NewBatchController(batchService, accessService, metrics, batchMaxObjects(config.Batch))
+	transferController := adapter.NewTransferController(transferService, accessService, metrics)
+	lockController := adapter.NewLockController(lockService, accessService, metrics)
+	healthController := adapter.NewHealthController(healthService)
+	multipartController := adapter.NewMultipartController(multipartService, accessService, metrics)
+	importController := adapter.NewImportController(importService, accessService)
+	objectController := adapter.NewObjectController(usecase.NewObjectService(metaDataRepo, contentRepo), accessService)
+	accessTokenController := adapter.NewAccessTokenController(accessTokenService, accessService)
+	repositoryController := adapter.NewRepositoryController(repositoryService, accessService)
+	deletionController := adapter.NewDeletionController(usecase.NewDeletionService(metaDataRepo, contentRepo, tombstoneRepo, auditLog), accessService)
+	auditController := adapter.NewAuditController(auditLog, accessService)
+
+	app := newApp(config.Server, logger, batchController, transferController, multipartController, importController, objectController, lockController, healthController, accessTokenController, repositoryController, deletionController, auditController, accessService)
+	app.db = db
+	app.webhooks = webhooks
+
+	if config.Metrics.Enabled {
+		path := config.Metrics.Path
+		if path == "" {
+			path = defaultMetricsPath
+		}
+		app.router.Methods("GET").Path(path).Handler(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	}
 
 	return app, nil
 }
+
+// openDatabase opens the meta data database and brings its schema up to date
+func openDatabase(conf databaseConfig) (*bolt.DB, error) {
+
+	path := conf.MetaDB
+	if path == "" {
+		path = defaultMetaDB
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = adapter.Migrate(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func newTokenService(conf authConfig) usecase.TokenService {
+
+	ttl := conf.TokenTTL.Duration
+	if ttl <= 0 {
+		ttl = defaultTokenTTL
+	}
+
+	return usecase.NewTokenService([]byte(conf.TokenSecret), ttl)
+}
+
+func newTransferAdapters(conf transferConfig, s3Conf s3Config) (*usecase.TransferAdapters, error) {
+
+	basic := adapter.NewBasicTransferAdapter()
+	if conf.Presigned {
+		expiry := conf.PresignExpiry.Duration
+		if expiry <= 0 {
+			expiry = defaultPresignExpiry
+		}
+
+		var err error
+		basic, err = adapter.NewPresignedTransferAdapter(s3Conf.Bucket, expiry)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	adapters := []usecase.TransferAdapter{basic}
+	if conf.Multipart {
+		adapters = append(adapters, adapter.NewMultipartTransferAdapter())
+	}
+
+	return usecase.NewTransferAdapters(adapters...), nil
+}
+
+func batchConcurrency(conf batchConfig) int {
+
+	if conf.Concurrency <= 0 {
+		return defaultBatchConcurrency
+	}
+
+	return conf.Concurrency
+}
+
+func batchMaxObjects(conf batchConfig) int {
+
+	if conf.MaxObjects <= 0 {
+		return defaultBatchMaxObjects
+	}
+
+	return conf.MaxObjects
+}
+
+func multipartPartSize(conf transferConfig) int64 {
+
+	size := conf.MultipartPartSize
+	if size <= 0 {
+		return defaultMultipartPartSize
+	}
+	if size < minMultipartPartSize {
+		return minMultipartPartSize
+	}
+
+	return size
+}
+
+func newQuotas(conf quotaConfig) *usecase.Quotas {
+
+	quotas := &usecase.Quotas{
+		Repo:  entity.Quota{Size: conf.RepoSize, Objects: conf.RepoObjects},
+		User:  entity.Quota{Size: conf.UserSize, Objects: conf.UserObjects},
+		Repos: make(map[string]entity.Quota),
+		Users: make(map[string]entity.Quota),
+	}
+
+	for repo, l := range conf.Repos {
+		quotas.Repos[repo] = entity.Quota{Size: l.Size, Objects: l.Objects}
+	}
+
+	for user, l := range conf.Users {
+		quotas.Users[user] = entity.Quota{Size: l.Size, Objects: l.Objects}
+	}
+
+	return quotas
+}
+
+func newUploadPolicies(conf policyConfig) *usecase.UploadPolicies {
+
+	policies := &usecase.UploadPolicies{
+		Default: conf.uploadPolicy.entity(),
+		Repos:   make(map[string]entity.UploadPolicy),
+	}
+
+	for repo, p := range conf.Repos {
+		policies.Repos[repo] = p.entity()
+	}
+
+	return policies
+}
+
+// newAuditLog returns an audit log writing to the configured sinks, which
+// records nothing when none is configured
+func newAuditLog(conf auditConfig, db *bolt.DB, metrics adapter.Metrics, onError func(err error)) (*usecase.AuditLog, error) {
+
+	// the database is indexed, so it is queried whenever it is enabled
+	var store usecase.AuditSink
+	if conf.Database {
+		store = adapter.NewBoltAuditSink(db, metrics)
+	}
+
+	var sinks []usecase.AuditSink
+	if conf.File != "" {
+		sink, err := adapter.NewFileAuditSink(conf.File)
+		if err != nil {
+			return nil, err
+		}
+
+		if store == nil {
+			store = sink
+		} else {
+			sinks = append(sinks, sink)
+		}
+	}
+
+	return usecase.NewAuditLog(onError, store, sinks...), nil
+}
+
+// newWebhooks returns nil when no endpoint is configured
+func newWebhooks(conf webhookConfig, db *bolt.DB, metrics adapter.Metrics, onError func(err error)) (*usecase.Webhooks, error) {
+
+	if len(conf.Endpoints) == 0 {
+		return nil, nil
+	}
+
+	var hooks []entity.Webhook
+	for _, e := range conf.Endpoints {
+
+		if e.URL == "" {
+			return nil, errors.New("Webhook endpoints need a url")
+		}
+
+		if e.Secret == "" {
+			return nil, fmt.Errorf("Webhook endpoint %s needs a secret", e.URL)
+		}
+
+		for _, event := range e.Events {
+			switch event {
+			case entity.WebhookLockCreated, entity.WebhookLockReleased, entity.WebhookLockForceReleased,
+				entity.WebhookObjectUploaded, entity.WebhookObjectVerified:
+			default:
+				return nil, fmt.Errorf("Unknown webhook event: %s", event)
+			}
+		}
+
+		hooks = append(hooks, entity.Webhook{URL: e.URL, Secret: e.Secret, Repos: e.Repos, Events: e.Events})
+	}
+
+	webhooks := usecase.NewWebhooks(hooks, adapter.NewWebhookDeliveryRepository(db, metrics), adapter.NewWebhookSender(conf.Timeout.Duration), onError)
+	if conf.MaxAttempts > 0 {
+		webhooks.MaxAttempts = conf.MaxAttempts
+	}
+	if conf.Backoff.Duration > 0 {
+		webhooks.Backoff = conf.Backoff.Duration
+	}
+	if conf.MaxBackoff.Duration > 0 {
+		webhooks.MaxBackoff = conf.MaxBackoff.Duration
+	}
+
+	return webhooks, nil
+}
+
+// newBearerTokenVerifier returns nil when no identity provider is configured
+func newBearerTokenVerifier(conf oidcConfig) (usecase.BearerTokenVerifier, error) {
+
+	if conf.JWKSFile == "" && conf.JWKSURL == "" {
+		return nil, nil
+	}
+
+	return adapter.NewJWTVerifier(adapter.JWTConfig{
+		Issuer:      conf.Issuer,
+		Audience:    conf.Audience,
+		UserClaim:   conf.UserClaim,
+		GroupsClaim: conf.GroupsClaim,
+		JWKSFile:    conf.JWKSFile,
+		JWKSURL:     conf.JWKSURL,
+		JWKSRefresh: conf.JWKSRefresh.Duration,
+	})
+}
@@ -0,0 +1,251 @@
+package usecase
+
+import (
+	"time"
+
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+// AccessService is ...
+type AccessService interface {
+	Authenticate(user string, pass string) (*Identity, error)
+	AuthenticateToken(token string) (*Identity, error)
+	AuthenticateBearer(token string) (*Identity, error)
+	IssueToken(user string, repo string, operation string) (*Token, error)
+	Authorize(id *Identity, repo string, required entity.Role, scope entity.Scope) error
+	AuthorizeUser(id *Identity, user string) error
+	AuthorizeAdmin(id *Identity) error
+}
+
+// GroupPrefix marks permissions granted to a group rather than a user
+const GroupPrefix = "@"
+
+type accessService struct {
+	UserRepository        UserRepository
+	PermissionRepository  PermissionRepository
+	AccessTokenRepository AccessTokenRepository
+	TokenService          TokenService
+	BearerTokenVerifier   BearerTokenVerifier
+	Admins                []string
+	AuditLog              *AuditLog
+}
+
+// NewAccessService is ...
+// bearerVerifier may be nil, in which case bearer tokens are rejected.
+// admins are the users allowed to manage the tokens of other users and the repositories.
+// audit may be nil, otherwise failed logins are audited.
+func NewAccessService(userRepo UserRepository, permissionRepo PermissionRepository, accessTokenRepo AccessTokenRepository, tokenService TokenService, bearerVerifier BearerTokenVerifier, admins []string, audit *AuditLog) AccessService {
+	return &accessService{
+		UserRepository:        userRepo,
+		PermissionRepository:  permissionRepo,
+		AccessTokenRepository: accessTokenRepo,
+		TokenService:          tokenService,
+		BearerTokenVerifier:   bearerVerifier,
+		Admins:                admins,
+		AuditLog:              audit,
+	}
+}
+
+// Authenticate returns the identity for valid credentials and ErrUnauthorized otherwise.
+// A personal access token is accepted in place of the password.
+func (s *accessService) Authenticate(user string, pass string) (*Identity, error) {
+
+	if id, secret, ok := parseAccessToken(pass); ok {
+		identity, err := s.authenticateAccessToken(user, id, secret)
+		if err != nil {
+			s.loginFailed(user, "access_token")
+		}
+		return identity, err
+	}
+
+	u, err := s.UserRepository.Authenticate(user, pass)
+	if err != nil {
+		s.loginFailed(user, "password")
+		return nil, ErrUnauthorized
+	}
+
+	return &Identity{User: u.Name}, nil
+}
+
+func (s *accessService) authenticateAccessToken(user string, id string, secret string) (*Identity, error) {
+
+	token, err := s.AccessTokenRepository.Authenticate(user, id, secret)
+	if err != nil || token.Expired(time.Now().Unix()) {
+		return nil, ErrUnauthorized
+	}
+
+	identity := &Identity{
+		User:   token.User,
+		Repo:   token.Repo,
+		Scopes: token.Scopes,
+	}
+
+	return identity, nil
+}
+
+// AuthenticateToken returns the identity for a token issued by IssueToken.
+// The identity is limited to the repository and operation of the token.
+func (s *accessService) AuthenticateToken(token string) (*Identity, error) {
+
+	claims, err := s.TokenService.Verify(token)
+	if err != nil {
+		s.loginFailed("", "token")
+		return nil, ErrUnauthorized
+	}
+
+	id := &Identity{
+		User: claims.User,
+		Repo: claims.Repo,
+		Role: operationRole(claims.Operation),
+	}
+
+	return id, nil
+}
+
+// AuthenticateBearer returns the identity for a token of the configured identity provider
+func (s *accessService) AuthenticateBearer(token string) (*Identity, error) {
+
+	if s.BearerTokenVerifier == nil {
+		return nil, ErrUnauthorized
+	}
+
+	id, err := s.BearerTokenVerifier.Verify(token)
+	if err != nil || id.User == "" {
+		s.loginFailed("", "bearer")
+		return nil, ErrUnauthorized
+	}
+
+	return id, nil
+}
+
+// IssueToken returns a short-lived token for the operation if the user is allowed to perform it
+func (s *accessService) IssueToken(user string, repo string, operation string) (*Token, error) {
+
+	err := s.Authorize(&Identity{User: user}, repo, operationRole(operation), operationScope(operation))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.TokenService.Issue(user, repo, operation)
+}
+
+// Authorize returns ErrForbidden unless the identity has at least the required role on the repo
+// and, for personal access tokens, the required scope
+func (s *accessService) Authorize(id *Identity, repo string, required entity.Role, scope entity.Scope) error {
+
+	if id == nil || id.User == "" {
+		return ErrUnauthorized
+	}
+
+	if id.Repo != "" && id.Repo != repo {
+		return ErrForbidden
+	}
+
+	if id.Role != "" && !id.Role.Allows(required) {
+		return ErrForbidden
+	}
+
+	if id.Scopes != nil && !hasScope(id.Scopes, scope) {
+		return ErrForbidden
+	}
+
+	principals := []string{id.User}
+	for _, g := range id.Groups {
+		principals = append(principals, GroupPrefix+g)
+	}
+
+	for _, p := range principals {
+		perm, err := s.PermissionRepository.Get(p, repo)
+		if err == nil && perm.Role.Allows(required) {
+			return nil
+		}
+	}
+
+	return ErrForbidden
+}
+
+// AuthorizeUser returns ErrForbidden unless the identity may manage the account of the user.
+// Users manage their own account and admins any account, but only with unrestricted credentials.
+func (s *accessService) AuthorizeUser(id *Identity, user string) error {
+
+	if id == nil || id.User == "" {
+		return ErrUnauthorized
+	}
+
+	if id.restricted() {
+		return ErrForbidden
+	}
+
+	if id.User == user || s.isAdmin(id.User) {
+		return nil
+	}
+
+	return ErrForbidden
+}
+
+// AuthorizeAdmin returns ErrForbidden unless the identity is an admin with unrestricted credentials.
+func (s *accessService) AuthorizeAdmin(id *Identity) error {
+
+	if id == nil || id.User == "" {
+		return ErrUnauthorized
+	}
+
+	if id.restricted() || !s.isAdmin(id.User) {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+// loginFailed audits a failed login. The user is empty for tokens, as
+// nothing in a token that fails verification can be trusted.
+func (s *accessService) loginFailed(user string, method string) {
+
+	s.AuditLog.Record(&entity.AuditEvent{
+		Actor:   user,
+		Action:  entity.AuditLoginFailed,
+		Details: map[string]string{"method": method},
+	})
+}
+
+func (s *accessService) isAdmin(user string) bool {
+
+	for _, admin := range s.Admins {
+		if user == admin {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasScope(scopes []entity.Scope, required entity.Scope) bool {
+
+	for _, s := range scopes {
+		if s.Allows(required) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// operationScope returns the token scope needed for a Git LFS operation
+func operationScope(operation string) entity.Scope {
+
+	if operation == "upload" {
+		return entity.ScopeWrite
+	}
+
+	return entity.ScopeRead
+}
+
+// operationRole returns the role needed for a Git LFS operation
+func operationRole(operation string) entity.Role {
+
+	if operation == "upload" {
+		return entity.RoleWrite
+	}
+
+	return entity.RoleRead
+}
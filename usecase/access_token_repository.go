@@ -0,0 +1,15 @@
+package usecase
+
+import (
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+// AccessTokenRepository is ...
+type AccessTokenRepository interface {
+	// Add stores the token with a hash of its secret
+	Add(token *entity.AccessToken, secret string) error
+	Tokens(user string) ([]*entity.AccessToken, error)
+	Revoke(user string, id string) error
+	// Authenticate returns the token if the secret matches and records its use
+	Authenticate(user string, id string, secret string) (*entity.AccessToken, error)
+}
@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+// accessTokenPrefix starts every personal access token, so that tokens can
+// be told apart from passwords in Basic credentials
+const accessTokenPrefix = "lfs3_"
+
+var (
+	errAccessTokenNameRequired   = errors.New("Token name is required")
+	errAccessTokenScopesRequired = errors.New("At least one scope is required")
+)
+
+// AccessTokenService is ...
+type AccessTokenService interface {
+	Create(req *AccessTokenRequest) (*AccessTokenResult, error)
+	Tokens(user string) ([]*entity.AccessToken, error)
+	Revoke(user string, id string, actor string) error
+}
+
+type accessTokenService struct {
+	AccessTokenRepository AccessTokenRepository
+	AuditLog              *AuditLog
+}
+
+// NewAccessTokenService is ...
+// audit may be nil.
+func NewAccessTokenService(tokenRepo AccessTokenRepository, audit *AuditLog) AccessTokenService {
+	return &accessTokenService{
+		AccessTokenRepository: tokenRepo,
+		AuditLog:              audit,
+	}
+}
+
+// Create generates a new token. Its value is only ever returned here.
+func (s *accessTokenService) Create(req *AccessTokenRequest) (*AccessTokenResult, error) {
+
+	if req.Name == "" {
+		return nil, errAccessTokenNameRequired
+	}
+
+	if len(req.Scopes) == 0 {
+		return nil, errAccessTokenScopesRequired
+	}
+
+	for _, scope := range req.Scopes {
+		if !scope.Valid() {
+			return nil, fmt.Errorf("Invalid scope: %s", scope)
+		}
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	token := &entity.AccessToken{
+		ID:        id,
+		User:      req.User,
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		Repo:      req.Repo,
+		CreatedAt: now.Unix(),
+	}
+	if req.TTL > 0 {
+		token.ExpiresAt = now.Add(req.TTL).Unix()
+	}
+
+	err = s.AccessTokenRepository.Add(token, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	var scopes []string
+	for _, scope := range token.Scopes {
+		scopes = append(scopes, string(scope))
+	}
+
+	s.AuditLog.Record(&entity.AuditEvent{
+		Time:    token.CreatedAt,
+		Actor:   req.Actor,
+		Action:  entity.AuditTokenCreate,
+		Repo:    token.Repo,
+		Target:  token.User,
+		Details: map[string]string{"id": token.ID, "name": token.Name, "scopes": strings.Join(scopes, ",")},
+	})
+
+	result := &AccessTokenResult{
+		Token: token,
+		Value: accessTokenPrefix + id + "_" + secret,
+	}
+
+	return result, nil
+}
+
+// Tokens returns the tokens of the user, without their values
+func (s *accessTokenService) Tokens(user string) ([]*entity.AccessToken, error) {
+
+	return s.AccessTokenRepository.Tokens(user)
+}
+
+// Revoke deletes the token so that it can no longer be used
+func (s *accessTokenService) Revoke(user string, id string, actor string) error {
+
+	err := s.AccessTokenRepository.Revoke(user, id)
+	if err != nil {
+		return err
+	}
+
+	s.AuditLog.Record(&entity.AuditEvent{
+		Actor:   actor,
+		Action:  entity.AuditTokenRevoke,
+		Target:  user,
+		Details: map[string]string{"id": id},
+	})
+
+	return nil
+}
+
+// parseAccessToken splits a personal access token into its id and secret
+func parseAccessToken(value string) (string, string, bool) {
+
+	if !strings.HasPrefix(value, accessTokenPrefix) {
+		return "", "", false
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, accessTokenPrefix), "_")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+func randomHex(n int) (string, error) {
+
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"time"
+
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+const defaultAuditQueryLimit = 1000
+
+// AuditSink stores audit events. Events are only ever appended.
+type AuditSink interface {
+	Append(event *entity.AuditEvent) error
+	// Query returns a page of the events matching q, the most recently
+	// appended first
+	Query(q *AuditQuery) (*AuditQueryResult, error)
+}
+
+// AuditQuery selects audit events. Fields left empty match any event.
+type AuditQuery struct {
+	Actor  string
+	Repo   string
+	Since  int64  // UnixTime, inclusive
+	Until  int64  // UnixTime, exclusive
+	Limit  int    // defaults to 1000
+	Cursor string // the NextCursor of the previous page
+}
+
+// AuditQueryResult is ...
+// NextCursor is empty on the last page.
+type AuditQueryResult struct {
+	Events     []*entity.AuditEvent
+	NextCursor string
+}
+
+// Matches reports whether the event is selected by the query
+func (q *AuditQuery) Matches(event *entity.AuditEvent) bool {
+
+	if q.Actor != "" && event.Actor != q.Actor {
+		return false
+	}
+
+	if q.Repo != "" && event.Repo != q.Repo {
+		return false
+	}
+
+	if q.Since != 0 && event.Time < q.Since {
+		return false
+	}
+
+	if q.Until != 0 && event.Time >= q.Until {
+		return false
+	}
+
+	return true
+}
+
+// MaxEvents returns the maximum number of events the query returns
+func (q *AuditQuery) MaxEvents() int {
+
+	if q.Limit <= 0 {
+		return defaultAuditQueryLimit
+	}
+
+	return q.Limit
+}
+
+// AuditLog records audit events to all of its sinks and is queried through
+// its store. A nil AuditLog records nothing.
+type AuditLog struct {
+	Store AuditSink // nil if the log cannot be queried
+	Sinks []AuditSink
+	// OnError is called with the errors of sinks, as failing to record an
+	// action that has been taken must not fail the action
+	OnError func(err error)
+}
+
+// NewAuditLog is ...
+// The store is queried and recorded to along with the other sinks. It may be
+// nil, as may onError, in which case errors of sinks are dropped.
+func NewAuditLog(onError func(err error), store AuditSink, sinks ...AuditSink) *AuditLog {
+
+	if store != nil {
+		sinks = append([]AuditSink{store}, sinks...)
+	}
+
+	return &AuditLog{
+		Store:   store,
+		Sinks:   sinks,
+		OnError: onError,
+	}
+}
+
+// Record appends the event to every sink, setting its time if it is unset
+func (l *AuditLog) Record(event *entity.AuditEvent) {
+
+	if l == nil {
+		return
+	}
+
+	if event.Time == 0 {
+		event.Time = time.Now().Unix()
+	}
+
+	for _, sink := range l.Sinks {
+		err := sink.Append(event)
+		if err != nil && l.OnError != nil {
+			l.OnError(err)
+		}
+	}
+}
+
+// Query returns a page of the events matching q from the store
+func (l *AuditLog) Query(q *AuditQuery) (*AuditQueryResult, error) {
+
+	if l == nil || l.Store == nil {
+		return nil, ErrAuditLogDisabled
+	}
+
+	return l.Store.Query(q)
+}
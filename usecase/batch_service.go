@@ -1,6 +1,8 @@
 package usecase
 
 import (
+	"sync"
+
 	"github.com/ikmski/git-lfs3/entity"
 )
 
@@ -10,15 +12,34 @@ type BatchService interface {
 }
 
 type batchService struct {
-	MetaDataRepository MetaDataRepository
-	ContentRepository  ContentRepository
+	MetaDataRepository   MetaDataRepository
+	ContentRepository    ContentRepository
+	RepositoryRepository RepositoryRepository
+	TombstoneRepository  TombstoneRepository
+	Quotas               *Quotas
+	UploadPolicies       *UploadPolicies
+	TransferAdapters     *TransferAdapters
+	Concurrency          int
+	AuditLog             *AuditLog
 }
 
 // NewBatchService is ...
-func NewBatchService(metaDataRepo MetaDataRepository, contentRepo ContentRepository) BatchService {
+// quotas and policies may be nil, in which case uploads are unrestricted.
+// repoRepo may be nil too, otherwise uploads to archived repos are rejected,
+// and so may tombstones, otherwise uploads of deleted objects are rejected.
+// concurrency limits the existence checks of a batch that run at a time.
+// audit may be nil, in which case references added to stored content are not audited.
+func NewBatchService(metaDataRepo MetaDataRepository, contentRepo ContentRepository, repoRepo RepositoryRepository, tombstones TombstoneRepository, quotas *Quotas, policies *UploadPolicies, transfers *TransferAdapters, concurrency int, audit *AuditLog) BatchService {
 	return &batchService{
-		MetaDataRepository: metaDataRepo,
-		ContentRepository:  contentRepo,
+		MetaDataRepository:   metaDataRepo,
+		ContentRepository:    contentRepo,
+		RepositoryRepository: repoRepo,
+		TombstoneRepository:  tombstones,
+		Quotas:               quotas,
+		UploadPolicies:       policies,
+		TransferAdapters:     transfers,
+		Concurrency:          concurrency,
+		AuditLog:             audit,
 	}
 }
 
@@ -26,32 +47,202 @@ func (c *batchService) Batch(req *BatchRequest) (*BatchResult, error) {
 
 	var objectResults []*ObjectResult
 
+	if req.HashAlgo != "" && req.HashAlgo != entity.HashAlgoSHA256 {
+		return nil, ErrHashAlgoNotSupported
+	}
+
+	transfer, err := c.TransferAdapters.Negotiate(req.Transfers)
+	if err != nil {
+		return nil, err
+	}
+
+	quota, err := newQuotaCheck(c.Quotas, c.MetaDataRepository, req.Repo, req.User)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := c.UploadPolicies.Policy(req.Repo)
+	if req.Operation == "upload" {
+		policy, err = repositoryPolicy(c.UploadPolicies, c.RepositoryRepository, req.Repo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// never look up invalid OIDs, they would be used as storage keys
+	var oids []string
 	for _, obj := range req.Objects {
+		if entity.ValidOid(obj.Oid) {
+			oids = append(oids, obj.Oid)
+		}
+	}
 
-		meta, err := c.MetaDataRepository.Get(obj.Oid)
+	metas, err := c.MetaDataRepository.GetMany(oids)
+	if err != nil {
+		return nil, err
+	}
+
+	tombstones := map[string]*entity.Tombstone{}
+	if req.Operation == "upload" && c.TombstoneRepository != nil {
+		tombstones, err = c.TombstoneRepository.GetMany(oids)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	exists := c.existing(req.Objects, metas)
+
+	for i, obj := range req.Objects {
+
+		if !entity.ValidOid(obj.Oid) {
+			objectResults = append(objectResults, &ObjectResult{Oid: obj.Oid, Size: obj.Size, Error: &ObjectError{Code: 422, Message: "Invalid object ID"}})
+			continue
+		}
+
+		if tombstones[obj.Oid] != nil {
+			objectResults = append(objectResults, &ObjectResult{Oid: obj.Oid, Size: obj.Size, Error: &ObjectError{Code: 410, Message: "Object was deleted by an admin"}})
+			continue
+		}
+
+		meta := metas[obj.Oid]
+
+		// Only objects stored for the repo can be downloaded, and nothing
+		// is registered for the others.
+		if req.Operation != "upload" {
+			if !exists[i] || !meta.InRepo(req.Repo) {
+				objectResults = append(objectResults, &ObjectResult{Oid: obj.Oid, Size: obj.Size, Error: &ObjectError{Code: 404, Message: "Object does not exist"}})
+				continue
+			}
 
-		if err == nil && c.ContentRepository.Exists(meta) {
-			// Object is found and exists
 			objectResult := createObjectResult(obj, meta, true, true)
 			objectResults = append(objectResults, objectResult)
 			continue
 		}
 
-		// Object is not found
-		meta, err = c.MetaDataRepository.Put(obj.Oid, obj.Size)
-		if err == nil {
-			objectResult := createObjectResult(obj, meta, true, false)
+		if exists[i] {
+			// Object is found and exists. Uploading it to another repo
+			// only adds a reference to the stored content.
+			if !meta.InRepo(req.Repo) {
+				objErr := checkUploadPolicy(&policy, obj)
+				if objErr == nil {
+					objErr = quota.reserve(obj, meta)
+				}
+				if objErr == nil {
+					_, err = c.MetaDataRepository.AddReference(req.Repo, obj.Oid)
+					if err != nil {
+						objErr = &ObjectError{Code: 500, Message: "Failed to register object"}
+					}
+				}
+				if objErr == nil {
+					c.AuditLog.Record(&entity.AuditEvent{
+						Actor:  req.User,
+						Action: entity.AuditObjectLink,
+						Repo:   req.Repo,
+						Target: obj.Oid,
+					})
+				}
+				if objErr != nil {
+					objectResults = append(objectResults, &ObjectResult{Oid: obj.Oid, Size: obj.Size, Error: objErr})
+					continue
+				}
+			}
+
+			objectResult := createObjectResult(obj, meta, true, true)
 			objectResults = append(objectResults, objectResult)
+			continue
+		}
+
+		objErr := checkUploadPolicy(&policy, obj)
+		if objErr == nil {
+			objErr = quota.reserve(obj, meta)
+		}
+		if objErr != nil {
+			objectResults = append(objectResults, &ObjectResult{Oid: obj.Oid, Size: obj.Size, Error: objErr})
+			continue
+		}
+
+		// Object is not found
+		meta, err = c.MetaDataRepository.Put(&entity.MetaData{
+			Oid:      obj.Oid,
+			Size:     obj.Size,
+			Uploader: entity.User{Name: req.User},
+			Repos:    []string{req.Repo},
+		})
+		if err != nil {
+			objectResults = append(objectResults, &ObjectResult{Oid: obj.Oid, Size: obj.Size, Error: &ObjectError{Code: 500, Message: "Failed to register object"}})
+			continue
+		}
+
+		objectResult := createObjectResult(obj, meta, true, false)
+		objectResults = append(objectResults, objectResult)
+	}
+
+	for _, objectResult := range objectResults {
+		if objectResult.Error != nil {
+			continue
+		}
+
+		objectResult.Actions, err = transfer.Actions(req, objectResult)
+		if err != nil {
+			return nil, err
 		}
 	}
 
 	result := &BatchResult{
-		Objects: objectResults,
+		Transfer: transfer.Name(),
+		HashAlgo: entity.HashAlgoSHA256,
+		Objects:  objectResults,
 	}
 
 	return result, nil
 }
 
+// existing checks which of the objects with complete meta data are stored,
+// running at most Concurrency checks at a time. Content that was uploaded
+// but neither verified nor hashed by the server does not exist.
+// The result is in the order of objs.
+func (c *batchService) existing(objs []*ObjectRequest, metas map[string]*entity.MetaData) []bool {
+
+	exists := make([]bool, len(objs))
+
+	var checks []int
+	for i, obj := range objs {
+		if meta := metas[obj.Oid]; meta != nil && meta.Complete {
+			checks = append(checks, i)
+		}
+	}
+
+	workers := c.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(checks) {
+		workers = len(checks)
+	}
+
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				exists[i] = c.ContentRepository.Exists(metas[objs[i].Oid])
+			}
+		}()
+	}
+
+	for _, i := range checks {
+		indexes <- i
+	}
+	close(indexes)
+
+	wg.Wait()
+
+	return exists
+}
+
 func createObjectResult(o *ObjectRequest, meta *entity.MetaData, metaExists, objectExists bool) *ObjectResult {
 
 	return &ObjectResult{
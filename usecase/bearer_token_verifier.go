@@ -0,0 +1,6 @@
+package usecase
+
+// BearerTokenVerifier is ...
+type BearerTokenVerifier interface {
+	Verify(token string) (*Identity, error)
+}
@@ -10,5 +10,18 @@ import (
 type ContentRepository interface {
 	Get(meta *entity.MetaData, w io.Writer, from int64, to int64) (int64, error)
 	Put(meta *entity.MetaData, r io.Reader) error
+	Delete(meta *entity.MetaData) error
 	Exists(meta *entity.MetaData) bool
+	// Stat returns the details of the stored content
+	Stat(meta *entity.MetaData) (*entity.Content, error)
+	// Check returns an error unless the content is stored with the size of meta
+	Check(meta *entity.MetaData) error
+	Ping() error
+
+	// CreateMultipartUpload starts an upload in parts and returns its id
+	CreateMultipartUpload(meta *entity.MetaData) (string, error)
+	// UploadPart stores a part of exactly size bytes and returns its ETag
+	UploadPart(meta *entity.MetaData, uploadID string, number int, r io.Reader, size int64) (string, error)
+	CompleteMultipartUpload(meta *entity.MetaData, uploadID string, parts []entity.UploadPart) error
+	AbortMultipartUpload(meta *entity.MetaData, uploadID string) error
 }
@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+// DeletionService deletes objects on behalf of admins, e.g. secrets pushed
+// by mistake. Deleted objects are removed from every repo and tombstoned,
+// and every deletion is recorded with who made it, when and why.
+type DeletionService interface {
+	Delete(oid string, user string, reason string) error
+	// Clear removes the tombstone of a deleted object so that it can be uploaded again
+	Clear(oid string, user string, reason string) error
+	Tombstones() ([]*entity.Tombstone, error)
+	Deletions() ([]*entity.ObjectDeletion, error)
+}
+
+type deletionService struct {
+	MetaDataRepository  MetaDataRepository
+	ContentRepository   ContentRepository
+	TombstoneRepository TombstoneRepository
+	AuditLog            *AuditLog
+}
+
+// NewDeletionService is ...
+// audit may be nil. Deletions are recorded in the tombstone repository either way.
+func NewDeletionService(metaDataRepo MetaDataRepository, contentRepo ContentRepository, tombstoneRepo TombstoneRepository, audit *AuditLog) DeletionService {
+	return &deletionService{
+		MetaDataRepository:  metaDataRepo,
+		ContentRepository:   contentRepo,
+		TombstoneRepository: tombstoneRepo,
+		AuditLog:            audit,
+	}
+}
+
+// Delete tombstones the object before deleting its content and meta data,
+// so that it cannot be uploaded again while it is being deleted.
+func (s *deletionService) Delete(oid string, user string, reason string) error {
+
+	if reason == "" {
+		return ErrReasonRequired
+	}
+
+	meta, err := s.MetaDataRepository.Get(oid)
+	if err != nil {
+		return ErrObjectNotFound
+	}
+
+	deletion := &entity.ObjectDeletion{
+		Action: entity.DeletionActionDelete,
+		Oid:    oid,
+		Size:   meta.Size,
+		Repos:  meta.Repos,
+		User:   user,
+		Reason: reason,
+		Time:   time.Now().Unix(),
+	}
+
+	err = s.TombstoneRepository.Add(deletion)
+	if err != nil {
+		return err
+	}
+
+	s.AuditLog.Record(deletionEvent(entity.AuditObjectDelete, deletion))
+
+	if s.ContentRepository.Exists(meta) {
+		err = s.ContentRepository.Delete(meta)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.MetaDataRepository.Delete(oid)
+}
+
+// Clear removes the tombstone of the object
+func (s *deletionService) Clear(oid string, user string, reason string) error {
+
+	if reason == "" {
+		return ErrReasonRequired
+	}
+
+	deletion := &entity.ObjectDeletion{
+		Action: entity.DeletionActionClear,
+		Oid:    oid,
+		User:   user,
+		Reason: reason,
+		Time:   time.Now().Unix(),
+	}
+
+	err := s.TombstoneRepository.Clear(deletion)
+	if err != nil {
+		return err
+	}
+
+	s.AuditLog.Record(deletionEvent(entity.AuditTombstoneClear, deletion))
+
+	return nil
+}
+
+// Tombstones returns the tombstones of all deleted objects
+func (s *deletionService) Tombstones() ([]*entity.Tombstone, error) {
+
+	return s.TombstoneRepository.Tombstones()
+}
+
+// Deletions returns the record of all deletions and cleared tombstones
+func (s *deletionService) Deletions() ([]*entity.ObjectDeletion, error) {
+
+	return s.TombstoneRepository.Deletions()
+}
+
+// deletionEvent returns the audit event of the deletion. Deleted objects
+// are removed from every repo, so the event has no repo.
+func deletionEvent(action string, deletion *entity.ObjectDeletion) *entity.AuditEvent {
+
+	details := map[string]string{"reason": deletion.Reason}
+	if len(deletion.Repos) > 0 {
+		details["repos"] = strings.Join(deletion.Repos, ",")
+	}
+
+	return &entity.AuditEvent{
+		Time:    deletion.Time,
+		Actor:   deletion.User,
+		Action:  action,
+		Target:  deletion.Oid,
+		Details: details,
+	}
+}
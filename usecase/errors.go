@@ -0,0 +1,42 @@
+package usecase
+
+import (
+	"errors"
+)
+
+var (
+	// ErrUnauthorized is returned when the credentials are missing or invalid
+	ErrUnauthorized = errors.New("Unauthorized")
+	// ErrForbidden is returned when the user lacks the role required for a repository
+	ErrForbidden = errors.New("Forbidden")
+	// ErrAccessTokenNotFound is returned when revoking a token that does not exist
+	ErrAccessTokenNotFound = errors.New("Access token not found")
+	// ErrLockNotFound is returned when deleting a lock that does not exist
+	ErrLockNotFound = errors.New("Lock not found")
+	// ErrNotLockOwner is returned when deleting another user's lock without force
+	ErrNotLockOwner = errors.New("Attempt to delete other user's lock")
+	// ErrObjectNotFound is returned when an object was not registered in a batch request
+	ErrObjectNotFound = errors.New("Object not found")
+	// ErrContentLengthMismatch is returned when the declared length of an upload is not the registered size
+	ErrContentLengthMismatch = errors.New("Content-Length does not match the size of the object")
+	// ErrSizeMismatch is returned when uploaded content is not of the size registered in the batch
+	ErrSizeMismatch = errors.New("Content size does not match")
+	// ErrHashMismatch is returned when uploaded content does not hash to its OID
+	ErrHashMismatch = errors.New("Content hash does not match OID")
+	// ErrHashAlgoNotSupported is returned for batch requests with a hash algorithm other than sha256
+	ErrHashAlgoNotSupported = errors.New("Hash algorithm not supported")
+	// ErrRepositoryNotFound is returned for repositories that are not registered
+	ErrRepositoryNotFound = errors.New("Repository not found")
+	// ErrRepositoryExists is returned when registering or renaming to a name that is in use
+	ErrRepositoryExists = errors.New("Repository already exists")
+	// ErrInvalidRepositoryName is returned for names that are not <owner>/<repo> paths
+	ErrInvalidRepositoryName = errors.New("Invalid repository name")
+	// ErrInvalidCursor is returned when listing from a cursor of another list or sort order
+	ErrInvalidCursor = errors.New("Invalid cursor")
+	// ErrTombstoneNotFound is returned for objects that were not deleted by an admin
+	ErrTombstoneNotFound = errors.New("Tombstone not found")
+	// ErrReasonRequired is returned when deleting an object without giving a reason
+	ErrReasonRequired = errors.New("A reason is required")
+	// ErrAuditLogDisabled is returned when querying an audit log without a store
+	ErrAuditLogDisabled = errors.New("Audit log is disabled")
+)
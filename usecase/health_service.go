@@ -0,0 +1,39 @@
+package usecase
+
+import (
+	"fmt"
+)
+
+// HealthService is ...
+type HealthService interface {
+	Ready() error
+}
+
+type healthService struct {
+	MetaDataRepository MetaDataRepository
+	ContentRepository  ContentRepository
+}
+
+// NewHealthService is ...
+func NewHealthService(metaDataRepo MetaDataRepository, contentRepo ContentRepository) HealthService {
+	return &healthService{
+		MetaDataRepository: metaDataRepo,
+		ContentRepository:  contentRepo,
+	}
+}
+
+// Ready checks that the meta data store and the content store answer requests
+func (s *healthService) Ready() error {
+
+	err := s.MetaDataRepository.Ping()
+	if err != nil {
+		return fmt.Errorf("meta data store is unavailable: %s", err)
+	}
+
+	err = s.ContentRepository.Ping()
+	if err != nil {
+		return fmt.Errorf("content store is unavailable: %s", err)
+	}
+
+	return nil
+}
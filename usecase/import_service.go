@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+// ImportService adds the objects of one repo to another without uploading
+// them again. Content is stored under its OID whichever repos reference it,
+// so an import only adds references and never copies content.
+type ImportService interface {
+	Import(req *ImportRequest) (*ImportResult, error)
+}
+
+type importService struct {
+	MetaDataRepository   MetaDataRepository
+	ContentRepository    ContentRepository
+	RepositoryRepository RepositoryRepository
+	Quotas               *Quotas
+	UploadPolicies       *UploadPolicies
+}
+
+// NewImportService is ...
+// Imported objects are subject to the quotas and upload policies of the
+// target repo like uploads are, and repoRepo, quotas and policies may be nil
+// like for NewBatchService.
+func NewImportService(metaDataRepo MetaDataRepository, contentRepo ContentRepository, repoRepo RepositoryRepository, quotas *Quotas, policies *UploadPolicies) ImportService {
+	return &importService{
+		MetaDataRepository:   metaDataRepo,
+		ContentRepository:    contentRepo,
+		RepositoryRepository: repoRepo,
+		Quotas:               quotas,
+		UploadPolicies:       policies,
+	}
+}
+
+// Import adds the objects to the repo and returns a result per object.
+// Objects the source repo does not reference or whose content is not
+// stored are not imported.
+func (s *importService) Import(req *ImportRequest) (*ImportResult, error) {
+
+	oids := req.Oids
+	if req.All {
+		var err error
+		oids, err = s.MetaDataRepository.References(req.Source)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	quota, err := newQuotaCheck(s.Quotas, s.MetaDataRepository, req.Repo, req.User)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := repositoryPolicy(s.UploadPolicies, s.RepositoryRepository, req.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	// never look up invalid OIDs, they would be used as storage keys
+	var valid []string
+	for _, oid := range oids {
+		if entity.ValidOid(oid) {
+			valid = append(valid, oid)
+		}
+	}
+
+	metas, err := s.MetaDataRepository.GetMany(valid)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{}
+	for _, oid := range oids {
+		result.Objects = append(result.Objects, s.importObject(req, oid, metas[oid], &policy, quota))
+	}
+
+	return result, nil
+}
+
+func (s *importService) importObject(req *ImportRequest, oid string, meta *entity.MetaData, policy *entity.UploadPolicy, quota *quotaCheck) *ImportObjectResult {
+
+	if !entity.ValidOid(oid) {
+		return &ImportObjectResult{Oid: oid, Error: &ObjectError{Code: 422, Message: "Invalid object ID"}}
+	}
+
+	if meta == nil || !meta.InRepo(req.Source) {
+		return &ImportObjectResult{Oid: oid, Error: &ObjectError{Code: 404, Message: "Object not found in source repository"}}
+	}
+
+	res := &ImportObjectResult{Oid: oid, Size: meta.Size}
+
+	if meta.InRepo(req.Repo) {
+		return res
+	}
+
+	// content that was uploaded but not verified is not linked
+	if !meta.Complete || !s.ContentRepository.Exists(meta) {
+		res.Error = &ObjectError{Code: 404, Message: "Object content not found"}
+		return res
+	}
+
+	obj := &ObjectRequest{Oid: oid, Size: meta.Size}
+
+	res.Error = checkUploadPolicy(policy, obj)
+	if res.Error == nil {
+		res.Error = quota.reserve(obj, meta)
+	}
+	if res.Error != nil {
+		return res
+	}
+
+	_, err := s.MetaDataRepository.AddReference(req.Repo, oid)
+	if err != nil {
+		res.Error = &ObjectError{Code: 500, Message: "Failed to import object"}
+		return res
+	}
+
+	res.Imported = true
+
+	return res
+}
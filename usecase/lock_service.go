@@ -19,11 +19,18 @@ type LockService interface {
 
 type lockService struct {
 	LockRepository LockRepository
+	AuditLog       *AuditLog
+	Webhooks       *Webhooks
 }
 
-func NewLockService(lockRepo LockRepository) LockService {
+// NewLockService is ...
+// audit may be nil, in which case lock operations are not audited,
+// and so may webhooks.
+func NewLockService(lockRepo LockRepository, audit *AuditLog, webhooks *Webhooks) LockService {
 	return &lockService{
 		LockRepository: lockRepo,
+		AuditLog:       audit,
+		Webhooks:       webhooks,
 	}
 }
 
@@ -59,6 +66,21 @@ func (s *lockService) Lock(req *LockRequest) (*LockResult, error) {
 		return nil, err
 	}
 
+	s.AuditLog.Record(&entity.AuditEvent{
+		Actor:   req.User,
+		Action:  entity.AuditLockCreate,
+		Repo:    req.Repo,
+		Target:  lock.Path,
+		Details: map[string]string{"id": lock.ID},
+	})
+
+	s.Webhooks.Publish(&entity.WebhookEvent{
+		Event: entity.WebhookLockCreated,
+		Repo:  req.Repo,
+		Actor: req.User,
+		Lock:  &lock,
+	})
+
 	result := &LockResult{
 		ID:           lock.ID,
 		Path:         lock.Path,
@@ -77,10 +99,29 @@ func (s *lockService) Unlock(req *UnlockRequest) (*LockResult, error) {
 		return nil, err
 	}
 	if lock == nil {
-		// TODO
-		return nil, nil
+		return nil, ErrLockNotFound
+	}
+
+	action, event := entity.AuditLockRelease, entity.WebhookLockReleased
+	if lock.Owner.Name != req.User {
+		action, event = entity.AuditLockForceRelease, entity.WebhookLockForceReleased
 	}
 
+	s.AuditLog.Record(&entity.AuditEvent{
+		Actor:   req.User,
+		Action:  action,
+		Repo:    req.Repo,
+		Target:  lock.Path,
+		Details: map[string]string{"id": lock.ID, "owner": lock.Owner.Name},
+	})
+
+	s.Webhooks.Publish(&entity.WebhookEvent{
+		Event: event,
+		Repo:  req.Repo,
+		Actor: req.User,
+		Lock:  lock,
+	})
+
 	result := &LockResult{
 		ID:           lock.ID,
 		Path:         lock.Path,
@@ -7,7 +7,25 @@ import (
 // MetaDataRepository is ...
 type MetaDataRepository interface {
 	Get(oid string) (*entity.MetaData, error)
-	Put(oid string, size int64) (*entity.MetaData, error)
+	// GetMany returns the meta data of the objects that exist, keyed by oid
+	GetMany(oids []string) (map[string]*entity.MetaData, error)
+	Put(meta *entity.MetaData) (*entity.MetaData, error)
+	// Touch records that the content of the object was accessed at the UnixTime
+	Touch(oid string, accessedAt int64) error
+	// MarkComplete records that the content of the object is stored in the storage class
+	MarkComplete(oid string, storageClass string) error
 	Delete(oid string) error
+	// DeleteUnreferenced deletes the meta data of the object unless a repo
+	// references it or it is shared, and returns it if it was deleted
+	DeleteUnreferenced(oid string) (*entity.MetaData, error)
+	// AddReference adds the object to the repo and returns the number of repos referencing it
+	AddReference(repo string, oid string) (int, error)
+	// RemoveReference removes the object from the repo and returns the number of repos still referencing it
+	RemoveReference(repo string, oid string) (int, error)
+	// References returns the oids of the objects the repo references
+	References(repo string) ([]string, error)
 	Objects() ([]*entity.MetaData, error)
+	RepoUsage(repo string) (*entity.Usage, error)
+	UserUsage(user string) (*entity.Usage, error)
+	Ping() error
 }
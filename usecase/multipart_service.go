@@ -0,0 +1,282 @@
+package usecase
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+var (
+	// ErrUploadNotStarted is returned for parts of an upload that was not started
+	ErrUploadNotStarted = errors.New("Upload not started")
+	// ErrUnexpectedPart is returned for a part that is not the next one of the upload
+	ErrUnexpectedPart = errors.New("Parts must be uploaded in order")
+	// ErrUploadIncomplete is returned when completing an upload with missing parts
+	ErrUploadIncomplete = errors.New("Upload is missing parts")
+)
+
+// MultipartService uploads objects in parts, so that a failed upload can be
+// resumed from the last stored part. Parts are uploaded in order, which
+// allows to verify the hash of the object without reading it back.
+type MultipartService interface {
+	Start(req *ObjectRequest) (*entity.MultipartUpload, error)
+	UploadPart(req *ObjectRequest, number int, r io.Reader) (*entity.MultipartUpload, error)
+	Complete(req *ObjectRequest) error
+	Abort(req *ObjectRequest) error
+}
+
+type multipartService struct {
+	MetaDataRepository        MetaDataRepository
+	ContentRepository         ContentRepository
+	MultipartUploadRepository MultipartUploadRepository
+	RepositoryRepository      RepositoryRepository
+	TombstoneRepository       TombstoneRepository
+	UploadPolicies            *UploadPolicies
+	PartSize                  int64
+	AuditLog                  *AuditLog
+	Webhooks                  *Webhooks
+}
+
+// NewMultipartService is ...
+// repoRepo, tombstones and policies may be nil, as in NewBatchService.
+func NewMultipartService(metaDataRepo MetaDataRepository, contentRepo ContentRepository, uploadRepo MultipartUploadRepository, repoRepo RepositoryRepository, tombstones TombstoneRepository, policies *UploadPolicies, partSize int64, audit *AuditLog, webhooks *Webhooks) MultipartService {
+	return &multipartService{
+		MetaDataRepository:        metaDataRepo,
+		ContentRepository:         contentRepo,
+		MultipartUploadRepository: uploadRepo,
+		RepositoryRepository:      repoRepo,
+		TombstoneRepository:       tombstones,
+		UploadPolicies:            policies,
+		PartSize:                  partSize,
+		AuditLog:                  audit,
+		Webhooks:                  webhooks,
+	}
+}
+
+// Start returns the upload of the object, starting it unless it is in progress
+func (s *multipartService) Start(req *ObjectRequest) (*entity.MultipartUpload, error) {
+
+	meta, err := repoObject(s.MetaDataRepository, req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkContentUpload(s.UploadPolicies, s.RepositoryRepository, s.TombstoneRepository, req, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	upload, err := s.MultipartUploadRepository.Get(req.Oid)
+	if err == nil {
+		return upload, nil
+	}
+
+	uploadID, err := s.ContentRepository.CreateMultipartUpload(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := marshalHash(sha256.New())
+	if err != nil {
+		return nil, err
+	}
+
+	upload = &entity.MultipartUpload{
+		Oid:       meta.Oid,
+		Size:      meta.Size,
+		UploadID:  uploadID,
+		PartSize:  s.PartSize,
+		HashState: state,
+		CreatedAt: time.Now().Unix(),
+	}
+
+	err = s.MultipartUploadRepository.Put(upload)
+	if err != nil {
+		s.ContentRepository.AbortMultipartUpload(meta, uploadID)
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+// UploadPart stores the next part of the upload.
+// req.Size is the declared length of the part, or negative if unknown.
+func (s *multipartService) UploadPart(req *ObjectRequest, number int, r io.Reader) (*entity.MultipartUpload, error) {
+
+	meta, err := repoObject(s.MetaDataRepository, req)
+	if err != nil {
+		return nil, err
+	}
+
+	upload, err := s.MultipartUploadRepository.Get(req.Oid)
+	if err != nil {
+		return nil, ErrUploadNotStarted
+	}
+
+	err = s.checkUpload(req, meta, upload)
+	if err != nil {
+		return nil, err
+	}
+
+	if number != upload.NextPart() || number > upload.PartCount() {
+		return nil, ErrUnexpectedPart
+	}
+
+	size := upload.PartLength(number)
+	if req.Size >= 0 && req.Size != size {
+		return nil, ErrContentLengthMismatch
+	}
+
+	h, err := unmarshalHash(upload.HashState)
+	if err != nil {
+		return nil, err
+	}
+
+	limited := &sizeLimitedReader{r: r, remaining: size}
+	etag, err := s.ContentRepository.UploadPart(meta, upload.UploadID, number, io.TeeReader(limited, h), size)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := marshalHash(h)
+	if err != nil {
+		return nil, err
+	}
+
+	upload.Parts = append(upload.Parts, entity.UploadPart{Number: number, Size: size, ETag: etag})
+	upload.HashState = state
+
+	err = s.MultipartUploadRepository.Put(upload)
+	if err != nil {
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+// Complete assembles the parts into the object once all are uploaded.
+// An upload whose content does not hash to the OID is aborted.
+func (s *multipartService) Complete(req *ObjectRequest) error {
+
+	meta, err := repoObject(s.MetaDataRepository, req)
+	if err != nil {
+		return err
+	}
+
+	upload, err := s.MultipartUploadRepository.Get(req.Oid)
+	if err != nil {
+		return ErrUploadNotStarted
+	}
+
+	// the repo may have been archived or the object deleted since the
+	// upload was started
+	err = s.checkUpload(req, meta, upload)
+	if err != nil {
+		return err
+	}
+
+	if len(upload.Parts) != upload.PartCount() || upload.Uploaded() != meta.Size {
+		return ErrUploadIncomplete
+	}
+
+	h, err := unmarshalHash(upload.HashState)
+	if err != nil {
+		return err
+	}
+
+	if hex.EncodeToString(h.Sum(nil)) != meta.Oid {
+		s.abort(meta, upload)
+		return ErrHashMismatch
+	}
+
+	err = s.ContentRepository.CompleteMultipartUpload(meta, upload.UploadID, upload.Parts)
+	if err != nil {
+		return err
+	}
+
+	err = s.MultipartUploadRepository.Delete(meta.Oid)
+	if err != nil {
+		return err
+	}
+
+	err = s.MetaDataRepository.MarkComplete(meta.Oid, meta.StorageClass)
+	if err != nil {
+		return err
+	}
+
+	s.AuditLog.Record(uploadEvent(req, meta))
+	s.Webhooks.Publish(objectEvent(entity.WebhookObjectUploaded, req, meta))
+
+	return nil
+}
+
+// Abort discards the upload and its parts
+func (s *multipartService) Abort(req *ObjectRequest) error {
+
+	meta, err := repoObject(s.MetaDataRepository, req)
+	if err != nil {
+		return err
+	}
+
+	upload, err := s.MultipartUploadRepository.Get(req.Oid)
+	if err != nil {
+		return ErrUploadNotStarted
+	}
+
+	return s.abort(meta, upload)
+}
+
+// checkUpload aborts the upload if the content may no longer be uploaded
+func (s *multipartService) checkUpload(req *ObjectRequest, meta *entity.MetaData, upload *entity.MultipartUpload) error {
+
+	err := checkContentUpload(s.UploadPolicies, s.RepositoryRepository, s.TombstoneRepository, req, meta)
+	if err != nil {
+		s.abort(meta, upload)
+		return err
+	}
+
+	return nil
+}
+
+func (s *multipartService) abort(meta *entity.MetaData, upload *entity.MultipartUpload) error {
+
+	err := s.ContentRepository.AbortMultipartUpload(meta, upload.UploadID)
+	if err != nil {
+		return err
+	}
+
+	return s.MultipartUploadRepository.Delete(upload.Oid)
+}
+
+func marshalHash(h hash.Hash) ([]byte, error) {
+
+	m, ok := h.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, errors.New("Hash state cannot be saved")
+	}
+
+	return m.MarshalBinary()
+}
+
+func unmarshalHash(state []byte) (hash.Hash, error) {
+
+	h := sha256.New()
+
+	u, ok := h.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return nil, errors.New("Hash state cannot be restored")
+	}
+
+	err := u.UnmarshalBinary(state)
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
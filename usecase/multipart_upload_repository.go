@@ -0,0 +1,12 @@
+package usecase
+
+import (
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+// MultipartUploadRepository is ...
+type MultipartUploadRepository interface {
+	Get(oid string) (*entity.MultipartUpload, error)
+	Put(upload *entity.MultipartUpload) error
+	Delete(oid string) error
+}
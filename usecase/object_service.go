@@ -0,0 +1,248 @@
+package usecase
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+// Sort orders of object lists
+const (
+	ObjectSortOid  = "oid"
+	ObjectSortSize = "size"
+	ObjectSortDate = "date"
+)
+
+const (
+	defaultObjectListLimit = 100
+	maxObjectListLimit     = 1000
+)
+
+// ObjectService lists and inspects the objects of a repo
+type ObjectService interface {
+	List(req *ObjectListRequest) (*ObjectListResult, error)
+	Info(repo string, oid string) (*ObjectInfoResult, error)
+}
+
+type objectService struct {
+	MetaDataRepository MetaDataRepository
+	ContentRepository  ContentRepository
+}
+
+// NewObjectService is ...
+func NewObjectService(metaDataRepo MetaDataRepository, contentRepo ContentRepository) ObjectService {
+	return &objectService{
+		MetaDataRepository: metaDataRepo,
+		ContentRepository:  contentRepo,
+	}
+}
+
+// List returns a page of the objects of the repo.
+// The limit defaults to 100 and is at most 1000.
+func (s *objectService) List(req *ObjectListRequest) (*ObjectListResult, error) {
+
+	var cursor *entity.MetaData
+	if req.Cursor != "" {
+		var err error
+		cursor, err = parseObjectCursor(req.Cursor, req.Sort)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultObjectListLimit
+	}
+	if limit > maxObjectListLimit {
+		limit = maxObjectListLimit
+	}
+
+	oids, err := s.MetaDataRepository.References(req.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	less := objectLess(req.Sort, req.Descending)
+
+	// the references are in the order of their oids, so only the objects
+	// of the page need to be read when sorting by oid
+	if req.Sort != ObjectSortSize && req.Sort != ObjectSortDate {
+		if req.Descending {
+			for i, j := 0, len(oids)-1; i < j; i, j = i+1, j-1 {
+				oids[i], oids[j] = oids[j], oids[i]
+			}
+		}
+
+		start := 0
+		if cursor != nil {
+			start = sort.Search(len(oids), func(i int) bool {
+				return !less(&entity.MetaData{Oid: oids[i]}, cursor)
+			})
+		}
+
+		// read the page and one more object in one go, and read on only if
+		// objects were released since the references were read
+		var objects []*entity.MetaData
+		for rest := oids[start:]; len(rest) > 0 && len(objects) <= limit; {
+			n := limit + 1 - len(objects)
+			if n > len(rest) {
+				n = len(rest)
+			}
+
+			metas, err := s.MetaDataRepository.GetMany(rest[:n])
+			if err != nil {
+				return nil, err
+			}
+			for _, oid := range rest[:n] {
+				if meta, ok := metas[oid]; ok {
+					objects = append(objects, meta)
+				}
+			}
+
+			rest = rest[n:]
+		}
+
+		return objectPage(objects, req.Sort, limit), nil
+	}
+
+	metas, err := s.MetaDataRepository.GetMany(oids)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]*entity.MetaData, 0, len(metas))
+	for _, oid := range oids {
+		if meta, ok := metas[oid]; ok {
+			objects = append(objects, meta)
+		}
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return less(objects[i], objects[j])
+	})
+
+	if cursor != nil {
+		start := sort.Search(len(objects), func(i int) bool {
+			return !less(objects[i], cursor)
+		})
+		objects = objects[start:]
+	}
+
+	return objectPage(objects, req.Sort, limit), nil
+}
+
+// Info returns the meta data of an object of the repo and the details of
+// its stored content.
+func (s *objectService) Info(repo string, oid string) (*ObjectInfoResult, error) {
+
+	meta, err := s.MetaDataRepository.Get(oid)
+	if err != nil || !meta.InRepo(repo) {
+		return nil, ErrObjectNotFound
+	}
+
+	result := &ObjectInfoResult{MetaData: meta}
+
+	// objects that were registered but never uploaded have no content
+	if s.ContentRepository.Exists(meta) {
+		result.Content, err = s.ContentRepository.Stat(meta)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// objectPage returns the first limit objects, with the cursor of the next
+// one if there are more
+func objectPage(objects []*entity.MetaData, key string, limit int) *ObjectListResult {
+
+	result := &ObjectListResult{Objects: objects}
+	if len(objects) > limit {
+		result.Objects = objects[:limit]
+		result.NextCursor = objectCursor(objects[limit], key)
+	}
+
+	return result
+}
+
+// objectLess orders by the key and then by oid, so that the order of a list
+// is the same on every page
+func objectLess(key string, descending bool) func(a, b *entity.MetaData) bool {
+
+	less := func(a, b *entity.MetaData) bool {
+
+		switch key {
+		case ObjectSortSize:
+			if a.Size != b.Size {
+				return a.Size < b.Size
+			}
+		case ObjectSortDate:
+			if a.CreatedAt != b.CreatedAt {
+				return a.CreatedAt < b.CreatedAt
+			}
+		}
+
+		return a.Oid < b.Oid
+	}
+
+	if descending {
+		return func(a, b *entity.MetaData) bool { return less(b, a) }
+	}
+
+	return less
+}
+
+// objectCursor encodes the sort key of the object followed by its oid, or
+// only its oid when sorting by oid
+func objectCursor(meta *entity.MetaData, key string) string {
+
+	switch key {
+	case ObjectSortSize:
+		return fmt.Sprintf("%d:%s", meta.Size, meta.Oid)
+	case ObjectSortDate:
+		return fmt.Sprintf("%d:%s", meta.CreatedAt, meta.Oid)
+	}
+
+	return meta.Oid
+}
+
+// parseObjectCursor returns the sort key and oid of a cursor as meta data,
+// which need not exist anymore
+func parseObjectCursor(cursor string, key string) (*entity.MetaData, error) {
+
+	oid := cursor
+	var value int64
+
+	if key == ObjectSortSize || key == ObjectSortDate {
+		parts := strings.SplitN(cursor, ":", 2)
+		if len(parts) != 2 {
+			return nil, ErrInvalidCursor
+		}
+
+		var err error
+		value, err = strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		oid = parts[1]
+	}
+
+	if !entity.ValidOid(oid) {
+		return nil, ErrInvalidCursor
+	}
+
+	meta := &entity.MetaData{Oid: oid}
+	switch key {
+	case ObjectSortSize:
+		meta.Size = value
+	case ObjectSortDate:
+		meta.CreatedAt = value
+	}
+
+	return meta, nil
+}
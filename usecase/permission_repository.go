@@ -0,0 +1,13 @@
+package usecase
+
+import (
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+// PermissionRepository is ...
+type PermissionRepository interface {
+	Grant(user string, repo string, role entity.Role) error
+	Revoke(user string, repo string) error
+	Get(user string, repo string) (*entity.Permission, error)
+	Permissions(repo string) ([]*entity.Permission, error)
+}
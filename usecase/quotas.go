@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+// Quotas holds the storage quotas of repos and users.
+// Repos and users without their own quota get the default one.
+type Quotas struct {
+	Repo  entity.Quota
+	User  entity.Quota
+	Repos map[string]entity.Quota
+	Users map[string]entity.Quota
+}
+
+// RepoQuota returns the quota of the repo
+func (q *Quotas) RepoQuota(repo string) entity.Quota {
+
+	if q == nil {
+		return entity.Quota{}
+	}
+
+	if quota, ok := q.Repos[repo]; ok {
+		return quota
+	}
+
+	return q.Repo
+}
+
+// UserQuota returns the quota of the user
+func (q *Quotas) UserQuota(user string) entity.Quota {
+
+	if q == nil {
+		return entity.Quota{}
+	}
+
+	if quota, ok := q.Users[user]; ok {
+		return quota
+	}
+
+	return q.User
+}
+
+// quotaCheck tracks the usage of a repo and a user while the objects of a
+// batch request are reserved, so that the objects of one batch are
+// checked against each other as well.
+type quotaCheck struct {
+	repo      string
+	repoQuota entity.Quota
+	repoUsage entity.Usage
+	userQuota entity.Quota
+	userUsage entity.Usage
+}
+
+func newQuotaCheck(quotas *Quotas, metaDataRepo MetaDataRepository, repo string, user string) (*quotaCheck, error) {
+
+	q := &quotaCheck{
+		repo:      repo,
+		repoQuota: quotas.RepoQuota(repo),
+		userQuota: quotas.UserQuota(user),
+	}
+
+	if !q.repoQuota.Unlimited() {
+		usage, err := metaDataRepo.RepoUsage(repo)
+		if err != nil {
+			return nil, err
+		}
+		q.repoUsage = *usage
+	}
+
+	if !q.userQuota.Unlimited() {
+		usage, err := metaDataRepo.UserUsage(user)
+		if err != nil {
+			return nil, err
+		}
+		q.userUsage = *usage
+	}
+
+	return q, nil
+}
+
+// reserve adds the object to the usage unless that exceeds a quota.
+// meta is the stored meta data of the object, if any.
+func (q *quotaCheck) reserve(obj *ObjectRequest, meta *entity.MetaData) *ObjectError {
+
+	repoUsage := q.repoUsage
+	userUsage := q.userUsage
+
+	if meta == nil {
+		userUsage.Size += obj.Size
+		userUsage.Objects++
+	}
+
+	if meta == nil || !meta.InRepo(q.repo) {
+		size := obj.Size
+		if meta != nil {
+			size = meta.Size
+		}
+		repoUsage.Size += size
+		repoUsage.Objects++
+	}
+
+	if !q.repoQuota.Allows(repoUsage) {
+		return &ObjectError{Code: 507, Message: "Repository storage quota exceeded"}
+	}
+
+	if !q.userQuota.Allows(userUsage) {
+		return &ObjectError{Code: 507, Message: "User storage quota exceeded"}
+	}
+
+	q.repoUsage = repoUsage
+	q.userUsage = userUsage
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package usecase
+
+// ReferenceService releases the references of repos to objects. Content is
+// stored once however many repos reference it, and is deleted together with
+// its meta data when the last reference goes.
+type ReferenceService interface {
+	Release(repo string, oid string) error
+	Collect(oid string) error
+}
+
+type referenceService struct {
+	MetaDataRepository MetaDataRepository
+	ContentRepository  ContentRepository
+}
+
+// NewReferenceService is ...
+func NewReferenceService(metaDataRepo MetaDataRepository, contentRepo ContentRepository) ReferenceService {
+	return &referenceService{
+		MetaDataRepository: metaDataRepo,
+		ContentRepository:  contentRepo,
+	}
+}
+
+// Release removes the object from the repo
+func (s *referenceService) Release(repo string, oid string) error {
+
+	refs, err := s.MetaDataRepository.RemoveReference(repo, oid)
+	if err != nil {
+		return err
+	}
+
+	if refs > 0 {
+		return nil
+	}
+
+	return s.Collect(oid)
+}
+
+// Collect deletes the object once no repo references it. A repo may have
+// referenced the object again in the meantime. The meta data goes first, so
+// that no repo can reference content being deleted.
+func (s *referenceService) Collect(oid string) error {
+
+	meta, err := s.MetaDataRepository.DeleteUnreferenced(oid)
+	if err != nil || meta == nil {
+		return err
+	}
+
+	if s.ContentRepository.Exists(meta) {
+		return s.ContentRepository.Delete(meta)
+	}
+
+	return nil
+}
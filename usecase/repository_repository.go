@@ -0,0 +1,20 @@
+package usecase
+
+import (
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+// RepositoryRepository is the registry of repositories.
+type RepositoryRepository interface {
+	// Create registers the repo, or returns ErrRepositoryExists
+	Create(repo *entity.Repository) error
+	// Get returns the repo, or ErrRepositoryNotFound
+	Get(name string) (*entity.Repository, error)
+	Repositories() ([]*entity.Repository, error)
+	SetArchived(name string, archived bool) error
+	// Rename moves the repo together with its locks, object references and permissions
+	Rename(name string, newName string) error
+	// Delete removes the repo together with its locks, permissions and object
+	// references, and returns the oids of the objects no repo references anymore
+	Delete(name string) ([]string, error)
+}
@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"time"
+
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+// RepositoryService manages the lifecycle of registered repositories.
+// Repositories that were used before they were registered can be created
+// afterwards, and keep their objects and locks.
+type RepositoryService interface {
+	Create(name string, actor string) (*entity.Repository, error)
+	Get(name string) (*entity.Repository, error)
+	List() ([]*entity.Repository, error)
+	Rename(name string, newName string, actor string) (*entity.Repository, error)
+	Archive(name string, archived bool, actor string) (*entity.Repository, error)
+	Delete(name string, actor string) error
+}
+
+type repositoryService struct {
+	RepositoryRepository RepositoryRepository
+	ReferenceService     ReferenceService
+	AuditLog             *AuditLog
+}
+
+// NewRepositoryService is ...
+// audit may be nil.
+func NewRepositoryService(repoRepo RepositoryRepository, refs ReferenceService, audit *AuditLog) RepositoryService {
+	return &repositoryService{
+		RepositoryRepository: repoRepo,
+		ReferenceService:     refs,
+		AuditLog:             audit,
+	}
+}
+
+// Create registers the repo
+func (s *repositoryService) Create(name string, actor string) (*entity.Repository, error) {
+
+	if !entity.ValidRepositoryPath(name) {
+		return nil, ErrInvalidRepositoryName
+	}
+
+	repo := &entity.Repository{
+		Name:      name,
+		CreatedAt: time.Now().Unix(),
+	}
+
+	err := s.RepositoryRepository.Create(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	s.AuditLog.Record(&entity.AuditEvent{Time: repo.CreatedAt, Actor: actor, Action: entity.AuditRepoCreate, Repo: name})
+
+	return repo, nil
+}
+
+// Get returns the repo
+func (s *repositoryService) Get(name string) (*entity.Repository, error) {
+
+	return s.RepositoryRepository.Get(name)
+}
+
+// List returns all registered repos
+func (s *repositoryService) List() ([]*entity.Repository, error) {
+
+	return s.RepositoryRepository.Repositories()
+}
+
+// Rename moves the repo, its locks, object references and permissions to newName
+func (s *repositoryService) Rename(name string, newName string, actor string) (*entity.Repository, error) {
+
+	if !entity.ValidRepositoryPath(newName) {
+		return nil, ErrInvalidRepositoryName
+	}
+
+	err := s.RepositoryRepository.Rename(name, newName)
+	if err != nil {
+		return nil, err
+	}
+
+	s.AuditLog.Record(&entity.AuditEvent{
+		Actor:   actor,
+		Action:  entity.AuditRepoRename,
+		Repo:    name,
+		Details: map[string]string{"new_name": newName},
+	})
+
+	return s.RepositoryRepository.Get(newName)
+}
+
+// Archive makes the repo read-only, or writable again
+func (s *repositoryService) Archive(name string, archived bool, actor string) (*entity.Repository, error) {
+
+	err := s.RepositoryRepository.SetArchived(name, archived)
+	if err != nil {
+		return nil, err
+	}
+
+	action := entity.AuditRepoArchive
+	if !archived {
+		action = entity.AuditRepoUnarchive
+	}
+	s.AuditLog.Record(&entity.AuditEvent{Actor: actor, Action: action, Repo: name})
+
+	return s.RepositoryRepository.Get(name)
+}
+
+// Delete removes the repo, its locks and permissions, and then deletes the
+// objects and content no other repo references.
+func (s *repositoryService) Delete(name string, actor string) error {
+
+	_, err := s.RepositoryRepository.Get(name)
+	if err != nil {
+		return err
+	}
+
+	// the references are removed with the repo, so that none can be added
+	// between releasing the objects and deleting the repo
+	oids, err := s.RepositoryRepository.Delete(name)
+	if err != nil {
+		return err
+	}
+
+	s.AuditLog.Record(&entity.AuditEvent{Actor: actor, Action: entity.AuditRepoDelete, Repo: name})
+
+	for _, oid := range oids {
+		err = s.ReferenceService.Collect(oid)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,21 +1,40 @@
 package usecase
 
+import (
+	"time"
+
+	"github.com/ikmski/git-lfs3/entity"
+)
+
 // BatchRequest is ...
 type BatchRequest struct {
-	Objects []*ObjectRequest
+	Operation string
+	Transfers []string // the transfer adapters of the client in order of preference
+	HashAlgo  string   // the hash algorithm of the OIDs, sha256 if empty
+	User      string
+	Repo      string
+	Objects   []*ObjectRequest
+	// ObjectsURL is the URL of the objects of the repo that transfer
+	// adapters serving content through the server build links on
+	ObjectsURL string
 }
 
 // ObjectRequest is ...
 type ObjectRequest struct {
+	User string
+	Repo string
 	Oid  string
 	Size int64
+	Path string // the path of the file in the repo, if the client sent it
 	From int64
 	To   int64
 }
 
 // BatchResult is ...
 type BatchResult struct {
-	Objects []*ObjectResult
+	Transfer string // the name of the negotiated transfer adapter
+	HashAlgo string
+	Objects  []*ObjectResult
 }
 
 // ObjectResult is ...
@@ -24,6 +43,69 @@ type ObjectResult struct {
 	Size         int64
 	MetaExists   bool
 	ObjectExists bool
+	Error        *ObjectError // set if no action can be offered for the object
+	Actions      map[string]*Action
+}
+
+// ObjectError is ...
+type ObjectError struct {
+	Code    int
+	Message string
+}
+
+func (e *ObjectError) Error() string {
+	return e.Message
+}
+
+// ImportRequest is ...
+// The objects of Oids, or all objects of Source if All is set, are added
+// to Repo. Both are <owner>/<repo> paths.
+type ImportRequest struct {
+	User   string
+	Repo   string
+	Source string
+	Oids   []string
+	All    bool
+}
+
+// ImportResult is ...
+type ImportResult struct {
+	Objects []*ImportObjectResult
+}
+
+// ImportObjectResult is ...
+// Imported is false for objects that were in the repo already.
+type ImportObjectResult struct {
+	Oid      string
+	Size     int64
+	Imported bool
+	Error    *ObjectError
+}
+
+// ObjectListRequest is ...
+// Objects are sorted by oid, or by size or date and then oid, in ascending
+// order unless Descending is set. Cursor is the NextCursor of the previous
+// page, which lists from the position of that object even if it is gone.
+type ObjectListRequest struct {
+	Repo       string
+	Sort       string
+	Descending bool
+	Cursor     string
+	Limit      int
+}
+
+// ObjectListResult is ...
+// NextCursor is empty on the last page.
+type ObjectListResult struct {
+	Objects    []*entity.MetaData
+	NextCursor string
+}
+
+// ObjectInfoResult is ...
+// Content is nil if the content of the object is not stored.
+type ObjectInfoResult struct {
+	MetaData *entity.MetaData
+	Content  *entity.Content
 }
 
 type LockRequest struct {
@@ -76,3 +158,48 @@ type LockVerifyResult struct {
 	Theirs     []*LockResult
 	NextCursor string
 }
+
+// Identity is the authenticated user of a request and the limits of the
+// credentials it authenticated with
+type Identity struct {
+	User   string
+	Groups []string
+	Repo   string         // the only repository the credentials are valid for, if not empty
+	Role   entity.Role    // the highest role the credentials grant, if not empty
+	Scopes []entity.Scope // the scopes of a personal access token, nil if unrestricted
+}
+
+// restricted reports whether the credentials are limited in any way
+func (id *Identity) restricted() bool {
+	return id.Repo != "" || id.Role != "" || id.Scopes != nil
+}
+
+// AccessTokenRequest is ...
+type AccessTokenRequest struct {
+	Actor  string // the user creating the token, the user or an admin
+	User   string
+	Name   string
+	Scopes []entity.Scope
+	Repo   string
+	TTL    time.Duration // the token does not expire if zero
+}
+
+// AccessTokenResult is ...
+type AccessTokenResult struct {
+	Token *entity.AccessToken
+	Value string
+}
+
+// Token is ...
+type Token struct {
+	Value     string
+	ExpiresAt int64 // UnixTime
+}
+
+// TokenClaims is ...
+type TokenClaims struct {
+	User      string
+	Repo      string
+	Operation string
+	ExpiresAt int64 // UnixTime
+}
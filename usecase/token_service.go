@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	errTokenSecretNotConfigured = errors.New("Token secret is not configured")
+	errInvalidToken             = errors.New("Invalid token")
+	errTokenExpired             = errors.New("Token expired")
+)
+
+// TokenService is ...
+type TokenService interface {
+	Issue(user string, repo string, operation string) (*Token, error)
+	Verify(token string) (*TokenClaims, error)
+}
+
+type tokenService struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+type tokenPayload struct {
+	User      string `json:"u"`
+	Repo      string `json:"r"`
+	Operation string `json:"o"`
+	ExpiresAt int64  `json:"e"`
+}
+
+// NewTokenService returns a service signing tokens with HMAC-SHA256.
+// Tokens can neither be issued nor verified without a secret.
+func NewTokenService(secret []byte, ttl time.Duration) TokenService {
+	return &tokenService{
+		secret: secret,
+		ttl:    ttl,
+	}
+}
+
+// Issue returns a token for the operation on the repo, valid until the ttl elapses
+func (s *tokenService) Issue(user string, repo string, operation string) (*Token, error) {
+
+	if len(s.secret) == 0 {
+		return nil, errTokenSecretNotConfigured
+	}
+
+	payload := &tokenPayload{
+		User:      user,
+		Repo:      repo,
+		Operation: operation,
+		ExpiresAt: time.Now().Add(s.ttl).Unix(),
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+
+	token := &Token{
+		Value:     encoded + "." + base64.RawURLEncoding.EncodeToString(s.sign(encoded)),
+		ExpiresAt: payload.ExpiresAt,
+	}
+
+	return token, nil
+}
+
+// Verify checks the signature and the expiry of the token and returns its claims
+func (s *tokenService) Verify(token string) (*TokenClaims, error) {
+
+	if len(s.secret) == 0 {
+		return nil, errTokenSecretNotConfigured
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, errInvalidToken
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+
+	if !hmac.Equal(sig, s.sign(parts[0])) {
+		return nil, errInvalidToken
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+
+	var payload tokenPayload
+	err = json.Unmarshal(data, &payload)
+	if err != nil {
+		return nil, errInvalidToken
+	}
+
+	if time.Now().Unix() >= payload.ExpiresAt {
+		return nil, errTokenExpired
+	}
+
+	claims := &TokenClaims{
+		User:      payload.User,
+		Repo:      payload.Repo,
+		Operation: payload.Operation,
+		ExpiresAt: payload.ExpiresAt,
+	}
+
+	return claims, nil
+}
+
+func (s *tokenService) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
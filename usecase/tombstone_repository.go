@@ -0,0 +1,22 @@
+package usecase
+
+import (
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+// TombstoneRepository keeps the tombstones of deleted objects together with
+// an append-only record of deletions
+type TombstoneRepository interface {
+	// Add tombstones the deleted object and records its deletion
+	Add(deletion *entity.ObjectDeletion) error
+	// Get returns the tombstone of the object, or ErrTombstoneNotFound
+	Get(oid string) (*entity.Tombstone, error)
+	// GetMany returns the tombstones of the objects that have one, keyed by oid
+	GetMany(oids []string) (map[string]*entity.Tombstone, error)
+	// Clear removes the tombstone of the object and records who cleared it,
+	// or returns ErrTombstoneNotFound
+	Clear(deletion *entity.ObjectDeletion) error
+	Tombstones() ([]*entity.Tombstone, error)
+	// Deletions returns the deletion records in the order they were added
+	Deletions() ([]*entity.ObjectDeletion, error)
+}
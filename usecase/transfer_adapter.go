@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"errors"
+	"time"
+)
+
+// BasicTransfer is the transfer adapter every client supports and that is
+// used when the client does not list any
+const BasicTransfer = "basic"
+
+// ErrTransferNotSupported is returned when the server offers none of the
+// transfer adapters of the client
+var ErrTransferNotSupported = errors.New("None of the requested transfer adapters is supported")
+
+// TransferAdapter produces the actions a client follows to transfer objects
+// in one transfer mode, e.g. through the server or directly to storage.
+type TransferAdapter interface {
+	// Name is the name of the adapter in the transfers of batch requests
+	Name() string
+	// Actions returns the actions for an object that needs no error,
+	// keyed by "download", "upload" or "verify"
+	Actions(req *BatchRequest, obj *ObjectResult) (map[string]*Action, error)
+}
+
+// Action is ...
+type Action struct {
+	Href      string
+	Header    map[string]string
+	ExpiresAt time.Time // zero if the action does not expire
+}
+
+// TransferAdapters is the set of transfer adapters the server offers
+type TransferAdapters struct {
+	adapters map[string]TransferAdapter
+}
+
+// NewTransferAdapters is ...
+func NewTransferAdapters(adapters ...TransferAdapter) *TransferAdapters {
+
+	t := &TransferAdapters{adapters: make(map[string]TransferAdapter)}
+	for _, a := range adapters {
+		t.adapters[a.Name()] = a
+	}
+
+	return t
+}
+
+// Negotiate returns the first adapter of the client that is offered,
+// or basic, which clients support whether they list it or not.
+func (t *TransferAdapters) Negotiate(transfers []string) (TransferAdapter, error) {
+
+	for _, name := range transfers {
+		if a, ok := t.adapters[name]; ok {
+			return a, nil
+		}
+	}
+
+	if a, ok := t.adapters[BasicTransfer]; ok {
+		return a, nil
+	}
+
+	return nil, ErrTransferNotSupported
+}
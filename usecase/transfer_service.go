@@ -2,52 +2,143 @@ package usecase
 
 import (
 	"io"
+	"strconv"
+	"time"
+
+	"github.com/ikmski/git-lfs3/entity"
 )
 
 type transferService struct {
-	ContentRepository  ContentRepository
-	MetaDataRepository MetaDataRepository
+	ContentRepository    ContentRepository
+	MetaDataRepository   MetaDataRepository
+	RepositoryRepository RepositoryRepository
+	TombstoneRepository  TombstoneRepository
+	UploadPolicies       *UploadPolicies
+	AuditLog             *AuditLog
+	Webhooks             *Webhooks
 }
 
 // TransferService is ...
 type TransferService interface {
 	Download(req *ObjectRequest, w io.Writer) (int64, error)
 	Upload(req *ObjectRequest, r io.Reader) error
+	Verify(req *ObjectRequest) error
 	Exists(req *ObjectRequest) bool
 	GetSize(req *ObjectRequest) int64
 }
 
 // NewTransferService is ...
-func NewTransferService(metaDataRepo MetaDataRepository, contentRepo ContentRepository) TransferService {
+// repoRepo, tombstones and policies may be nil, as in NewBatchService.
+// audit may be nil, in which case uploads are not audited, and so may webhooks.
+func NewTransferService(metaDataRepo MetaDataRepository, contentRepo ContentRepository, repoRepo RepositoryRepository, tombstones TombstoneRepository, policies *UploadPolicies, audit *AuditLog, webhooks *Webhooks) TransferService {
 	return &transferService{
-		ContentRepository:  contentRepo,
-		MetaDataRepository: metaDataRepo,
+		ContentRepository:    contentRepo,
+		MetaDataRepository:   metaDataRepo,
+		RepositoryRepository: repoRepo,
+		TombstoneRepository:  tombstones,
+		UploadPolicies:       policies,
+		AuditLog:             audit,
+		Webhooks:             webhooks,
 	}
 }
 
 func (s *transferService) Download(req *ObjectRequest, w io.Writer) (int64, error) {
 
-	meta, err := s.MetaDataRepository.Get(req.Oid)
+	meta, err := repoObject(s.MetaDataRepository, req)
 	if err != nil {
 		return 0, err
 	}
 
-	return s.ContentRepository.Get(meta, w, req.From, req.To)
+	n, err := s.ContentRepository.Get(meta, w, req.From, req.To)
+	if err != nil {
+		return n, err
+	}
+
+	// The content has already been written, so a failure to record the
+	// access time must not fail the download.
+	s.MetaDataRepository.Touch(meta.Oid, time.Now().Unix())
+
+	return n, nil
 }
 
+// Upload stores the content of an object registered in a batch request.
+// req.Size is the declared length of the content, or negative if unknown.
 func (s *transferService) Upload(req *ObjectRequest, r io.Reader) error {
 
-	meta, err := s.MetaDataRepository.Get(req.Oid)
+	meta, err := repoObject(s.MetaDataRepository, req)
+	if err != nil {
+		return err
+	}
+
+	if req.Size >= 0 && req.Size != meta.Size {
+		return ErrContentLengthMismatch
+	}
+
+	err = checkContentUpload(s.UploadPolicies, s.RepositoryRepository, s.TombstoneRepository, req, meta)
 	if err != nil {
 		return err
 	}
 
-	return s.ContentRepository.Put(meta, r)
+	// Content beyond the registered size is never read, and the
+	// content repository sees an error instead of the end of short content.
+	err = s.ContentRepository.Put(meta, &sizeLimitedReader{r: r, remaining: meta.Size})
+	if err != nil {
+		return err
+	}
+
+	err = s.MetaDataRepository.MarkComplete(meta.Oid, meta.StorageClass)
+	if err != nil {
+		return err
+	}
+
+	s.AuditLog.Record(uploadEvent(req, meta))
+	s.Webhooks.Publish(objectEvent(entity.WebhookObjectUploaded, req, meta))
+
+	return nil
+}
+
+// Verify marks an object as complete once a client has uploaded it
+// directly to the content repository, which only accepts content hashing
+// to the OID the upload was signed with. The size is checked here.
+func (s *transferService) Verify(req *ObjectRequest) error {
+
+	meta, err := repoObject(s.MetaDataRepository, req)
+	if err != nil {
+		return err
+	}
+
+	if req.Size != meta.Size {
+		return ErrSizeMismatch
+	}
+
+	err = checkContentUpload(s.UploadPolicies, s.RepositoryRepository, s.TombstoneRepository, req, meta)
+	if err != nil {
+		return err
+	}
+
+	if !s.ContentRepository.Exists(meta) {
+		return ErrObjectNotFound
+	}
+
+	err = s.ContentRepository.Check(meta)
+	if err != nil {
+		return err
+	}
+
+	err = s.MetaDataRepository.MarkComplete(meta.Oid, meta.StorageClass)
+	if err != nil {
+		return err
+	}
+
+	s.AuditLog.Record(uploadEvent(req, meta))
+	s.Webhooks.Publish(objectEvent(entity.WebhookObjectVerified, req, meta))
+
+	return nil
 }
 
 func (s *transferService) Exists(req *ObjectRequest) bool {
 
-	meta, err := s.MetaDataRepository.Get(req.Oid)
+	meta, err := repoObject(s.MetaDataRepository, req)
 	if err != nil {
 		return false
 	}
@@ -57,10 +148,73 @@ func (s *transferService) Exists(req *ObjectRequest) bool {
 
 func (s *transferService) GetSize(req *ObjectRequest) int64 {
 
-	meta, err := s.MetaDataRepository.Get(req.Oid)
+	meta, err := repoObject(s.MetaDataRepository, req)
 	if err != nil {
 		return 0
 	}
 
 	return meta.Size
 }
+
+// repoObject returns the meta data of an object of the repo of the request.
+// Objects of other repos are not found, whatever the access to them.
+func repoObject(metaDataRepo MetaDataRepository, req *ObjectRequest) (*entity.MetaData, error) {
+
+	meta, err := metaDataRepo.Get(req.Oid)
+	if err != nil || !meta.InRepo(req.Repo) {
+		return nil, ErrObjectNotFound
+	}
+
+	return meta, nil
+}
+
+// objectEvent returns the webhook event of a completed upload
+func objectEvent(event string, req *ObjectRequest, meta *entity.MetaData) *entity.WebhookEvent {
+
+	return &entity.WebhookEvent{
+		Event:  event,
+		Repo:   req.Repo,
+		Actor:  req.User,
+		Object: &entity.WebhookObject{Oid: meta.Oid, Size: meta.Size},
+	}
+}
+
+// sizeLimitedReader fails with ErrSizeMismatch as soon as more or less
+// content than expected is read
+type sizeLimitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *sizeLimitedReader) Read(p []byte) (int, error) {
+
+	if l.remaining < 0 {
+		return 0, ErrSizeMismatch
+	}
+
+	// read a byte more than remaining to detect content that is too large
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+
+	if l.remaining < 0 || (err == io.EOF && l.remaining > 0) {
+		return n, ErrSizeMismatch
+	}
+
+	return n, err
+}
+
+// uploadEvent returns the audit event of a completed upload
+func uploadEvent(req *ObjectRequest, meta *entity.MetaData) *entity.AuditEvent {
+
+	return &entity.AuditEvent{
+		Actor:   req.User,
+		Action:  entity.AuditObjectUpload,
+		Repo:    req.Repo,
+		Target:  meta.Oid,
+		Details: map[string]string{"size": strconv.FormatInt(meta.Size, 10)},
+	}
+}
@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"fmt"
+
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+// UploadPolicies holds the upload policies of repos.
+// The policy of a repo adds to the default policy: fields it leaves unset
+// are taken from the default.
+type UploadPolicies struct {
+	Default entity.UploadPolicy
+	Repos   map[string]entity.UploadPolicy
+}
+
+// Policy returns the policy of the repo
+func (p *UploadPolicies) Policy(repo string) entity.UploadPolicy {
+
+	if p == nil {
+		return entity.UploadPolicy{}
+	}
+
+	policy := p.Default
+
+	r, ok := p.Repos[repo]
+	if !ok {
+		return policy
+	}
+
+	if r.MaxSize > 0 {
+		policy.MaxSize = r.MaxSize
+	}
+	if r.AllowedExtensions != nil {
+		policy.AllowedExtensions = r.AllowedExtensions
+	}
+	if r.DeniedExtensions != nil {
+		policy.DeniedExtensions = r.DeniedExtensions
+	}
+	policy.ReadOnly = policy.ReadOnly || r.ReadOnly
+	policy.Archived = policy.Archived || r.Archived
+
+	return policy
+}
+
+// repositoryPolicy returns the upload policy of the repo, which is archived
+// if either its configuration or its registration says so.
+// repoRepo may be nil.
+func repositoryPolicy(policies *UploadPolicies, repoRepo RepositoryRepository, repo string) (entity.UploadPolicy, error) {
+
+	policy := policies.Policy(repo)
+	if repoRepo == nil {
+		return policy, nil
+	}
+
+	r, err := repoRepo.Get(repo)
+	if err == ErrRepositoryNotFound {
+		return policy, nil
+	}
+	if err != nil {
+		return policy, err
+	}
+
+	policy.Archived = policy.Archived || r.Archived
+
+	return policy, nil
+}
+
+// checkContentUpload returns the error for content of the object that may not
+// be stored anymore, as the object was deleted by an admin, or the repo was
+// archived or its policy changed since the batch request.
+// policies, repoRepo and tombstones may be nil.
+func checkContentUpload(policies *UploadPolicies, repoRepo RepositoryRepository, tombstones TombstoneRepository, req *ObjectRequest, meta *entity.MetaData) error {
+
+	if tombstones != nil {
+		_, err := tombstones.Get(meta.Oid)
+		if err == nil {
+			return &ObjectError{Code: 410, Message: "Object was deleted by an admin"}
+		}
+		if err != ErrTombstoneNotFound {
+			return err
+		}
+	}
+
+	policy, err := repositoryPolicy(policies, repoRepo, req.Repo)
+	if err != nil {
+		return err
+	}
+
+	objErr := checkUploadPolicy(&policy, &ObjectRequest{Oid: meta.Oid, Size: meta.Size})
+	if objErr != nil {
+		return objErr
+	}
+
+	return nil
+}
+
+// checkUploadPolicy returns the error for an object the policy does not allow to upload
+func checkUploadPolicy(policy *entity.UploadPolicy, obj *ObjectRequest) *ObjectError {
+
+	if policy.Archived {
+		return &ObjectError{Code: 403, Message: "Repository is archived"}
+	}
+
+	if policy.ReadOnly {
+		return &ObjectError{Code: 403, Message: "Repository is read-only"}
+	}
+
+	if obj.Size < 0 {
+		return &ObjectError{Code: 422, Message: "Object size must not be negative"}
+	}
+
+	if policy.MaxSize > 0 && obj.Size > policy.MaxSize {
+		return &ObjectError{Code: 422, Message: fmt.Sprintf("Object size %d exceeds the maximum of %d bytes", obj.Size, policy.MaxSize)}
+	}
+
+	if obj.Path != "" && !policy.AllowsPath(obj.Path) {
+		return &ObjectError{Code: 422, Message: fmt.Sprintf("Files like %s are not allowed in this repository", obj.Path)}
+	}
+
+	return nil
+}
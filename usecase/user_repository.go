@@ -9,4 +9,5 @@ type UserRepository interface {
 	AddUser(user, pass string) error
 	DeleteUser(user string) error
 	Users() ([]*entity.User, error)
+	Authenticate(user, pass string) (*entity.User, error)
 }
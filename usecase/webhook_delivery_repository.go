@@ -0,0 +1,19 @@
+package usecase
+
+import (
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+// WebhookDeliveryRepository is the persistent queue of webhook deliveries
+type WebhookDeliveryRepository interface {
+	// Enqueue adds the delivery, assigning its ID
+	Enqueue(delivery *entity.WebhookDelivery) error
+	// Due returns at most limit deliveries whose next attempt is not after
+	// now, in the order of their next attempt. Only the oldest delivery of
+	// each URL is returned, so the deliveries to a URL are made in the order
+	// they were enqueued.
+	Due(now int64, limit int) ([]*entity.WebhookDelivery, error)
+	// Update stores the state of a failed attempt
+	Update(delivery *entity.WebhookDelivery) error
+	Delete(id string) error
+}
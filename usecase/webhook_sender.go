@@ -0,0 +1,10 @@
+package usecase
+
+import (
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+// WebhookSender posts a delivery to its webhook. Errors are retried.
+type WebhookSender interface {
+	Send(hook *entity.Webhook, delivery *entity.WebhookDelivery) error
+}
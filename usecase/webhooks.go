@@ -0,0 +1,226 @@
+package usecase
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ikmski/git-lfs3/entity"
+)
+
+const (
+	defaultWebhookMaxAttempts  = 10
+	defaultWebhookBackoff      = 10 * time.Second
+	defaultWebhookMaxBackoff   = time.Hour
+	defaultWebhookPollInterval = 5 * time.Second
+	webhookDeliveryBatch       = 100
+)
+
+// Webhooks notifies endpoints of events. Events are queued for every
+// matching webhook when they are published and delivered in the background,
+// so that slow or failing endpoints never delay requests. Failed deliveries
+// are retried with exponential backoff, and the later deliveries to the same
+// endpoint wait for them. A nil Webhooks publishes nothing.
+type Webhooks struct {
+	Hooks        []entity.Webhook
+	Queue        WebhookDeliveryRepository
+	Sender       WebhookSender
+	MaxAttempts  int           // deliveries are dropped after as many failed attempts
+	Backoff      time.Duration // delay of the first retry, doubled on every further retry
+	MaxBackoff   time.Duration
+	PollInterval time.Duration // how often the queue is checked for due retries
+	// OnError is called with the errors of the queue and with the
+	// deliveries that are dropped
+	OnError func(err error)
+
+	wake chan struct{}
+}
+
+// NewWebhooks is ...
+// onError may be nil, in which case errors are dropped.
+func NewWebhooks(hooks []entity.Webhook, queue WebhookDeliveryRepository, sender WebhookSender, onError func(err error)) *Webhooks {
+	return &Webhooks{
+		Hooks:        hooks,
+		Queue:        queue,
+		Sender:       sender,
+		MaxAttempts:  defaultWebhookMaxAttempts,
+		Backoff:      defaultWebhookBackoff,
+		MaxBackoff:   defaultWebhookMaxBackoff,
+		PollInterval: defaultWebhookPollInterval,
+		OnError:      onError,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Publish queues the event for every webhook fired on it, setting its time if it is unset
+func (w *Webhooks) Publish(event *entity.WebhookEvent) {
+
+	if w == nil {
+		return
+	}
+
+	if event.Time == 0 {
+		event.Time = time.Now().Unix()
+	}
+
+	queued := false
+	for i := range w.Hooks {
+
+		hook := &w.Hooks[i]
+		if !hook.Matches(event.Event, event.Repo) {
+			continue
+		}
+
+		err := w.Queue.Enqueue(&entity.WebhookDelivery{
+			URL:           hook.URL,
+			Event:         *event,
+			NextAttemptAt: event.Time,
+		})
+		if err != nil {
+			w.error(err)
+			continue
+		}
+		queued = true
+	}
+
+	if queued {
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Deliver attempts the deliveries that are due at now. The deliveries to a
+// webhook are made one at a time in the order they were queued, so a failed
+// delivery blocks the webhook until it succeeds or is dropped. Deliveries to
+// webhooks that are no longer configured are dropped.
+func (w *Webhooks) Deliver(now int64) error {
+
+	for {
+		deliveries, err := w.Queue.Due(now, webhookDeliveryBatch)
+		if err != nil {
+			return err
+		}
+
+		// the next deliveries of a webhook are due once its first is removed
+		removed := false
+		for _, d := range deliveries {
+
+			hook := w.hook(d.URL)
+			if hook == nil {
+				removed = w.delete(d) || removed
+				continue
+			}
+
+			err := w.Sender.Send(hook, d)
+			if err == nil {
+				removed = w.delete(d) || removed
+				continue
+			}
+
+			d.Attempts++
+			d.LastError = err.Error()
+
+			if d.Attempts >= w.MaxAttempts {
+				removed = w.delete(d) || removed
+				w.error(fmt.Errorf("Dropped webhook delivery %s to %s after %d attempts: %s", d.ID, d.URL, d.Attempts, err))
+				continue
+			}
+
+			d.NextAttemptAt = now + int64(w.backoff(d.Attempts)/time.Second)
+			err = w.Queue.Update(d)
+			if err != nil {
+				w.error(err)
+			}
+		}
+
+		if !removed {
+			return nil
+		}
+	}
+}
+
+// Start delivers queued events in the background until the returned
+// function is called, which waits for the delivery in progress
+func (w *Webhooks) Start() (stop func()) {
+
+	if w == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(w.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			err := w.Deliver(time.Now().Unix())
+			if err != nil {
+				w.error(err)
+			}
+
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			case <-w.wake:
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	}
+}
+
+// backoff returns the delay before the retry following the attempts
+func (w *Webhooks) backoff(attempts int) time.Duration {
+
+	delay := w.Backoff
+	for i := 1; i < attempts && delay < w.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > w.MaxBackoff {
+		return w.MaxBackoff
+	}
+
+	return delay
+}
+
+func (w *Webhooks) hook(url string) *entity.Webhook {
+
+	for i := range w.Hooks {
+		if w.Hooks[i].URL == url {
+			return &w.Hooks[i]
+		}
+	}
+
+	return nil
+}
+
+// delete removes the delivery from the queue, reporting whether it succeeded
+func (w *Webhooks) delete(d *entity.WebhookDelivery) bool {
+
+	err := w.Queue.Delete(d.ID)
+	if err != nil {
+		w.error(err)
+		return false
+	}
+
+	return true
+}
+
+func (w *Webhooks) error(err error) {
+
+	if w.OnError != nil {
+		w.OnError(err)
+	}
+}